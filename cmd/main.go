@@ -6,6 +6,7 @@ import (
 	"avito/internal/cache"
 	"avito/internal/config"
 	"avito/internal/logger"
+	"avito/internal/scheduler"
 	"avito/internal/storage"
 	"avito/internal/utils"
 	"context"
@@ -28,15 +29,57 @@ func main() {
 		log.Fatalf("Failed to init logger: %v", err)
 	}
 
-	db := storage.NewStorage(storage.GetDatabaseDSN(*cfg))
-	defer db.Tm.DB.Close()
+	var db app.Storage
+	closeStorage := func() {}
+	switch cfg.Storage {
+	case config.StorageMemory:
+		db = storage.NewMemoryStorage()
+	case config.StoragePostgres:
+		pg := storage.NewStorage(storage.GetDatabaseDSN(*cfg))
+		closeStorage = func() { pg.Tm.DB.Close() }
+		defer closeStorage()
 
-	err = utils.GooseUp(db.Tm.DB)
-	if err != nil {
-		log.Fatalf("migration error: %v", err)
+		err = utils.GooseUp(pg.Tm.DB)
+		if err != nil {
+			log.Fatalf("migration error: %v", err)
+		}
+		db = pg
+	default:
+		log.Fatalf("unknown storage type: %s", cfg.Storage)
+	}
+
+	// "reconcile [-repair]" checks the wallets instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		code := reconcile(db, os.Args[2:])
+		closeStorage()
+		os.Exit(code)
+	}
+	// "promote <user uuid>" grants the admin role, logins never do
+	if len(os.Args) > 1 && os.Args[1] == "promote" {
+		code := promote(db, os.Args[2:])
+		closeStorage()
+		os.Exit(code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if cfg.SchedulerInterval > 0 {
+		sched := scheduler.Scheduler{
+			Interval: cfg.SchedulerInterval,
+			Jobs: []scheduler.Job{
+				scheduler.ScheduledTransfers(db, lfu),
+				scheduler.ExpirePendingTransfers(db, lfu),
+				scheduler.ExpireCoins(db, lfu),
+				scheduler.CloseAuctions(db, lfu),
+			},
+		}
+		if cfg.AllowanceAmount > 0 {
+			sched.Jobs = append(sched.Jobs, scheduler.Allowance(db, lfu, cfg.AllowanceAmount, cfg.AllowanceLifetime))
+		}
+		go sched.Run(ctx)
 	}
 
-	A := app.NewApp(db, lfu)
+	A := app.NewApp(db, lfu, *cfg)
 	h := routes.NewHandler(*A)
 
 	srv := http.Server{
@@ -49,6 +92,7 @@ func main() {
 		sigs := make(chan os.Signal, 1)
 		signal.Notify(sigs, os.Interrupt)
 		<-sigs
+		cancel()
 		if err = srv.Shutdown(context.Background()); err != nil {
 			log.Printf("HTTP server Shutdown: %v", err)
 		}
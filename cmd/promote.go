@@ -0,0 +1,38 @@
+package main
+
+import (
+	"avito/internal/app"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"log"
+)
+
+// promote runs the promote subcommand that gives the admin role to the user
+// with the given id and returns the exit code: 0 on success, 1 when the user
+// does not exist and 2 on bad arguments or a storage error
+func promote(db app.Storage, args []string) int {
+	if len(args) != 1 {
+		log.Printf("usage: promote <user uuid>")
+		return 2
+	}
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		log.Printf("promote: %v", err)
+		return 2
+	}
+
+	err = db.SetUserRole(context.Background(), id.String(), models.RoleAdmin)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		log.Printf("promote: %v", err)
+		return 1
+	}
+	if err != nil {
+		log.Printf("promote: %v", err)
+		return 2
+	}
+	log.Printf("promote: %s is an admin now", id)
+	return 0
+}
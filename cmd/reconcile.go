@@ -0,0 +1,51 @@
+package main
+
+import (
+	"avito/internal/app"
+	"avito/internal/models"
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+)
+
+// reconcile runs the reconcile subcommand and returns the exit code: 0 when
+// every wallet is consistent or was repaired, 1 when problems remain and 2
+// when the check itself failed
+func reconcile(db app.Storage, args []string) int {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	repair := fs.Bool("repair", false, "fix discrepancies and record every adjustment")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	report, err := db.Reconcile(context.Background(), *repair)
+	if err != nil {
+		log.Printf("reconcile: %v", err)
+		return 2
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(report); err != nil {
+		log.Printf("reconcile: %v", err)
+		return 2
+	}
+	if !consistent(report) {
+		return 1
+	}
+	return 0
+}
+
+func consistent(report *models.ReconcileReport) bool {
+	if len(report.UnbalancedEntries) > 0 {
+		return false
+	}
+	for _, d := range report.Discrepancies {
+		if !d.Repaired {
+			return false
+		}
+	}
+	return true
+}
@@ -1,25 +1,103 @@
 package app
 
 import (
+	"avito/internal/app/services/admin"
+	"avito/internal/app/services/auctions"
 	"avito/internal/app/services/auth"
 	"avito/internal/app/services/buy"
+	"avito/internal/app/services/catalog"
+	"avito/internal/app/services/export"
 	"avito/internal/app/services/info"
+	"avito/internal/app/services/listings"
+	"avito/internal/app/services/orders"
+	"avito/internal/app/services/payments"
+	"avito/internal/app/services/pending"
+	"avito/internal/app/services/purchases"
+	"avito/internal/app/services/refunds"
+	"avito/internal/app/services/schedules"
 	"avito/internal/app/services/sendCoin"
+	"avito/internal/app/services/transactions"
 	"avito/internal/cache"
+	"avito/internal/config"
 	"avito/internal/models"
 	"context"
+	"time"
 )
 
 type Storage interface {
 	GetUser(ctx context.Context, username string) (*models.User, error)
 	CreateUser(ctx context.Context, user *models.User) error
+	GetUserRole(ctx context.Context, uuid string) (string, error)
+	SetUserRole(ctx context.Context, uuid string, role string) error
 
 	GetInfo(ctx context.Context, uuid string) (*models.Info, error)
+	GetCounterparties(ctx context.Context, uuid string) (*models.Counterparties, error)
+	GetTransactions(ctx context.Context, uuid string, filter models.TransactionFilter) ([]models.Transaction, error)
+	ExportActivity(ctx context.Context, uuid string, filter models.ExportFilter, fn func(models.ActivityRecord) error) error
 
-	Send(ctx context.Context, uuid string, toUser string, amount int) error
+	Send(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote) error
+	SendBulk(ctx context.Context, uuid string, transfers []models.BulkTransfer, note models.TransferNote) ([]models.BulkTransfer, error)
 	GetUuidByUsername(ctx context.Context, username string) (string, error)
 
+	CreateSchedule(ctx context.Context, uuid string, schedule models.ScheduledTransfer) (*models.ScheduledTransfer, error)
+	GetSchedules(ctx context.Context, uuid string) ([]models.ScheduledTransfer, error)
+	UpdateSchedule(ctx context.Context, uuid string, scheduleID int, update models.ScheduleUpdate) (*models.ScheduledTransfer, error)
+	CancelSchedule(ctx context.Context, uuid string, scheduleID int) error
+	GetScheduleRuns(ctx context.Context, uuid string, scheduleID int) ([]models.ScheduledRun, error)
+	RunDueSchedules(ctx context.Context, now time.Time, limit int) ([]models.ScheduledRun, error)
+
+	ExpireCoins(ctx context.Context, now time.Time, limit int) (int, error)
+	ResumeAllowancePeriod(ctx context.Context) (*time.Time, error)
+	IssueAllowances(ctx context.Context, period time.Time, amount int, expiresAt *time.Time, limit int) (int, error)
+	Reconcile(ctx context.Context, repair bool) (*models.ReconcileReport, error)
+
+	GetSendLimits(ctx context.Context) ([]models.SendLimit, error)
+	SetSendLimit(ctx context.Context, adminUuid string, limit models.SendLimit) (*models.SendLimit, error)
+	DeleteSendLimit(ctx context.Context, scope string, subject string) error
+
+	CreatePendingTransfer(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote, ttl time.Duration) (*models.PendingTransfer, error)
+	GetPendingTransfers(ctx context.Context, uuid string) ([]models.PendingTransfer, error)
+	AcceptPendingTransfer(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error)
+	DeclinePendingTransfer(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error)
+
+	CreatePaymentRequest(ctx context.Context, uuid string, payers []string, amount int, note models.TransferNote) (*models.PaymentRequest, error)
+	GetPaymentRequests(ctx context.Context, uuid string) ([]models.PaymentRequest, error)
+	PayPaymentRequest(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error)
+	RejectPaymentRequest(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error)
+	ExpirePendingTransfers(ctx context.Context, now time.Time, limit int) ([]models.PendingTransfer, error)
+
 	Buy(ctx context.Context, uuid string, item string) error
+	CreateOrder(ctx context.Context, uuid string, lines []models.OrderLine) (*models.Order, error)
+	SendGift(ctx context.Context, uuid string, toUser string, lines []models.OrderLine, message string) (*models.Order, error)
+
+	CreateListing(ctx context.Context, uuid string, item string, quantity int, price int) (*models.Listing, error)
+	GetListings(ctx context.Context, uuid string, filter models.ListingFilter) ([]models.Listing, error)
+	BuyListing(ctx context.Context, uuid string, listingID int) (*models.Listing, error)
+	CancelListing(ctx context.Context, uuid string, listingID int) (*models.Listing, error)
+
+	CreateAuction(ctx context.Context, adminUuid string, item string, startingBid int, endsAt time.Time) (*models.Auction, error)
+	GetAuctions(ctx context.Context) ([]models.Auction, error)
+	GetAuction(ctx context.Context, auctionID int) (*models.Auction, error)
+	PlaceBid(ctx context.Context, uuid string, auctionID int, amount int) (*models.Auction, error)
+	CloseAuctions(ctx context.Context, now time.Time, limit int) ([]models.Auction, error)
+	GetPurchases(ctx context.Context, uuid string, filter models.PurchaseFilter) ([]models.Order, error)
+	RefundPurchase(ctx context.Context, uuid string, orderID int, item string, quantity int, window time.Duration) (*models.Refund, error)
+	AdminRefundPurchase(ctx context.Context, adminUuid string, orderID int, item string, quantity int) (*models.Refund, error)
+
+	GetCatalog(ctx context.Context) ([]models.Merch, error)
+	GetMerch(ctx context.Context, item string) (*models.Merch, error)
+
+	CreateMerch(ctx context.Context, adminUuid string, merch models.Merch) error
+	UpdateMerch(ctx context.Context, adminUuid string, item string, update models.MerchUpdate) (*models.Merch, error)
+	SetMerchHidden(ctx context.Context, adminUuid string, item string, hidden bool) error
+	RetireMerch(ctx context.Context, adminUuid string, item string) error
+	RestockMerch(ctx context.Context, adminUuid string, item string, quantity int) (*models.Merch, error)
+	GetMerchHistory(ctx context.Context, item string) ([]models.MerchChange, error)
+	ReverseTransaction(ctx context.Context, adminUuid string, transactionID int, policy string) (*models.Reversal, error)
+
+	ReserveIdempotencyKey(ctx context.Context, uuid, key, requestHash string) (*models.IdempotentResponse, error)
+	SaveIdempotentResponse(ctx context.Context, uuid, key string, response models.IdempotentResponse) error
+	ReleaseIdempotencyKey(ctx context.Context, uuid, key string) error
 }
 
 type App struct {
@@ -27,15 +105,41 @@ type App struct {
 	info.InfoController
 	sendCoin.SendController
 	buy.BuyController
-	Lfu *cache.LFUCache
+	orders.OrderController
+	purchases.PurchasesController
+	refunds.RefundController
+	schedules.ScheduleController
+	pending.PendingController
+	payments.PaymentController
+	listings.ListingController
+	auctions.AuctionController
+	transactions.TransactionsController
+	export.ExportController
+	catalog.CatalogController
+	admin.AdminController
+	Storage Storage
+	Lfu     *cache.LFUCache
 }
 
-func NewApp(storage Storage, cache *cache.LFUCache) *App {
+func NewApp(storage Storage, cache *cache.LFUCache, cfg config.Config) *App {
 	return &App{
-		AuthController: auth.AuthController{Storage: storage},
-		InfoController: info.InfoController{Storage: storage, Lfu: cache},
-		SendController: sendCoin.SendController{Storage: storage, Lfu: cache},
-		BuyController:  buy.BuyController{Storage: storage, Lfu: cache},
-		Lfu:            cache,
+		AuthController:         auth.AuthController{Storage: storage},
+		InfoController:         info.InfoController{Storage: storage, Lfu: cache},
+		SendController:         sendCoin.SendController{Storage: storage, Lfu: cache},
+		BuyController:          buy.BuyController{Storage: storage, Lfu: cache},
+		OrderController:        orders.OrderController{Storage: storage, Lfu: cache},
+		PurchasesController:    purchases.PurchasesController{Storage: storage},
+		RefundController:       refunds.RefundController{Storage: storage, Lfu: cache, Window: cfg.RefundWindow},
+		ScheduleController:     schedules.ScheduleController{Storage: storage},
+		PendingController:      pending.PendingController{Storage: storage, Lfu: cache, TTL: cfg.PendingTransferTTL},
+		PaymentController:      payments.PaymentController{Storage: storage, Lfu: cache},
+		ListingController:      listings.ListingController{Storage: storage, Lfu: cache},
+		AuctionController:      auctions.AuctionController{Storage: storage, Lfu: cache},
+		TransactionsController: transactions.TransactionsController{Storage: storage},
+		ExportController:       export.ExportController{Storage: storage},
+		CatalogController:      catalog.CatalogController{Storage: storage, Lfu: cache},
+		AdminController:        admin.AdminController{Storage: storage, Lfu: cache},
+		Storage:                storage,
+		Lfu:                    cache,
 	}
 }
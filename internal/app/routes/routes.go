@@ -11,8 +11,53 @@ func NewHandler(App app.App) *mux.Router {
 	handler := mux.NewRouter()
 
 	handler.HandleFunc("/api/info", middleware.Compress(middleware.Cookie(logger.GetLogger(App.Info)))).Methods("GET")
-	handler.HandleFunc("/api/sendCoin", middleware.Compress(middleware.Cookie(logger.PostLogger(App.SendCoin)))).Methods("POST")
-	handler.HandleFunc("/api/buy/{item}", middleware.Compress(middleware.Cookie(logger.GetLogger(App.Buy)))).Methods("GET")
+	handler.HandleFunc("/api/info/counterparties", middleware.Compress(middleware.Cookie(logger.GetLogger(App.Counterparties)))).Methods("GET")
+	handler.HandleFunc("/api/sendCoin", middleware.Compress(middleware.Cookie(middleware.Idempotency(App.Storage, logger.PostLogger(App.SendCoin))))).Methods("POST")
+	handler.HandleFunc("/api/sendCoin/bulk", middleware.Compress(middleware.Cookie(middleware.Idempotency(App.Storage, logger.PostLogger(App.SendBulk))))).Methods("POST")
+	handler.HandleFunc("/api/buy/{item}", middleware.Compress(middleware.Cookie(middleware.Idempotency(App.Storage, logger.GetLogger(App.Buy))))).Methods("GET")
+	handler.HandleFunc("/api/orders", middleware.Compress(middleware.Cookie(middleware.Idempotency(App.Storage, logger.PostLogger(App.CreateOrder))))).Methods("POST")
+	handler.HandleFunc("/api/gifts", middleware.Compress(middleware.Cookie(middleware.Idempotency(App.Storage, logger.PostLogger(App.SendGift))))).Methods("POST")
+	handler.HandleFunc("/api/purchases", middleware.Compress(middleware.Cookie(logger.GetLogger(App.Purchases)))).Methods("GET")
+	handler.HandleFunc("/api/purchases/{id}/refund", middleware.Compress(middleware.Cookie(middleware.Idempotency(App.Storage, logger.PostLogger(App.Refund))))).Methods("POST")
+	handler.HandleFunc("/api/schedules", middleware.Compress(middleware.Cookie(middleware.Idempotency(App.Storage, logger.PostLogger(App.CreateSchedule))))).Methods("POST")
+	handler.HandleFunc("/api/schedules", middleware.Compress(middleware.Cookie(logger.GetLogger(App.Schedules)))).Methods("GET")
+	handler.HandleFunc("/api/schedules/{id}", middleware.Compress(middleware.Cookie(logger.PostLogger(App.UpdateSchedule)))).Methods("PATCH")
+	handler.HandleFunc("/api/schedules/{id}", middleware.Compress(middleware.Cookie(logger.PostLogger(App.CancelSchedule)))).Methods("DELETE")
+	handler.HandleFunc("/api/schedules/{id}/runs", middleware.Compress(middleware.Cookie(logger.GetLogger(App.ScheduleRuns)))).Methods("GET")
+	handler.HandleFunc("/api/pendingTransfers", middleware.Compress(middleware.Cookie(middleware.Idempotency(App.Storage, logger.PostLogger(App.CreatePendingTransfer))))).Methods("POST")
+	handler.HandleFunc("/api/pendingTransfers", middleware.Compress(middleware.Cookie(logger.GetLogger(App.PendingTransfers)))).Methods("GET")
+	handler.HandleFunc("/api/pendingTransfers/{id}/accept", middleware.Compress(middleware.Cookie(logger.PostLogger(App.AcceptPendingTransfer)))).Methods("POST")
+	handler.HandleFunc("/api/pendingTransfers/{id}/decline", middleware.Compress(middleware.Cookie(logger.PostLogger(App.DeclinePendingTransfer)))).Methods("POST")
+	handler.HandleFunc("/api/paymentRequests", middleware.Compress(middleware.Cookie(middleware.Idempotency(App.Storage, logger.PostLogger(App.CreatePaymentRequest))))).Methods("POST")
+	handler.HandleFunc("/api/paymentRequests", middleware.Compress(middleware.Cookie(logger.GetLogger(App.PaymentRequests)))).Methods("GET")
+	handler.HandleFunc("/api/paymentRequests/{id}/pay", middleware.Compress(middleware.Cookie(logger.PostLogger(App.PayPaymentRequest)))).Methods("POST")
+	handler.HandleFunc("/api/paymentRequests/{id}/reject", middleware.Compress(middleware.Cookie(logger.PostLogger(App.RejectPaymentRequest)))).Methods("POST")
+	handler.HandleFunc("/api/listings", middleware.Compress(middleware.Cookie(middleware.Idempotency(App.Storage, logger.PostLogger(App.CreateListing))))).Methods("POST")
+	handler.HandleFunc("/api/listings", middleware.Compress(middleware.Cookie(logger.GetLogger(App.Listings)))).Methods("GET")
+	handler.HandleFunc("/api/listings/{id}/buy", middleware.Compress(middleware.Cookie(middleware.Idempotency(App.Storage, logger.PostLogger(App.BuyListing))))).Methods("POST")
+	handler.HandleFunc("/api/listings/{id}/cancel", middleware.Compress(middleware.Cookie(logger.PostLogger(App.CancelListing)))).Methods("POST")
+	handler.HandleFunc("/api/auctions", middleware.Compress(middleware.Cookie(logger.GetLogger(App.Auctions)))).Methods("GET")
+	handler.HandleFunc("/api/auctions/{id}", middleware.Compress(middleware.Cookie(logger.GetLogger(App.Auction)))).Methods("GET")
+	handler.HandleFunc("/api/auctions/{id}/bids", middleware.Compress(middleware.Cookie(middleware.Idempotency(App.Storage, logger.PostLogger(App.PlaceBid))))).Methods("POST")
+	handler.HandleFunc("/api/transactions", middleware.Compress(middleware.Cookie(logger.GetLogger(App.Transactions)))).Methods("GET")
+	handler.HandleFunc("/api/export", middleware.Compress(middleware.Cookie(logger.GetLogger(App.Export)))).Methods("GET")
+	handler.HandleFunc("/api/merch", middleware.Compress(logger.GetLogger(App.Catalog))).Methods("GET")
+	handler.HandleFunc("/api/merch/{item}", middleware.Compress(logger.GetLogger(App.CatalogItem))).Methods("GET")
+	handler.HandleFunc("/api/admin/merch", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.PostLogger(App.CreateMerch))))).Methods("POST")
+	handler.HandleFunc("/api/admin/merch/{item}", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.PostLogger(App.UpdateMerch))))).Methods("PATCH")
+	handler.HandleFunc("/api/admin/merch/{item}", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.PostLogger(App.RetireMerch))))).Methods("DELETE")
+	handler.HandleFunc("/api/admin/merch/{item}/hide", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.PostLogger(App.HideMerch))))).Methods("POST")
+	handler.HandleFunc("/api/admin/merch/{item}/unhide", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.PostLogger(App.UnhideMerch))))).Methods("POST")
+	handler.HandleFunc("/api/admin/merch/{item}/restock", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.PostLogger(App.RestockMerch))))).Methods("POST")
+	handler.HandleFunc("/api/admin/merch/{item}/history", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.GetLogger(App.MerchHistory))))).Methods("GET")
+	handler.HandleFunc("/api/admin/export", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.GetLogger(App.ExportAll))))).Methods("GET")
+	handler.HandleFunc("/api/admin/transactions/{id}/reverse", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.PostLogger(App.ReverseTransaction))))).Methods("POST")
+	handler.HandleFunc("/api/admin/limits", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.GetLogger(App.SendLimits))))).Methods("GET")
+	handler.HandleFunc("/api/admin/limits", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.PostLogger(App.SetSendLimit))))).Methods("PUT")
+	handler.HandleFunc("/api/admin/limits", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.PostLogger(App.DeleteSendLimit))))).Methods("DELETE")
+	handler.HandleFunc("/api/admin/purchases/{id}/refund", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.PostLogger(App.AdminRefund))))).Methods("POST")
+	handler.HandleFunc("/api/admin/users/{username}/role", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.PostLogger(App.SetUserRole))))).Methods("PUT")
+	handler.HandleFunc("/api/admin/auctions", middleware.Compress(middleware.Cookie(middleware.Admin(App.Storage, logger.PostLogger(App.CreateAuction))))).Methods("POST")
 	handler.HandleFunc("/api/auth", middleware.Compress(logger.PostLogger(App.Auth))).Methods("POST")
 
 	return handler
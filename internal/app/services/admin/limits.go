@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+)
+
+func (ac *AdminController) SendLimits(w http.ResponseWriter, r *http.Request) {
+	limits, err := ac.Storage.GetSendLimits(r.Context())
+	if err != nil {
+		response := models.ErrorResponse{Errors: "error getting limits"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+	if limits == nil {
+		limits = []models.SendLimit{}
+	}
+	utils.JsonResponse(w, http.StatusOK, limits)
+}
+
+func (ac *AdminController) SetSendLimit(w http.ResponseWriter, r *http.Request) {
+	var req models.SendLimit
+
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil || !validSubject(req.Scope, req.Subject) {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	limit, err := ac.Storage.SetSendLimit(r.Context(), adminUuid(r), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUserNotFound):
+			response := models.ErrorResponse{Errors: storage.ErrUserNotFound.Error()}
+			utils.JsonResponse(w, http.StatusNotFound, response)
+		default:
+			response := models.ErrorResponse{Errors: "error setting limit"}
+			utils.JsonResponse(w, http.StatusInternalServerError, response)
+		}
+		return
+	}
+	utils.JsonResponse(w, http.StatusOK, limit)
+}
+
+func (ac *AdminController) DeleteSendLimit(w http.ResponseWriter, r *http.Request) {
+	scope := r.URL.Query().Get("scope")
+	subject := r.URL.Query().Get("subject")
+	if !validSubject(scope, subject) {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	err := ac.Storage.DeleteSendLimit(r.Context(), scope, subject)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrSendLimitNotFound):
+			response := models.ErrorResponse{Errors: storage.ErrSendLimitNotFound.Error()}
+			utils.JsonResponse(w, http.StatusNotFound, response)
+		default:
+			response := models.ErrorResponse{Errors: "error deleting limit"}
+			utils.JsonResponse(w, http.StatusInternalServerError, response)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// the global default has no subject, overrides must name a role or a user
+func validSubject(scope, subject string) bool {
+	switch scope {
+	case models.LimitScopeGlobal:
+		return subject == ""
+	case models.LimitScopeRole, models.LimitScopeUser:
+		return subject != ""
+	}
+	return false
+}
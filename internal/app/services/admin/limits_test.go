@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminController_SendLimits(t *testing.T) {
+	mockSt := &mockStorage{}
+	controller := &AdminController{Storage: mockSt, Lfu: cache.NewLFUCache(10)}
+	token, _ := jwtToken.BuidToken("admin-uuid")
+
+	doRequest := func(handler http.HandlerFunc, method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	t.Run("set with invalid params -> 400", func(t *testing.T) {
+		for _, body := range []string{
+			`{"scope":"team","daily":10}`,
+			`{"scope":"global","subject":"bob","daily":10}`,
+			`{"scope":"user","daily":10}`,
+			`{"scope":"role","subject":"user","weekly":-1}`,
+		} {
+			assert.Equal(t, http.StatusBadRequest, doRequest(controller.SetSendLimit, http.MethodPut, "/api/admin/limits", body).Code, body)
+		}
+	})
+
+	t.Run("set keeps unset fields inherited", func(t *testing.T) {
+		mockSt.SetSendLimitFunc = func(ctx context.Context, adminUuid string, limit models.SendLimit) (*models.SendLimit, error) {
+			assert.Equal(t, "admin-uuid", adminUuid)
+			assert.Equal(t, models.LimitScopeUser, limit.Scope)
+			assert.Equal(t, "bob", limit.Subject)
+			assert.Equal(t, 0, *limit.Daily)
+			assert.Nil(t, limit.Weekly)
+			return &limit, nil
+		}
+		w := doRequest(controller.SetSendLimit, http.MethodPut, "/api/admin/limits", `{"scope":"user","subject":"bob","daily":0}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("set for unknown user -> 404", func(t *testing.T) {
+		mockSt.SetSendLimitFunc = func(ctx context.Context, adminUuid string, limit models.SendLimit) (*models.SendLimit, error) {
+			return nil, storage.ErrUserNotFound
+		}
+		w := doRequest(controller.SetSendLimit, http.MethodPut, "/api/admin/limits", `{"scope":"user","subject":"nobody","daily":10}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("list without limits -> empty list", func(t *testing.T) {
+		mockSt.GetSendLimitsFunc = func(ctx context.Context) ([]models.SendLimit, error) {
+			return nil, nil
+		}
+		w := doRequest(controller.SendLimits, http.MethodGet, "/api/admin/limits", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		mockSt.DeleteSendLimitFunc = func(ctx context.Context, scope string, subject string) error {
+			assert.Equal(t, models.LimitScopeRole, scope)
+			assert.Equal(t, "user", subject)
+			return storage.ErrSendLimitNotFound
+		}
+		assert.Equal(t, http.StatusBadRequest, doRequest(controller.DeleteSendLimit, http.MethodDelete, "/api/admin/limits?scope=role", "").Code)
+		assert.Equal(t, http.StatusNotFound, doRequest(controller.DeleteSendLimit, http.MethodDelete, "/api/admin/limits?scope=role&subject=user", "").Code)
+	})
+}
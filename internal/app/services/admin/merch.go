@@ -0,0 +1,226 @@
+package admin
+
+import (
+	"avito/internal/app/services/catalog"
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type Storage interface {
+	CreateMerch(ctx context.Context, adminUuid string, merch models.Merch) error
+	UpdateMerch(ctx context.Context, adminUuid string, item string, update models.MerchUpdate) (*models.Merch, error)
+	SetMerchHidden(ctx context.Context, adminUuid string, item string, hidden bool) error
+	RetireMerch(ctx context.Context, adminUuid string, item string) error
+	RestockMerch(ctx context.Context, adminUuid string, item string, quantity int) (*models.Merch, error)
+	GetMerchHistory(ctx context.Context, item string) ([]models.MerchChange, error)
+	ReverseTransaction(ctx context.Context, adminUuid string, transactionID int, policy string) (*models.Reversal, error)
+	GetUuidByUsername(ctx context.Context, username string) (string, error)
+	SetUserRole(ctx context.Context, uuid string, role string) error
+	GetSendLimits(ctx context.Context) ([]models.SendLimit, error)
+	SetSendLimit(ctx context.Context, adminUuid string, limit models.SendLimit) (*models.SendLimit, error)
+	DeleteSendLimit(ctx context.Context, scope string, subject string) error
+}
+
+type AdminController struct {
+	Storage Storage
+	Lfu     *cache.LFUCache
+}
+
+type CreateMerchRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Price       int    `json:"price" validate:"required,gt=0"`
+	Description string `json:"description"`
+	Available   *bool  `json:"available"`
+	// omitted for unlimited items
+	Stock *int `json:"stock" validate:"omitempty,gte=0"`
+}
+
+type RestockRequest struct {
+	Quantity int `json:"quantity" validate:"required,gt=0"`
+}
+
+func (ac *AdminController) CreateMerch(w http.ResponseWriter, r *http.Request) {
+	var req CreateMerchRequest
+
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	merch := models.Merch{
+		Name:        req.Name,
+		Price:       req.Price,
+		Description: req.Description,
+		Available:   req.Available == nil || *req.Available,
+		Stock:       req.Stock,
+	}
+	err = ac.Storage.CreateMerch(r.Context(), adminUuid(r), merch)
+	if err != nil {
+		merchError(w, err)
+		return
+	}
+
+	ac.invalidate(merch.Name)
+	utils.JsonResponse(w, http.StatusOK, merch)
+}
+
+func (ac *AdminController) UpdateMerch(w http.ResponseWriter, r *http.Request) {
+	item, ok := itemVar(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MerchUpdate
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	merch, err := ac.Storage.UpdateMerch(r.Context(), adminUuid(r), item, req)
+	if err != nil {
+		merchError(w, err)
+		return
+	}
+
+	ac.invalidate(item, merch.Name)
+	utils.JsonResponse(w, http.StatusOK, merch)
+}
+
+func (ac *AdminController) HideMerch(w http.ResponseWriter, r *http.Request) {
+	ac.setHidden(w, r, true)
+}
+
+func (ac *AdminController) UnhideMerch(w http.ResponseWriter, r *http.Request) {
+	ac.setHidden(w, r, false)
+}
+
+func (ac *AdminController) setHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	item, ok := itemVar(w, r)
+	if !ok {
+		return
+	}
+
+	err := ac.Storage.SetMerchHidden(r.Context(), adminUuid(r), item, hidden)
+	if err != nil {
+		merchError(w, err)
+		return
+	}
+
+	ac.invalidate(item)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (ac *AdminController) RetireMerch(w http.ResponseWriter, r *http.Request) {
+	item, ok := itemVar(w, r)
+	if !ok {
+		return
+	}
+
+	err := ac.Storage.RetireMerch(r.Context(), adminUuid(r), item)
+	if err != nil {
+		merchError(w, err)
+		return
+	}
+
+	ac.invalidate(item)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (ac *AdminController) RestockMerch(w http.ResponseWriter, r *http.Request) {
+	item, ok := itemVar(w, r)
+	if !ok {
+		return
+	}
+
+	var req RestockRequest
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	merch, err := ac.Storage.RestockMerch(r.Context(), adminUuid(r), item, req.Quantity)
+	if err != nil {
+		merchError(w, err)
+		return
+	}
+
+	ac.invalidate(item)
+	utils.JsonResponse(w, http.StatusOK, merch)
+}
+
+func (ac *AdminController) MerchHistory(w http.ResponseWriter, r *http.Request) {
+	item, ok := itemVar(w, r)
+	if !ok {
+		return
+	}
+
+	history, err := ac.Storage.GetMerchHistory(r.Context(), item)
+	if err != nil {
+		merchError(w, err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, history)
+}
+
+// invalidate drops cached catalog responses after a change
+func (ac *AdminController) invalidate(items ...string) {
+	ac.Lfu.Delete(catalog.CacheKey)
+	for _, item := range items {
+		ac.Lfu.Delete(catalog.ItemCacheKey(item))
+	}
+}
+
+func adminUuid(r *http.Request) string {
+	return jwtToken.GetUserID(r.Header.Get("Authorization"))
+}
+
+func itemVar(w http.ResponseWriter, r *http.Request) (string, bool) {
+	item, ok := mux.Vars(r)["item"]
+	if !ok || item == "" {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return "", false
+	}
+	return item, true
+}
+
+func merchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrItemNotFound):
+		response := models.ErrorResponse{Errors: storage.ErrItemNotFound.Error()}
+		utils.JsonResponse(w, http.StatusNotFound, response)
+	case errors.Is(err, storage.ErrItemAlreadyExists):
+		response := models.ErrorResponse{Errors: storage.ErrItemAlreadyExists.Error()}
+		utils.JsonResponse(w, http.StatusConflict, response)
+	default:
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+	}
+}
@@ -0,0 +1,192 @@
+package admin
+
+import (
+	"avito/internal/app/services/catalog"
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type mockStorage struct {
+	CreateMerchFunc     func(ctx context.Context, adminUuid string, merch models.Merch) error
+	UpdateMerchFunc     func(ctx context.Context, adminUuid string, item string, update models.MerchUpdate) (*models.Merch, error)
+	SetMerchHiddenFunc  func(ctx context.Context, adminUuid string, item string, hidden bool) error
+	RetireMerchFunc     func(ctx context.Context, adminUuid string, item string) error
+	RestockMerchFunc    func(ctx context.Context, adminUuid string, item string, quantity int) (*models.Merch, error)
+	GetMerchHistoryFunc func(ctx context.Context, item string) ([]models.MerchChange, error)
+
+	ReverseTransactionFunc func(ctx context.Context, adminUuid string, transactionID int, policy string) (*models.Reversal, error)
+	GetUuidByUsernameFunc  func(ctx context.Context, username string) (string, error)
+	SetUserRoleFunc        func(ctx context.Context, uuid string, role string) error
+
+	GetSendLimitsFunc   func(ctx context.Context) ([]models.SendLimit, error)
+	SetSendLimitFunc    func(ctx context.Context, adminUuid string, limit models.SendLimit) (*models.SendLimit, error)
+	DeleteSendLimitFunc func(ctx context.Context, scope string, subject string) error
+}
+
+func (m *mockStorage) CreateMerch(ctx context.Context, adminUuid string, merch models.Merch) error {
+	return m.CreateMerchFunc(ctx, adminUuid, merch)
+}
+
+func (m *mockStorage) UpdateMerch(ctx context.Context, adminUuid string, item string, update models.MerchUpdate) (*models.Merch, error) {
+	return m.UpdateMerchFunc(ctx, adminUuid, item, update)
+}
+
+func (m *mockStorage) SetMerchHidden(ctx context.Context, adminUuid string, item string, hidden bool) error {
+	return m.SetMerchHiddenFunc(ctx, adminUuid, item, hidden)
+}
+
+func (m *mockStorage) RetireMerch(ctx context.Context, adminUuid string, item string) error {
+	return m.RetireMerchFunc(ctx, adminUuid, item)
+}
+
+func (m *mockStorage) RestockMerch(ctx context.Context, adminUuid string, item string, quantity int) (*models.Merch, error) {
+	return m.RestockMerchFunc(ctx, adminUuid, item, quantity)
+}
+
+func (m *mockStorage) GetMerchHistory(ctx context.Context, item string) ([]models.MerchChange, error) {
+	return m.GetMerchHistoryFunc(ctx, item)
+}
+
+func (m *mockStorage) ReverseTransaction(ctx context.Context, adminUuid string, transactionID int, policy string) (*models.Reversal, error) {
+	return m.ReverseTransactionFunc(ctx, adminUuid, transactionID, policy)
+}
+
+func (m *mockStorage) GetUuidByUsername(ctx context.Context, username string) (string, error) {
+	return m.GetUuidByUsernameFunc(ctx, username)
+}
+
+func (m *mockStorage) SetUserRole(ctx context.Context, uuid string, role string) error {
+	return m.SetUserRoleFunc(ctx, uuid, role)
+}
+
+func (m *mockStorage) GetSendLimits(ctx context.Context) ([]models.SendLimit, error) {
+	return m.GetSendLimitsFunc(ctx)
+}
+
+func (m *mockStorage) SetSendLimit(ctx context.Context, adminUuid string, limit models.SendLimit) (*models.SendLimit, error) {
+	return m.SetSendLimitFunc(ctx, adminUuid, limit)
+}
+
+func (m *mockStorage) DeleteSendLimit(ctx context.Context, scope string, subject string) error {
+	return m.DeleteSendLimitFunc(ctx, scope, subject)
+}
+
+func TestAdminController_CreateMerch(t *testing.T) {
+	mockSt := &mockStorage{}
+	lfu := cache.NewLFUCache(10)
+	controller := &AdminController{Storage: mockSt, Lfu: lfu}
+	token, _ := jwtToken.BuidToken("admin-uuid")
+
+	doRequest := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/merch", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		controller.CreateMerch(w, req)
+		return w
+	}
+
+	t.Run("invalid body -> 400", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, doRequest(`{"name":"cap"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, doRequest(`{"name":"cap","price":-1}`).Code)
+	})
+
+	t.Run("created -> 200 and catalog cache dropped", func(t *testing.T) {
+		lfu.Set(catalog.CacheKey, "[]")
+		mockSt.CreateMerchFunc = func(ctx context.Context, adminUuid string, merch models.Merch) error {
+			assert.Equal(t, "admin-uuid", adminUuid)
+			assert.Equal(t, models.Merch{Name: "cap", Price: 40, Available: true}, merch)
+			return nil
+		}
+
+		assert.Equal(t, http.StatusOK, doRequest(`{"name":"cap","price":40}`).Code)
+		_, found := lfu.Get(catalog.CacheKey)
+		assert.False(t, found)
+	})
+
+	t.Run("already exists -> 409", func(t *testing.T) {
+		mockSt.CreateMerchFunc = func(ctx context.Context, adminUuid string, merch models.Merch) error {
+			return storage.ErrItemAlreadyExists
+		}
+
+		assert.Equal(t, http.StatusConflict, doRequest(`{"name":"cap","price":40}`).Code)
+	})
+}
+
+func TestAdminController_UpdateMerch(t *testing.T) {
+	mockSt := &mockStorage{}
+	controller := &AdminController{Storage: mockSt, Lfu: cache.NewLFUCache(10)}
+
+	doRequest := func(item, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/admin/merch/"+item, bytes.NewBufferString(body))
+		req = mux.SetURLVars(req, map[string]string{"item": item})
+		w := httptest.NewRecorder()
+		controller.UpdateMerch(w, req)
+		return w
+	}
+
+	t.Run("update -> 200", func(t *testing.T) {
+		mockSt.UpdateMerchFunc = func(ctx context.Context, adminUuid string, item string, update models.MerchUpdate) (*models.Merch, error) {
+			assert.Equal(t, "cup", item)
+			assert.Equal(t, 25, *update.Price)
+			assert.Nil(t, update.Name)
+			return &models.Merch{Name: "cup", Price: 25}, nil
+		}
+
+		assert.Equal(t, http.StatusOK, doRequest("cup", `{"price":25}`).Code)
+	})
+
+	t.Run("not found -> 404", func(t *testing.T) {
+		mockSt.UpdateMerchFunc = func(ctx context.Context, adminUuid string, item string, update models.MerchUpdate) (*models.Merch, error) {
+			return nil, storage.ErrItemNotFound
+		}
+
+		assert.Equal(t, http.StatusNotFound, doRequest("unknown", `{"price":25}`).Code)
+	})
+
+	t.Run("other error -> 500", func(t *testing.T) {
+		mockSt.UpdateMerchFunc = func(ctx context.Context, adminUuid string, item string, update models.MerchUpdate) (*models.Merch, error) {
+			return nil, errors.New("some error")
+		}
+
+		assert.Equal(t, http.StatusInternalServerError, doRequest("cup", `{"price":25}`).Code)
+	})
+}
+
+func TestAdminController_RestockMerch(t *testing.T) {
+	mockSt := &mockStorage{}
+	controller := &AdminController{Storage: mockSt, Lfu: cache.NewLFUCache(10)}
+
+	doRequest := func(item, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/merch/"+item+"/restock", bytes.NewBufferString(body))
+		req = mux.SetURLVars(req, map[string]string{"item": item})
+		w := httptest.NewRecorder()
+		controller.RestockMerch(w, req)
+		return w
+	}
+
+	t.Run("invalid quantity -> 400", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, doRequest("pink-hoody", `{"quantity":0}`).Code)
+		assert.Equal(t, http.StatusBadRequest, doRequest("pink-hoody", `{"quantity":-5}`).Code)
+	})
+
+	t.Run("restock -> 200", func(t *testing.T) {
+		stock := 15
+		mockSt.RestockMerchFunc = func(ctx context.Context, adminUuid string, item string, quantity int) (*models.Merch, error) {
+			assert.Equal(t, "pink-hoody", item)
+			assert.Equal(t, 5, quantity)
+			return &models.Merch{Name: "pink-hoody", Price: 500, Available: true, Stock: &stock}, nil
+		}
+
+		assert.Equal(t, http.StatusOK, doRequest("pink-hoody", `{"quantity":5}`).Code)
+	})
+}
@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+type ReverseRequest struct {
+	// fail by default
+	Policy string `json:"policy" validate:"omitempty,oneof=fail partial"`
+}
+
+func (ac *AdminController) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	transactionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || transactionID <= 0 {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req ReverseRequest
+	validate := validator.New()
+	err = json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+	if req.Policy == "" {
+		req.Policy = models.ReversalPolicyFail
+	}
+
+	reversal, err := ac.Storage.ReverseTransaction(r.Context(), adminUuid(r), transactionID, req.Policy)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrTransactionNotFound):
+			response := models.ErrorResponse{Errors: storage.ErrTransactionNotFound.Error()}
+			utils.JsonResponse(w, http.StatusNotFound, response)
+		case errors.Is(err, storage.ErrAlreadyReversed),
+			errors.Is(err, storage.ErrReversalOfReversal),
+			errors.Is(err, storage.ErrSystemTransaction),
+			errors.Is(err, storage.ErrNotEnoughBalance):
+			response := models.ErrorResponse{Errors: err.Error()}
+			utils.JsonResponse(w, http.StatusConflict, response)
+		default:
+			response := models.ErrorResponse{Errors: "error reversing transaction"}
+			utils.JsonResponse(w, http.StatusInternalServerError, response)
+		}
+		return
+	}
+
+	// both balances and histories changed
+	for _, username := range []string{reversal.FromUser, reversal.ToUser} {
+		uuid, err := ac.Storage.GetUuidByUsername(r.Context(), username)
+		if err != nil {
+			ac.Lfu.ClearCache()
+			break
+		}
+		ac.Lfu.Delete(uuid)
+	}
+	utils.JsonResponse(w, http.StatusOK, reversal)
+}
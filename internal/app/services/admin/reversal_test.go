@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminController_ReverseTransaction(t *testing.T) {
+	mockSt := &mockStorage{}
+	lfu := cache.NewLFUCache(10)
+	controller := &AdminController{Storage: mockSt, Lfu: lfu}
+	token, _ := jwtToken.BuidToken("admin-uuid")
+
+	doRequest := func(id string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/transactions/"+id+"/reverse", bytes.NewBufferString(body))
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		controller.ReverseTransaction(w, req)
+		return w
+	}
+
+	t.Run("invalid params -> 400", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, doRequest("abc", "").Code)
+		assert.Equal(t, http.StatusBadRequest, doRequest("5", `{"policy":"maybe"}`).Code)
+	})
+
+	t.Run("default policy and cache invalidation", func(t *testing.T) {
+		mockSt.ReverseTransactionFunc = func(ctx context.Context, adminUuid string, transactionID int, policy string) (*models.Reversal, error) {
+			assert.Equal(t, "admin-uuid", adminUuid)
+			assert.Equal(t, 5, transactionID)
+			assert.Equal(t, models.ReversalPolicyFail, policy)
+			return &models.Reversal{ID: 6, OriginalID: 5, FromUser: "bob", ToUser: "alice", Amount: 10}, nil
+		}
+		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
+			return username + "-uuid", nil
+		}
+		lfu.Set("alice-uuid", "{}")
+		lfu.Set("bob-uuid", "{}")
+
+		assert.Equal(t, http.StatusOK, doRequest("5", "").Code)
+		_, ok := lfu.Get("alice-uuid")
+		assert.False(t, ok)
+		_, ok = lfu.Get("bob-uuid")
+		assert.False(t, ok)
+	})
+
+	t.Run("partial policy", func(t *testing.T) {
+		mockSt.ReverseTransactionFunc = func(ctx context.Context, adminUuid string, transactionID int, policy string) (*models.Reversal, error) {
+			assert.Equal(t, models.ReversalPolicyPartial, policy)
+			return &models.Reversal{ID: 6, OriginalID: 5, FromUser: "bob", ToUser: "alice", Amount: 4, Remaining: 6}, nil
+		}
+		assert.Equal(t, http.StatusOK, doRequest("5", `{"policy":"partial"}`).Code)
+	})
+
+	t.Run("storage errors", func(t *testing.T) {
+		for err, status := range map[error]int{
+			storage.ErrTransactionNotFound: http.StatusNotFound,
+			storage.ErrAlreadyReversed:     http.StatusConflict,
+			storage.ErrReversalOfReversal:  http.StatusConflict,
+			storage.ErrSystemTransaction:   http.StatusConflict,
+			storage.ErrNotEnoughBalance:    http.StatusConflict,
+		} {
+			mockSt.ReverseTransactionFunc = func(ctx context.Context, adminUuid string, transactionID int, policy string) (*models.Reversal, error) {
+				return nil, err
+			}
+			assert.Equal(t, status, doRequest("5", "").Code, err.Error())
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package admin
+
+import (
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strings"
+)
+
+type SetRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin user"`
+}
+
+// SetUserRole promotes a registered user to admin or demotes them. Admins
+// can't change their own role, so the last admin can't lock everyone out.
+func (ac *AdminController) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	username := strings.ToLower(strings.TrimSpace(mux.Vars(r)["username"]))
+
+	var req SetRoleRequest
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil || username == "" {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	uuid, err := ac.Storage.GetUuidByUsername(r.Context(), username)
+	if err == nil && uuid == adminUuid(r) {
+		response := models.ErrorResponse{Errors: "can't change your own role"}
+		utils.JsonResponse(w, http.StatusConflict, response)
+		return
+	}
+	if err == nil {
+		err = ac.Storage.SetUserRole(r.Context(), uuid, req.Role)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUserNotFound):
+			response := models.ErrorResponse{Errors: storage.ErrUserNotFound.Error()}
+			utils.JsonResponse(w, http.StatusNotFound, response)
+		default:
+			response := models.ErrorResponse{Errors: "error setting role"}
+			utils.JsonResponse(w, http.StatusInternalServerError, response)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,70 @@
+package admin
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminController_SetUserRole(t *testing.T) {
+	mockSt := &mockStorage{}
+	controller := &AdminController{Storage: mockSt, Lfu: cache.NewLFUCache(10)}
+	token, _ := jwtToken.BuidToken("admin-uuid")
+
+	doRequest := func(username, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/admin/users/"+username+"/role", bytes.NewBufferString(body))
+		req = mux.SetURLVars(req, map[string]string{"username": username})
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		controller.SetUserRole(w, req)
+		return w
+	}
+
+	mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
+		switch username {
+		case "bob":
+			return "bob-uuid", nil
+		case "boss":
+			return "admin-uuid", nil
+		}
+		return "", storage.ErrUserNotFound
+	}
+
+	t.Run("invalid params -> 400", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"role":"system"}`, `{"role":"root"}`} {
+			assert.Equal(t, http.StatusBadRequest, doRequest("bob", body).Code, body)
+		}
+	})
+
+	t.Run("promote -> 200", func(t *testing.T) {
+		promoted := false
+		mockSt.SetUserRoleFunc = func(ctx context.Context, uuid string, role string) error {
+			assert.Equal(t, "bob-uuid", uuid)
+			assert.Equal(t, models.RoleAdmin, role)
+			promoted = true
+			return nil
+		}
+		assert.Equal(t, http.StatusOK, doRequest("bob", `{"role":"admin"}`).Code)
+		assert.True(t, promoted)
+	})
+
+	t.Run("own role -> 409", func(t *testing.T) {
+		mockSt.SetUserRoleFunc = func(ctx context.Context, uuid string, role string) error {
+			t.Error("own role must not change")
+			return nil
+		}
+		assert.Equal(t, http.StatusConflict, doRequest("boss", `{"role":"user"}`).Code)
+	})
+
+	t.Run("unknown user -> 404", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, doRequest("nobody", `{"role":"admin"}`).Code)
+	})
+}
@@ -0,0 +1,175 @@
+package auctions
+
+import (
+	"avito/internal/app/services/catalog"
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Storage interface {
+	CreateAuction(ctx context.Context, adminUuid string, item string, startingBid int, endsAt time.Time) (*models.Auction, error)
+	GetAuctions(ctx context.Context) ([]models.Auction, error)
+	GetAuction(ctx context.Context, auctionID int) (*models.Auction, error)
+	PlaceBid(ctx context.Context, uuid string, auctionID int, amount int) (*models.Auction, error)
+	GetUuidByUsername(ctx context.Context, username string) (string, error)
+}
+
+type AuctionController struct {
+	Storage Storage
+	Lfu     *cache.LFUCache
+}
+
+type AuctionRequest struct {
+	Item        string    `json:"item" validate:"required"`
+	StartingBid int       `json:"startingBid" validate:"required,gt=0"`
+	EndsAt      time.Time `json:"endsAt" validate:"required"`
+}
+
+type BidRequest struct {
+	Amount int `json:"amount" validate:"required,gt=0"`
+}
+
+// CreateAuction puts one item of the stock up for auction, it is for admins only.
+func (ac *AuctionController) CreateAuction(w http.ResponseWriter, r *http.Request) {
+	var req AuctionRequest
+
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+	auction, err := ac.Storage.CreateAuction(r.Context(), uuid, req.Item, req.StartingBid, req.EndsAt)
+	if err != nil {
+		auctionError(w, err)
+		return
+	}
+	ac.Lfu.Delete(catalog.CacheKey)
+	ac.Lfu.Delete(catalog.ItemCacheKey(req.Item))
+	utils.JsonResponse(w, http.StatusOK, auction)
+}
+
+// Auctions returns the open auctions with their top bids.
+func (ac *AuctionController) Auctions(w http.ResponseWriter, r *http.Request) {
+	auctions, err := ac.Storage.GetAuctions(r.Context())
+	if err != nil {
+		auctionError(w, err)
+		return
+	}
+	if auctions == nil {
+		auctions = []models.Auction{}
+	}
+	utils.JsonResponse(w, http.StatusOK, auctions)
+}
+
+// Auction returns one auction in any status with all bids.
+func (ac *AuctionController) Auction(w http.ResponseWriter, r *http.Request) {
+	auctionID, ok := auctionID(w, r)
+	if !ok {
+		return
+	}
+	auction, err := ac.Storage.GetAuction(r.Context(), auctionID)
+	if err != nil {
+		auctionError(w, err)
+		return
+	}
+	utils.JsonResponse(w, http.StatusOK, auction)
+}
+
+func (ac *AuctionController) PlaceBid(w http.ResponseWriter, r *http.Request) {
+	var req BidRequest
+
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+	auctionID, ok := auctionID(w, r)
+	if !ok {
+		return
+	}
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+
+	auction, err := ac.Storage.PlaceBid(r.Context(), uuid, auctionID, req.Amount)
+	if err != nil {
+		auctionError(w, err)
+		return
+	}
+
+	// the outbid user got the coins back
+	ac.Lfu.Delete(uuid)
+	if len(auction.Bids) > 1 {
+		outbidUuid, err := ac.Storage.GetUuidByUsername(r.Context(), auction.Bids[1].User)
+		if err != nil {
+			ac.Lfu.ClearCache()
+		}
+		ac.Lfu.Delete(outbidUuid)
+	}
+	utils.JsonResponse(w, http.StatusOK, auction)
+}
+
+func auctionID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	auctionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || auctionID <= 0 {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return 0, false
+	}
+	return auctionID, true
+}
+
+func userUuid(w http.ResponseWriter, r *http.Request) (string, bool) {
+	uuid := jwtToken.GetUserID(r.Header.Get("Authorization"))
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return "", false
+	}
+	return uuid, true
+}
+
+func auctionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrAuctionNotFound), errors.Is(err, storage.ErrItemNotFound):
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusNotFound, response)
+	case errors.Is(err, storage.ErrAuctionEndInPast), errors.Is(err, storage.ErrInvalidAmount):
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+	case errors.Is(err, storage.ErrAuctionClosed),
+		errors.Is(err, storage.ErrBidTooLow),
+		errors.Is(err, storage.ErrNotEnoughBalance),
+		errors.Is(err, storage.ErrOutOfStock):
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusConflict, response)
+	default:
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+	}
+}
@@ -0,0 +1,150 @@
+package auctions
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type mockStorage struct {
+	CreateAuctionFunc     func(ctx context.Context, adminUuid string, item string, startingBid int, endsAt time.Time) (*models.Auction, error)
+	GetAuctionsFunc       func(ctx context.Context) ([]models.Auction, error)
+	GetAuctionFunc        func(ctx context.Context, auctionID int) (*models.Auction, error)
+	PlaceBidFunc          func(ctx context.Context, uuid string, auctionID int, amount int) (*models.Auction, error)
+	GetUuidByUsernameFunc func(ctx context.Context, username string) (string, error)
+}
+
+func (m *mockStorage) CreateAuction(ctx context.Context, adminUuid string, item string, startingBid int, endsAt time.Time) (*models.Auction, error) {
+	return m.CreateAuctionFunc(ctx, adminUuid, item, startingBid, endsAt)
+}
+
+func (m *mockStorage) GetAuctions(ctx context.Context) ([]models.Auction, error) {
+	return m.GetAuctionsFunc(ctx)
+}
+
+func (m *mockStorage) GetAuction(ctx context.Context, auctionID int) (*models.Auction, error) {
+	return m.GetAuctionFunc(ctx, auctionID)
+}
+
+func (m *mockStorage) PlaceBid(ctx context.Context, uuid string, auctionID int, amount int) (*models.Auction, error) {
+	return m.PlaceBidFunc(ctx, uuid, auctionID, amount)
+}
+
+func (m *mockStorage) GetUuidByUsername(ctx context.Context, username string) (string, error) {
+	return m.GetUuidByUsernameFunc(ctx, username)
+}
+
+func TestAuctionController(t *testing.T) {
+	mockSt := &mockStorage{}
+	lfu := cache.NewLFUCache(10)
+	controller := &AuctionController{Storage: mockSt, Lfu: lfu}
+	token, _ := jwtToken.BuidToken("uuid-123")
+
+	doRequest := func(handler http.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/auctions", bytes.NewBufferString(body))
+		if id != "" {
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+		}
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	t.Run("create with invalid params -> 400", func(t *testing.T) {
+		for _, body := range []string{
+			`{"item":"pink-hoody","startingBid":100}`,
+			`{"item":"pink-hoody","startingBid":0,"endsAt":"2030-01-01T00:00:00Z"}`,
+			`{"item":"pink-hoody","startingBid":100,"endsAt":"tomorrow"}`,
+		} {
+			assert.Equal(t, http.StatusBadRequest, doRequest(controller.CreateAuction, http.MethodPost, "", body).Code, body)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		mockSt.CreateAuctionFunc = func(ctx context.Context, adminUuid string, item string, startingBid int, endsAt time.Time) (*models.Auction, error) {
+			assert.Equal(t, "uuid-123", adminUuid)
+			assert.Equal(t, "pink-hoody", item)
+			assert.Equal(t, 100, startingBid)
+			assert.True(t, endsAt.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+			return &models.Auction{ID: 1, Item: item, StartingBid: startingBid, EndsAt: endsAt, Status: models.AuctionStatusOpen}, nil
+		}
+		body := `{"item":"pink-hoody","startingBid":100,"endsAt":"2030-01-01T00:00:00Z"}`
+		assert.Equal(t, http.StatusOK, doRequest(controller.CreateAuction, http.MethodPost, "", body).Code)
+
+		for err, code := range map[error]int{
+			storage.ErrItemNotFound:     http.StatusNotFound,
+			storage.ErrOutOfStock:       http.StatusConflict,
+			storage.ErrAuctionEndInPast: http.StatusBadRequest,
+		} {
+			mockSt.CreateAuctionFunc = func(ctx context.Context, adminUuid string, item string, startingBid int, endsAt time.Time) (*models.Auction, error) {
+				return nil, err
+			}
+			assert.Equal(t, code, doRequest(controller.CreateAuction, http.MethodPost, "", body).Code, err.Error())
+		}
+	})
+
+	t.Run("list without auctions -> empty list", func(t *testing.T) {
+		mockSt.GetAuctionsFunc = func(ctx context.Context) ([]models.Auction, error) {
+			return nil, nil
+		}
+		w := doRequest(controller.Auctions, http.MethodGet, "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+	})
+
+	t.Run("get unknown auction -> 404", func(t *testing.T) {
+		mockSt.GetAuctionFunc = func(ctx context.Context, auctionID int) (*models.Auction, error) {
+			return nil, storage.ErrAuctionNotFound
+		}
+		assert.Equal(t, http.StatusBadRequest, doRequest(controller.Auction, http.MethodGet, "x", "").Code)
+		assert.Equal(t, http.StatusNotFound, doRequest(controller.Auction, http.MethodGet, "5", "").Code)
+	})
+
+	t.Run("bid invalidates the bidder and the outbid user", func(t *testing.T) {
+		mockSt.PlaceBidFunc = func(ctx context.Context, uuid string, auctionID int, amount int) (*models.Auction, error) {
+			assert.Equal(t, 2, auctionID)
+			assert.Equal(t, 300, amount)
+			return &models.Auction{ID: 2, Bids: []models.Bid{
+				{User: "alice", Amount: 300, Status: models.BidStatusHeld},
+				{User: "bob", Amount: 200, Status: models.BidStatusReleased},
+			}}, nil
+		}
+		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
+			assert.Equal(t, "bob", username)
+			return "uuid-bob", nil
+		}
+		lfu.Set("uuid-123", "{}")
+		lfu.Set("uuid-bob", "{}")
+
+		assert.Equal(t, http.StatusBadRequest, doRequest(controller.PlaceBid, http.MethodPost, "2", `{"amount":0}`).Code)
+		assert.Equal(t, http.StatusOK, doRequest(controller.PlaceBid, http.MethodPost, "2", `{"amount":300}`).Code)
+		_, ok := lfu.Get("uuid-123")
+		assert.False(t, ok)
+		_, ok = lfu.Get("uuid-bob")
+		assert.False(t, ok)
+	})
+
+	t.Run("bid errors", func(t *testing.T) {
+		for err, code := range map[error]int{
+			storage.ErrAuctionNotFound:  http.StatusNotFound,
+			storage.ErrAuctionClosed:    http.StatusConflict,
+			storage.ErrBidTooLow:        http.StatusConflict,
+			storage.ErrNotEnoughBalance: http.StatusConflict,
+		} {
+			mockSt.PlaceBidFunc = func(ctx context.Context, uuid string, auctionID int, amount int) (*models.Auction, error) {
+				return nil, err
+			}
+			assert.Equal(t, code, doRequest(controller.PlaceBid, http.MethodPost, "2", `{"amount":300}`).Code, err.Error())
+		}
+	})
+}
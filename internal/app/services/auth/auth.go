@@ -21,6 +21,8 @@ type Storage interface {
 	CreateUser(ctx context.Context, user *models.User) error
 }
 
+// AuthController never grants roles: new accounts are plain users and the
+// admin role is given by the promote command or by another admin.
 type AuthController struct {
 	Storage Storage
 }
@@ -69,6 +71,7 @@ func (ac *AuthController) Auth(w http.ResponseWriter, r *http.Request) {
 			Balance:      1000,
 			Username:     username,
 			PasswordHash: string(hashedPassword),
+			Role:         models.RoleUser,
 			CreatedAt:    time.Now(),
 		}
 
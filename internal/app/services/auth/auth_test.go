@@ -141,4 +141,21 @@ func TestAuthController_Auth(t *testing.T) {
 		assert.Equal(t, "auth_token", cookies[0].Name)
 		assert.Equal(t, resp.Token, cookies[0].Value)
 	})
+	t.Run("new user named admin -> created as plain user", func(t *testing.T) {
+		mockStrg := &mockStorage{
+			getUserFunc: func(ctx context.Context, username string) (*models.User, error) {
+				return nil, nil
+			},
+			createUserFunc: func(ctx context.Context, user *models.User) error {
+				assert.Equal(t, models.RoleUser, user.Role)
+				return nil
+			},
+		}
+		controller.Storage = mockStrg
+
+		body := []byte(`{"username":"admin","password":"password"}`)
+		rr, _ := sendRequest(http.MethodPost, "/api/auth", body)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
 }
@@ -1,6 +1,7 @@
 package buy
 
 import (
+	"avito/internal/app/services/catalog"
 	"avito/internal/cache"
 	"avito/internal/models"
 	"avito/internal/storage"
@@ -63,6 +64,14 @@ func (bc *BuyController) Buy(w http.ResponseWriter, r *http.Request) {
 			response := models.ErrorResponse{Errors: storage.ErrItemNotFound.Error()}
 			utils.JsonResponse(w, http.StatusInternalServerError, response)
 			return
+		case errors.Is(err, storage.ErrOutOfStock):
+			response := models.ErrorResponse{Errors: storage.ErrOutOfStock.Error()}
+			utils.JsonResponse(w, http.StatusConflict, response)
+			return
+		case errors.Is(err, storage.ErrItemNotAvailable):
+			response := models.ErrorResponse{Errors: storage.ErrItemNotAvailable.Error()}
+			utils.JsonResponse(w, http.StatusConflict, response)
+			return
 		case errors.Is(err, storage.ErrNotEnoughBalance):
 			response := models.ErrorResponse{Errors: storage.ErrNotEnoughBalance.Error()}
 			utils.JsonResponse(w, http.StatusInternalServerError, response)
@@ -75,6 +84,9 @@ func (bc *BuyController) Buy(w http.ResponseWriter, r *http.Request) {
 	}
 
 	bc.Lfu.Delete(uuid)
+	// stock of limited items is part of the cached catalog
+	bc.Lfu.Delete(catalog.CacheKey)
+	bc.Lfu.Delete(catalog.ItemCacheKey(item))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 }
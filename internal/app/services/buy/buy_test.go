@@ -113,6 +113,28 @@ func TestBuyController_Buy(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 	})
 
+	t.Run("out of stock or not available -> 409", func(t *testing.T) {
+		token, _ := jwtToken.BuidToken("user-uuid-123")
+		for _, storageErr := range []error{storage.ErrOutOfStock, storage.ErrItemNotAvailable} {
+			controller.Storage = &mockStorage{
+				BuyFunc: func(ctx context.Context, uuid, item string) error {
+					return storageErr
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/buy/pink-hoody", nil)
+			req = mux.SetURLVars(req, map[string]string{"item": "pink-hoody"})
+			req.Header.Set("Authorization", token)
+			w := httptest.NewRecorder()
+
+			controller.Buy(w, req)
+
+			resp := w.Result()
+			resp.Body.Close()
+			assert.Equal(t, http.StatusConflict, resp.StatusCode, storageErr.Error())
+		}
+	})
+
 	t.Run("not enough balance -> 500", func(t *testing.T) {
 		mockSt := &mockStorage{
 			BuyFunc: func(ctx context.Context, uuid, item string) error {
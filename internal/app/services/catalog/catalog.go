@@ -0,0 +1,117 @@
+package catalog
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// keys in the LFU cache, user keys are uuids so they never collide
+const CacheKey = "catalog"
+
+func ItemCacheKey(item string) string {
+	return CacheKey + ":" + item
+}
+
+const cacheControl = "public, max-age=60"
+
+type Storage interface {
+	GetCatalog(ctx context.Context) ([]models.Merch, error)
+	GetMerch(ctx context.Context, item string) (*models.Merch, error)
+}
+
+type CatalogController struct {
+	Storage Storage
+	Lfu     *cache.LFUCache
+}
+
+func (cc *CatalogController) Catalog(w http.ResponseWriter, r *http.Request) {
+	if val, ok := cc.Lfu.Get(CacheKey); ok {
+		writeCacheable(w, r, []byte(val.(string)))
+		return
+	}
+
+	items, err := cc.Storage.GetCatalog(r.Context())
+	if err != nil {
+		response := models.ErrorResponse{Errors: "error getting catalog"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+	if items == nil {
+		items = []models.Merch{}
+	}
+
+	body, err := json.Marshal(items)
+	if err != nil {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+	cc.Lfu.Set(CacheKey, string(body))
+
+	writeCacheable(w, r, body)
+}
+
+func (cc *CatalogController) CatalogItem(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	item, ok := vars["item"]
+	if !ok || item == "" {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	key := ItemCacheKey(item)
+	if val, ok := cc.Lfu.Get(key); ok {
+		writeCacheable(w, r, []byte(val.(string)))
+		return
+	}
+
+	merch, err := cc.Storage.GetMerch(r.Context(), item)
+	if err != nil {
+		if errors.Is(err, storage.ErrItemNotFound) {
+			response := models.ErrorResponse{Errors: storage.ErrItemNotFound.Error()}
+			utils.JsonResponse(w, http.StatusNotFound, response)
+			return
+		}
+		response := models.ErrorResponse{Errors: "error getting item"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	body, err := json.Marshal(merch)
+	if err != nil {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+	cc.Lfu.Set(key, string(body))
+
+	writeCacheable(w, r, body)
+}
+
+// writeCacheable sets HTTP caching headers and answers 304 when the client
+// already has the same version of the body.
+func writeCacheable(w http.ResponseWriter, r *http.Request, body []byte) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
@@ -0,0 +1,114 @@
+package catalog
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type mockStorage struct {
+	GetCatalogFunc func(ctx context.Context) ([]models.Merch, error)
+	GetMerchFunc   func(ctx context.Context, item string) (*models.Merch, error)
+}
+
+func (m *mockStorage) GetCatalog(ctx context.Context) ([]models.Merch, error) {
+	return m.GetCatalogFunc(ctx)
+}
+
+func (m *mockStorage) GetMerch(ctx context.Context, item string) (*models.Merch, error) {
+	return m.GetMerchFunc(ctx, item)
+}
+
+func TestCatalogController_Catalog(t *testing.T) {
+	lfu := cache.NewLFUCache(10)
+	calls := 0
+	mockSt := &mockStorage{
+		GetCatalogFunc: func(ctx context.Context) ([]models.Merch, error) {
+			calls++
+			return []models.Merch{{Name: "pen", Price: 10, Description: "Ballpoint pen", Available: true}}, nil
+		},
+	}
+	controller := &CatalogController{Storage: mockSt, Lfu: lfu}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/merch", nil)
+	w := httptest.NewRecorder()
+	controller.Catalog(w, req)
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, cacheControl, resp.Header.Get("Cache-Control"))
+	var items []models.Merch
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&items))
+	assert.Equal(t, "pen", items[0].Name)
+	etag := resp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	t.Run("served from cache with 304 on matching ETag", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/merch", nil)
+		req.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+		controller.Catalog(w, req)
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("storage error -> 500", func(t *testing.T) {
+		lfu.Delete(CacheKey)
+		mockSt.GetCatalogFunc = func(ctx context.Context) ([]models.Merch, error) {
+			return nil, errors.New("some error")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/api/merch", nil)
+		w := httptest.NewRecorder()
+		controller.Catalog(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestCatalogController_CatalogItem(t *testing.T) {
+	mockSt := &mockStorage{}
+	controller := &CatalogController{Storage: mockSt, Lfu: cache.NewLFUCache(10)}
+
+	doRequest := func(item string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/merch/"+item, nil)
+		req = mux.SetURLVars(req, map[string]string{"item": item})
+		w := httptest.NewRecorder()
+		controller.CatalogItem(w, req)
+		return w
+	}
+
+	t.Run("item found -> 200", func(t *testing.T) {
+		mockSt.GetMerchFunc = func(ctx context.Context, item string) (*models.Merch, error) {
+			assert.Equal(t, "cup", item)
+			return &models.Merch{Name: "cup", Price: 20, Available: true}, nil
+		}
+
+		w := doRequest("cup")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var merch models.Merch
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &merch))
+		assert.Equal(t, 20, merch.Price)
+	})
+
+	t.Run("item not found -> 404", func(t *testing.T) {
+		mockSt.GetMerchFunc = func(ctx context.Context, item string) (*models.Merch, error) {
+			return nil, storage.ErrItemNotFound
+		}
+
+		w := doRequest("unknown")
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
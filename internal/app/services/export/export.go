@@ -0,0 +1,218 @@
+package export
+
+import (
+	"avito/internal/logger"
+	"avito/internal/models"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/schema"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// rows written between flushes
+const flushEvery = 100
+
+var csvHeader = []string{"time", "type", "id", "from", "to", "item", "quantity", "amount", "message", "category"}
+
+type Storage interface {
+	ExportActivity(ctx context.Context, uuid string, filter models.ExportFilter, fn func(models.ActivityRecord) error) error
+}
+
+type ExportController struct {
+	Storage Storage
+}
+
+type ExportParams struct {
+	Format string `schema:"format" validate:"omitempty,oneof=csv ndjson"`
+	From   string `schema:"from"`
+	To     string `schema:"to"`
+}
+
+// Export streams the activity of the authenticated user.
+func (ec *ExportController) Export(w http.ResponseWriter, r *http.Request) {
+	cookie := r.Header.Get("Authorization")
+	uuid := jwtToken.GetUserID(cookie)
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+	ec.export(w, r, uuid)
+}
+
+// ExportAll streams the activity of every user, it is for admins only.
+func (ec *ExportController) ExportAll(w http.ResponseWriter, r *http.Request) {
+	ec.export(w, r, "")
+}
+
+func (ec *ExportController) export(w http.ResponseWriter, r *http.Request, uuid string) {
+	decoder := schema.NewDecoder()
+	validate := validator.New()
+
+	var params ExportParams
+	err := decoder.Decode(&params, r.URL.Query())
+	errValidate := validate.Struct(params)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	filter, err := params.filter()
+	if err != nil {
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	format := params.Format
+	if format == "" {
+		format = models.ExportCSV
+	}
+	out := newRecordWriter(w, format)
+
+	// the status is sent with the first row, until then a failure is still
+	// reported as an error response
+	err = ec.Storage.ExportActivity(r.Context(), uuid, filter, out.write)
+	if err != nil && !out.started {
+		response := models.ErrorResponse{Errors: "error exporting activity"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+	if err != nil {
+		// the client sees a truncated file
+		logger.Log.Error("export interrupted", zap.String("URI", r.RequestURI), zap.Error(err))
+		return
+	}
+	if err = out.finish(); err != nil {
+		logger.Log.Error("export interrupted", zap.String("URI", r.RequestURI), zap.Error(err))
+	}
+}
+
+func (p ExportParams) filter() (models.ExportFilter, error) {
+	var filter models.ExportFilter
+	if p.From != "" {
+		from, _, err := utils.ParseTime(p.From)
+		if err != nil {
+			return filter, errors.New("invalid from date")
+		}
+		filter.From = &from
+	}
+	if p.To != "" {
+		to, dateOnly, err := utils.ParseTime(p.To)
+		if err != nil {
+			return filter, errors.New("invalid to date")
+		}
+		// a plain date includes the whole day
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, errors.New("from must be before to")
+	}
+	return filter, nil
+}
+
+// recordWriter encodes records straight into the response and flushes them
+// in batches, so nothing is buffered beyond one batch.
+type recordWriter struct {
+	w       http.ResponseWriter
+	format  string
+	csv     *csv.Writer
+	json    *json.Encoder
+	rows    int
+	started bool
+}
+
+func newRecordWriter(w http.ResponseWriter, format string) *recordWriter {
+	out := &recordWriter{w: w, format: format}
+	if format == models.ExportCSV {
+		out.csv = csv.NewWriter(w)
+	} else {
+		out.json = json.NewEncoder(w)
+	}
+	return out
+}
+
+func (o *recordWriter) start() error {
+	o.started = true
+	if o.format == models.ExportCSV {
+		o.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		o.w.Header().Set("Content-Disposition", `attachment; filename="activity.csv"`)
+		o.w.WriteHeader(http.StatusOK)
+		return o.csv.Write(csvHeader)
+	}
+	o.w.Header().Set("Content-Type", "application/x-ndjson")
+	o.w.Header().Set("Content-Disposition", `attachment; filename="activity.ndjson"`)
+	o.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (o *recordWriter) write(rec models.ActivityRecord) error {
+	if !o.started {
+		if err := o.start(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if o.format == models.ExportCSV {
+		err = o.csv.Write([]string{
+			rec.Time.UTC().Format(time.RFC3339),
+			rec.Type,
+			strconv.Itoa(rec.ID),
+			rec.From,
+			rec.To,
+			rec.Item,
+			strconv.Itoa(rec.Quantity),
+			strconv.Itoa(rec.Amount),
+			rec.Message,
+			rec.Category,
+		})
+	} else {
+		err = o.json.Encode(rec)
+	}
+	if err != nil {
+		return err
+	}
+
+	o.rows++
+	if o.rows%flushEvery == 0 {
+		return o.flush()
+	}
+	return nil
+}
+
+// finish sends the headers of an empty export and the last batch
+func (o *recordWriter) finish() error {
+	if !o.started {
+		if err := o.start(); err != nil {
+			return err
+		}
+	}
+	return o.flush()
+}
+
+func (o *recordWriter) flush() error {
+	if o.csv != nil {
+		o.csv.Flush()
+		if err := o.csv.Error(); err != nil {
+			return err
+		}
+	}
+	err := http.NewResponseController(o.w).Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,151 @@
+package export
+
+import (
+	"avito/internal/models"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockStorage struct {
+	ExportActivityFunc func(ctx context.Context, uuid string, filter models.ExportFilter, fn func(models.ActivityRecord) error) error
+}
+
+func (m *mockStorage) ExportActivity(ctx context.Context, uuid string, filter models.ExportFilter, fn func(models.ActivityRecord) error) error {
+	return m.ExportActivityFunc(ctx, uuid, filter, fn)
+}
+
+var records = []models.ActivityRecord{
+	{Time: time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC), Type: models.ActivityTransfer, ID: 1, From: "alice", To: "bob", Amount: 50, Message: "thanks, bob", Category: models.CategoryThanks},
+	{Time: time.Date(2025, 2, 2, 10, 0, 0, 0, time.UTC), Type: models.ActivityPurchase, ID: 3, From: "alice", Item: "cup", Quantity: 2, Amount: 40},
+}
+
+func TestExportController_Export(t *testing.T) {
+	mockSt := &mockStorage{}
+	controller := &ExportController{Storage: mockSt}
+	token, _ := jwtToken.BuidToken("uuid-123")
+
+	doRequest := func(url string, token string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		w := httptest.NewRecorder()
+		controller.Export(w, req)
+		return w.Result()
+	}
+
+	t.Run("invalid params -> 400", func(t *testing.T) {
+		for _, url := range []string{
+			"/api/export?format=xml",
+			"/api/export?from=yesterday",
+			"/api/export?from=2025-03-01&to=2025-02-01",
+		} {
+			resp := doRequest(url, token)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, url)
+		}
+	})
+
+	t.Run("no JWT -> 500", func(t *testing.T) {
+		resp := doRequest("/api/export", "")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		mockSt.ExportActivityFunc = func(ctx context.Context, uuid string, filter models.ExportFilter, fn func(models.ActivityRecord) error) error {
+			assert.Equal(t, "uuid-123", uuid)
+			assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), *filter.From)
+			assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), *filter.To)
+			for _, rec := range records {
+				if err := fn(rec); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		resp := doRequest("/api/export?from=2025-02-01&to=2025-02-28", token)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "time,type,id,from,to,item,quantity,amount,message,category\n"+
+			"2025-02-01T10:00:00Z,transfer,1,alice,bob,,0,50,\"thanks, bob\",thanks\n"+
+			"2025-02-02T10:00:00Z,purchase,3,alice,,cup,2,40,,\n", string(body))
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		mockSt.ExportActivityFunc = func(ctx context.Context, uuid string, filter models.ExportFilter, fn func(models.ActivityRecord) error) error {
+			assert.Nil(t, filter.From)
+			assert.Nil(t, filter.To)
+			for _, rec := range records {
+				if err := fn(rec); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		resp := doRequest("/api/export?format=ndjson", token)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+		lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+		assert.Len(t, lines, 2)
+		assert.JSONEq(t, `{"time":"2025-02-02T10:00:00Z","type":"purchase","id":3,"from":"alice","item":"cup","quantity":2,"amount":40}`, lines[1])
+	})
+
+	t.Run("empty export still has the header", func(t *testing.T) {
+		mockSt.ExportActivityFunc = func(ctx context.Context, uuid string, filter models.ExportFilter, fn func(models.ActivityRecord) error) error {
+			return nil
+		}
+
+		resp := doRequest("/api/export", token)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "time,type,id,from,to,item,quantity,amount,message,category\n", string(body))
+	})
+
+	t.Run("storage error before the first row -> 500", func(t *testing.T) {
+		mockSt.ExportActivityFunc = func(ctx context.Context, uuid string, filter models.ExportFilter, fn func(models.ActivityRecord) error) error {
+			return errors.New("db error")
+		}
+
+		resp := doRequest("/api/export", token)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+}
+
+func TestExportController_ExportAll(t *testing.T) {
+	mockSt := &mockStorage{
+		ExportActivityFunc: func(ctx context.Context, uuid string, filter models.ExportFilter, fn func(models.ActivityRecord) error) error {
+			assert.Empty(t, uuid)
+			return fn(records[0])
+		},
+	}
+	controller := &ExportController{Storage: mockSt}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/export?format=ndjson", nil)
+	w := httptest.NewRecorder()
+	controller.ExportAll(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"to":"bob"`)
+}
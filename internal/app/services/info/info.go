@@ -13,6 +13,7 @@ import (
 
 type Storage interface {
 	GetInfo(ctx context.Context, uuid string) (*models.Info, error)
+	GetCounterparties(ctx context.Context, uuid string) (*models.Counterparties, error)
 }
 
 type InfoController struct {
@@ -57,3 +58,22 @@ func (ic *InfoController) Info(w http.ResponseWriter, r *http.Request) {
 
 	utils.JsonResponse(w, http.StatusOK, info)
 }
+
+// Counterparties returns the coin history grouped by who sent or received the coins.
+func (ic *InfoController) Counterparties(w http.ResponseWriter, r *http.Request) {
+	cookie := r.Header.Get("Authorization")
+	uuid := jwtToken.GetUserID(cookie)
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error."}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	counterparties, err := ic.Storage.GetCounterparties(r.Context(), uuid)
+	if err != nil {
+		response := models.ErrorResponse{Errors: "error getting counterparties"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+	utils.JsonResponse(w, http.StatusOK, counterparties)
+}
@@ -14,13 +14,18 @@ import (
 )
 
 type mockStorage struct {
-	GetInfoFunc func(ctx context.Context, uuid string) (*models.Info, error)
+	GetInfoFunc           func(ctx context.Context, uuid string) (*models.Info, error)
+	GetCounterpartiesFunc func(ctx context.Context, uuid string) (*models.Counterparties, error)
 }
 
 func (m *mockStorage) GetInfo(ctx context.Context, uuid string) (*models.Info, error) {
 	return m.GetInfoFunc(ctx, uuid)
 }
 
+func (m *mockStorage) GetCounterparties(ctx context.Context, uuid string) (*models.Counterparties, error) {
+	return m.GetCounterpartiesFunc(ctx, uuid)
+}
+
 func TestInfoController_Info(t *testing.T) {
 	lfu := cache.NewLFUCache(10)
 	controller := &InfoController{
@@ -100,3 +105,49 @@ func TestInfoController_Info(t *testing.T) {
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 	})
 }
+
+func TestInfoController_Counterparties(t *testing.T) {
+	mockSt := &mockStorage{}
+	controller := &InfoController{Storage: mockSt, Lfu: cache.NewLFUCache(10)}
+	token, _ := jwtToken.BuidToken("uuid-123")
+
+	doRequest := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/info/counterparties", nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		w := httptest.NewRecorder()
+		controller.Counterparties(w, req)
+		return w
+	}
+
+	t.Run("no JWT -> 500", func(t *testing.T) {
+		w := doRequest("")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("storage error -> 500", func(t *testing.T) {
+		mockSt.GetCounterpartiesFunc = func(ctx context.Context, uuid string) (*models.Counterparties, error) {
+			return nil, errors.New("db error")
+		}
+		w := doRequest(token)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("success -> 200", func(t *testing.T) {
+		expected := &models.Counterparties{
+			Received: []models.CounterpartyTotal{{User: "bob", Amount: 70, Count: 2}},
+			Sent:     []models.CounterpartyTotal{},
+		}
+		mockSt.GetCounterpartiesFunc = func(ctx context.Context, uuid string) (*models.Counterparties, error) {
+			assert.Equal(t, "uuid-123", uuid)
+			return expected, nil
+		}
+
+		w := doRequest(token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got models.Counterparties
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, *expected, got)
+	})
+}
@@ -0,0 +1,171 @@
+package listings
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/schema"
+	"net/http"
+	"strconv"
+)
+
+type Storage interface {
+	CreateListing(ctx context.Context, uuid string, item string, quantity int, price int) (*models.Listing, error)
+	GetListings(ctx context.Context, uuid string, filter models.ListingFilter) ([]models.Listing, error)
+	BuyListing(ctx context.Context, uuid string, listingID int) (*models.Listing, error)
+	CancelListing(ctx context.Context, uuid string, listingID int) (*models.Listing, error)
+	GetUuidByUsername(ctx context.Context, username string) (string, error)
+}
+
+type ListingController struct {
+	Storage Storage
+	Lfu     *cache.LFUCache
+}
+
+type ListingRequest struct {
+	Item string `json:"item" validate:"required"`
+	// the whole quantity is sold at once, one item when it is omitted
+	Quantity int `json:"quantity" validate:"omitempty,gt=0"`
+	Price    int `json:"price" validate:"required,gt=0"`
+}
+
+type ListingsParams struct {
+	Item string `schema:"item"`
+	Mine bool   `schema:"mine"`
+}
+
+// CreateListing puts items of the user's inventory up for sale.
+func (lc *ListingController) CreateListing(w http.ResponseWriter, r *http.Request) {
+	var req ListingRequest
+
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+	listing, err := lc.Storage.CreateListing(r.Context(), uuid, req.Item, req.Quantity, req.Price)
+	if err != nil {
+		listingError(w, err)
+		return
+	}
+	lc.Lfu.Delete(uuid)
+	utils.JsonResponse(w, http.StatusOK, listing)
+}
+
+// Listings returns the active listings, or with mine=true the user's own
+// listings and purchases in every status.
+func (lc *ListingController) Listings(w http.ResponseWriter, r *http.Request) {
+	decoder := schema.NewDecoder()
+
+	var params ListingsParams
+	err := decoder.Decode(&params, r.URL.Query())
+	if err != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+	listings, err := lc.Storage.GetListings(r.Context(), uuid, models.ListingFilter{Item: params.Item, Mine: params.Mine})
+	if err != nil {
+		listingError(w, err)
+		return
+	}
+	if listings == nil {
+		listings = []models.Listing{}
+	}
+	utils.JsonResponse(w, http.StatusOK, listings)
+}
+
+func (lc *ListingController) BuyListing(w http.ResponseWriter, r *http.Request) {
+	lc.close(w, r, lc.Storage.BuyListing)
+}
+
+func (lc *ListingController) CancelListing(w http.ResponseWriter, r *http.Request) {
+	lc.close(w, r, lc.Storage.CancelListing)
+}
+
+func (lc *ListingController) close(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, uuid string, listingID int) (*models.Listing, error)) {
+	listingID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || listingID <= 0 {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+
+	listing, err := fn(r.Context(), uuid, listingID)
+	if err != nil {
+		listingError(w, err)
+		return
+	}
+
+	sellerUuid, err := lc.Storage.GetUuidByUsername(r.Context(), listing.Seller)
+	if err != nil {
+		lc.Lfu.ClearCache()
+	}
+	lc.Lfu.Delete(sellerUuid)
+	lc.Lfu.Delete(uuid)
+	utils.JsonResponse(w, http.StatusOK, listing)
+}
+
+func userUuid(w http.ResponseWriter, r *http.Request) (string, bool) {
+	uuid := jwtToken.GetUserID(r.Header.Get("Authorization"))
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return "", false
+	}
+	return uuid, true
+}
+
+func listingError(w http.ResponseWriter, err error) {
+	var limitErr *storage.LimitError
+	switch {
+	case errors.As(err, &limitErr):
+		response := models.LimitErrorResponse{Errors: limitErr.Error(), Limit: limitErr.Limit, Remaining: limitErr.Remaining}
+		utils.JsonResponse(w, http.StatusTooManyRequests, response)
+	case errors.Is(err, storage.ErrListingNotFound):
+		response := models.ErrorResponse{Errors: storage.ErrListingNotFound.Error()}
+		utils.JsonResponse(w, http.StatusNotFound, response)
+	case errors.Is(err, storage.ErrItemNotFound),
+		errors.Is(err, storage.ErrBuyingOwnListing),
+		errors.Is(err, storage.ErrInvalidQuantity),
+		errors.Is(err, storage.ErrInvalidAmount):
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+	case errors.Is(err, storage.ErrListingClosed),
+		errors.Is(err, storage.ErrNotInInventory),
+		errors.Is(err, storage.ErrNotEnoughBalance):
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusConflict, response)
+	default:
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+	}
+}
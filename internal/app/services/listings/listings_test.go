@@ -0,0 +1,164 @@
+package listings
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type mockStorage struct {
+	CreateListingFunc     func(ctx context.Context, uuid string, item string, quantity int, price int) (*models.Listing, error)
+	GetListingsFunc       func(ctx context.Context, uuid string, filter models.ListingFilter) ([]models.Listing, error)
+	BuyListingFunc        func(ctx context.Context, uuid string, listingID int) (*models.Listing, error)
+	CancelListingFunc     func(ctx context.Context, uuid string, listingID int) (*models.Listing, error)
+	GetUuidByUsernameFunc func(ctx context.Context, username string) (string, error)
+}
+
+func (m *mockStorage) CreateListing(ctx context.Context, uuid string, item string, quantity int, price int) (*models.Listing, error) {
+	return m.CreateListingFunc(ctx, uuid, item, quantity, price)
+}
+
+func (m *mockStorage) GetListings(ctx context.Context, uuid string, filter models.ListingFilter) ([]models.Listing, error) {
+	return m.GetListingsFunc(ctx, uuid, filter)
+}
+
+func (m *mockStorage) BuyListing(ctx context.Context, uuid string, listingID int) (*models.Listing, error) {
+	return m.BuyListingFunc(ctx, uuid, listingID)
+}
+
+func (m *mockStorage) CancelListing(ctx context.Context, uuid string, listingID int) (*models.Listing, error) {
+	return m.CancelListingFunc(ctx, uuid, listingID)
+}
+
+func (m *mockStorage) GetUuidByUsername(ctx context.Context, username string) (string, error) {
+	return m.GetUuidByUsernameFunc(ctx, username)
+}
+
+func TestListingController(t *testing.T) {
+	mockSt := &mockStorage{}
+	lfu := cache.NewLFUCache(10)
+	controller := &ListingController{Storage: mockSt, Lfu: lfu}
+	token, _ := jwtToken.BuidToken("uuid-123")
+
+	doRequest := func(handler http.HandlerFunc, method, target, id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		if id != "" {
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+		}
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	t.Run("create with invalid params -> 400", func(t *testing.T) {
+		for _, body := range []string{`{"item":"hoody"}`, `{"price":100}`, `{"item":"hoody","price":100,"quantity":-1}`} {
+			assert.Equal(t, http.StatusBadRequest, doRequest(controller.CreateListing, http.MethodPost, "/api/listings", "", body).Code, body)
+		}
+	})
+
+	t.Run("create lists one item by default", func(t *testing.T) {
+		mockSt.CreateListingFunc = func(ctx context.Context, uuid string, item string, quantity int, price int) (*models.Listing, error) {
+			assert.Equal(t, "uuid-123", uuid)
+			assert.Equal(t, "hoody", item)
+			assert.Equal(t, 1, quantity)
+			assert.Equal(t, 250, price)
+			return &models.Listing{ID: 1, Seller: "alice", Item: item, Quantity: quantity, Price: price, Status: models.ListingStatusActive}, nil
+		}
+		lfu.Set("uuid-123", "{}")
+
+		w := doRequest(controller.CreateListing, http.MethodPost, "/api/listings", "", `{"item":"hoody","price":250}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		_, ok := lfu.Get("uuid-123")
+		assert.False(t, ok)
+	})
+
+	t.Run("create without the item -> 409", func(t *testing.T) {
+		mockSt.CreateListingFunc = func(ctx context.Context, uuid string, item string, quantity int, price int) (*models.Listing, error) {
+			return nil, storage.ErrNotInInventory
+		}
+		assert.Equal(t, http.StatusConflict, doRequest(controller.CreateListing, http.MethodPost, "/api/listings", "", `{"item":"hoody","price":250}`).Code)
+	})
+
+	t.Run("list passes the filter", func(t *testing.T) {
+		mockSt.GetListingsFunc = func(ctx context.Context, uuid string, filter models.ListingFilter) ([]models.Listing, error) {
+			assert.Equal(t, models.ListingFilter{Item: "pen", Mine: true}, filter)
+			return nil, nil
+		}
+		w := doRequest(controller.Listings, http.MethodGet, "/api/listings?item=pen&mine=true", "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+	})
+
+	t.Run("buy invalidates both users", func(t *testing.T) {
+		mockSt.BuyListingFunc = func(ctx context.Context, uuid string, listingID int) (*models.Listing, error) {
+			assert.Equal(t, 3, listingID)
+			return &models.Listing{ID: 3, Seller: "alice", Buyer: "bob", Status: models.ListingStatusSold, TransactionID: 7}, nil
+		}
+		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
+			assert.Equal(t, "alice", username)
+			return "uuid-alice", nil
+		}
+		lfu.Set("uuid-123", "{}")
+		lfu.Set("uuid-alice", "{}")
+
+		assert.Equal(t, http.StatusBadRequest, doRequest(controller.BuyListing, http.MethodPost, "/api/listings/x/buy", "x", "").Code)
+		w := doRequest(controller.BuyListing, http.MethodPost, "/api/listings/3/buy", "3", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var listing models.Listing
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&listing))
+		assert.Equal(t, models.ListingStatusSold, listing.Status)
+		_, ok := lfu.Get("uuid-123")
+		assert.False(t, ok)
+		_, ok = lfu.Get("uuid-alice")
+		assert.False(t, ok)
+	})
+
+	t.Run("buy errors", func(t *testing.T) {
+		for err, code := range map[error]int{
+			storage.ErrListingNotFound:  http.StatusNotFound,
+			storage.ErrListingClosed:    http.StatusConflict,
+			storage.ErrNotEnoughBalance: http.StatusConflict,
+			storage.ErrBuyingOwnListing: http.StatusBadRequest,
+		} {
+			mockSt.BuyListingFunc = func(ctx context.Context, uuid string, listingID int) (*models.Listing, error) {
+				return nil, err
+			}
+			assert.Equal(t, code, doRequest(controller.BuyListing, http.MethodPost, "/api/listings/3/buy", "3", "").Code, err.Error())
+		}
+	})
+
+	t.Run("buy over the sending limit -> 429", func(t *testing.T) {
+		mockSt.BuyListingFunc = func(ctx context.Context, uuid string, listingID int) (*models.Listing, error) {
+			return nil, &storage.LimitError{Limit: models.LimitDaily, Remaining: 50}
+		}
+		w := doRequest(controller.BuyListing, http.MethodPost, "/api/listings/3/buy", "3", "")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+		var response models.LimitErrorResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, models.LimitDaily, response.Limit)
+		assert.Equal(t, 50, response.Remaining)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		mockSt.CancelListingFunc = func(ctx context.Context, uuid string, listingID int) (*models.Listing, error) {
+			return &models.Listing{ID: 3, Seller: "alice", Status: models.ListingStatusCancelled}, nil
+		}
+		assert.Equal(t, http.StatusOK, doRequest(controller.CancelListing, http.MethodPost, "/api/listings/3/cancel", "3", "").Code)
+
+		mockSt.CancelListingFunc = func(ctx context.Context, uuid string, listingID int) (*models.Listing, error) {
+			return nil, storage.ErrListingClosed
+		}
+		assert.Equal(t, http.StatusConflict, doRequest(controller.CancelListing, http.MethodPost, "/api/listings/3/cancel", "3", "").Code)
+	})
+}
@@ -0,0 +1,90 @@
+package orders
+
+import (
+	"avito/internal/app/services/catalog"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"strings"
+)
+
+type GiftRequest struct {
+	ToUser  string      `json:"toUser" validate:"required"`
+	Items   []OrderItem `json:"items" validate:"required,min=1,dive"`
+	Message string      `json:"message" validate:"max=200"`
+}
+
+// SendGift buys the cart for another user, the items go to their inventory.
+func (oc *OrderController) SendGift(w http.ResponseWriter, r *http.Request) {
+	var req GiftRequest
+
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	cookie := r.Header.Get("Authorization")
+	uuid := jwtToken.GetUserID(cookie)
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	lines := make([]models.OrderLine, 0, len(req.Items))
+	for _, item := range req.Items {
+		lines = append(lines, models.OrderLine{Item: item.Item, Quantity: item.Quantity})
+	}
+	order, err := oc.Storage.SendGift(r.Context(), uuid, req.ToUser, lines, strings.TrimSpace(req.Message))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUserNotFound), errors.Is(err, storage.ErrGiftToYourself):
+			response := models.ErrorResponse{Errors: err.Error()}
+			utils.JsonResponse(w, http.StatusBadRequest, response)
+			return
+		// the storage error names the item that failed the cart
+		case errors.Is(err, storage.ErrItemNotFound):
+			response := models.ErrorResponse{Errors: err.Error()}
+			utils.JsonResponse(w, http.StatusNotFound, response)
+			return
+		case errors.Is(err, storage.ErrOutOfStock), errors.Is(err, storage.ErrItemNotAvailable):
+			response := models.ErrorResponse{Errors: err.Error()}
+			utils.JsonResponse(w, http.StatusConflict, response)
+			return
+		case errors.Is(err, storage.ErrNotEnoughBalance):
+			response := models.ErrorResponse{Errors: err.Error()}
+			utils.JsonResponse(w, http.StatusInternalServerError, response)
+			return
+		case errors.Is(err, storage.ErrEmptyOrder), errors.Is(err, storage.ErrInvalidQuantity):
+			response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+			utils.JsonResponse(w, http.StatusBadRequest, response)
+			return
+		default:
+			response := models.ErrorResponse{Errors: "error sending gift"}
+			utils.JsonResponse(w, http.StatusInternalServerError, response)
+			return
+		}
+	}
+
+	recipientUuid, err := oc.Storage.GetUuidByUsername(r.Context(), req.ToUser)
+	if err != nil {
+		oc.Lfu.ClearCache()
+	}
+	oc.Lfu.Delete(recipientUuid)
+	oc.Lfu.Delete(uuid)
+	oc.Lfu.Delete(catalog.CacheKey)
+	for _, line := range order.Items {
+		oc.Lfu.Delete(catalog.ItemCacheKey(line.Item))
+	}
+	utils.JsonResponse(w, http.StatusOK, order)
+}
@@ -0,0 +1,95 @@
+package orders
+
+import (
+	"avito/internal/app/services/catalog"
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+)
+
+type Storage interface {
+	CreateOrder(ctx context.Context, uuid string, lines []models.OrderLine) (*models.Order, error)
+	SendGift(ctx context.Context, uuid string, toUser string, lines []models.OrderLine, message string) (*models.Order, error)
+	GetUuidByUsername(ctx context.Context, username string) (string, error)
+}
+
+type OrderController struct {
+	Storage Storage
+	Lfu     *cache.LFUCache
+}
+
+type OrderItem struct {
+	Item     string `json:"item" validate:"required"`
+	Quantity int    `json:"quantity" validate:"required,gt=0,max=10000"`
+}
+
+type OrderRequest struct {
+	Items []OrderItem `json:"items" validate:"required,min=1,dive"`
+}
+
+func (oc *OrderController) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	var req OrderRequest
+
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	cookie := r.Header.Get("Authorization")
+	uuid := jwtToken.GetUserID(cookie)
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	lines := make([]models.OrderLine, 0, len(req.Items))
+	for _, item := range req.Items {
+		lines = append(lines, models.OrderLine{Item: item.Item, Quantity: item.Quantity})
+	}
+	order, err := oc.Storage.CreateOrder(r.Context(), uuid, lines)
+	if err != nil {
+		switch {
+		// the storage error names the item that failed the cart
+		case errors.Is(err, storage.ErrItemNotFound):
+			response := models.ErrorResponse{Errors: err.Error()}
+			utils.JsonResponse(w, http.StatusNotFound, response)
+			return
+		case errors.Is(err, storage.ErrOutOfStock), errors.Is(err, storage.ErrItemNotAvailable):
+			response := models.ErrorResponse{Errors: err.Error()}
+			utils.JsonResponse(w, http.StatusConflict, response)
+			return
+		case errors.Is(err, storage.ErrNotEnoughBalance):
+			response := models.ErrorResponse{Errors: err.Error()}
+			utils.JsonResponse(w, http.StatusInternalServerError, response)
+			return
+		case errors.Is(err, storage.ErrEmptyOrder), errors.Is(err, storage.ErrInvalidQuantity):
+			response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+			utils.JsonResponse(w, http.StatusBadRequest, response)
+			return
+		default:
+			response := models.ErrorResponse{Errors: "error placing order"}
+			utils.JsonResponse(w, http.StatusInternalServerError, response)
+			return
+		}
+	}
+
+	oc.Lfu.Delete(uuid)
+	oc.Lfu.Delete(catalog.CacheKey)
+	for _, line := range order.Items {
+		oc.Lfu.Delete(catalog.ItemCacheKey(line.Item))
+	}
+	utils.JsonResponse(w, http.StatusOK, order)
+}
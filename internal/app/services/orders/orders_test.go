@@ -0,0 +1,193 @@
+package orders
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type mockStorage struct {
+	CreateOrderFunc       func(ctx context.Context, uuid string, lines []models.OrderLine) (*models.Order, error)
+	SendGiftFunc          func(ctx context.Context, uuid string, toUser string, lines []models.OrderLine, message string) (*models.Order, error)
+	GetUuidByUsernameFunc func(ctx context.Context, username string) (string, error)
+}
+
+func (m *mockStorage) CreateOrder(ctx context.Context, uuid string, lines []models.OrderLine) (*models.Order, error) {
+	return m.CreateOrderFunc(ctx, uuid, lines)
+}
+
+func (m *mockStorage) SendGift(ctx context.Context, uuid string, toUser string, lines []models.OrderLine, message string) (*models.Order, error) {
+	return m.SendGiftFunc(ctx, uuid, toUser, lines, message)
+}
+
+func (m *mockStorage) GetUuidByUsername(ctx context.Context, username string) (string, error) {
+	return m.GetUuidByUsernameFunc(ctx, username)
+}
+
+func TestOrderController_CreateOrder(t *testing.T) {
+	controller := &OrderController{
+		Storage: &mockStorage{},
+		Lfu:     cache.NewLFUCache(10),
+	}
+	token, _ := jwtToken.BuidToken("user-uuid-123")
+
+	t.Run("empty cart -> 400", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/orders", bytes.NewBufferString(`{"items":[]}`))
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		controller.CreateOrder(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("zero quantity -> 400", func(t *testing.T) {
+		body := `{"items":[{"item":"pen","quantity":0}]}`
+		req := httptest.NewRequest(http.MethodPost, "/api/orders", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		controller.CreateOrder(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("huge quantity -> 400", func(t *testing.T) {
+		body := `{"items":[{"item":"pen","quantity":1844674407370955061}]}`
+		req := httptest.NewRequest(http.MethodPost, "/api/orders", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		controller.CreateOrder(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("order success -> 200", func(t *testing.T) {
+		controller.Storage = &mockStorage{
+			CreateOrderFunc: func(ctx context.Context, uuid string, lines []models.OrderLine) (*models.Order, error) {
+				assert.Equal(t, "user-uuid-123", uuid)
+				assert.Equal(t, []models.OrderLine{{Item: "socks", Quantity: 5}, {Item: "pen", Quantity: 1}}, lines)
+				return &models.Order{
+					ID: 1,
+					Items: []models.OrderLine{
+						{Item: "socks", Quantity: 5, UnitPrice: 10},
+						{Item: "pen", Quantity: 1, UnitPrice: 10},
+					},
+					Total: 60,
+				}, nil
+			},
+		}
+
+		body := `{"items":[{"item":"socks","quantity":5},{"item":"pen","quantity":1}]}`
+		req := httptest.NewRequest(http.MethodPost, "/api/orders", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		controller.CreateOrder(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var order models.Order
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&order))
+		assert.Equal(t, 60, order.Total)
+		assert.Len(t, order.Items, 2)
+	})
+
+	t.Run("cart line errors -> 4xx with item", func(t *testing.T) {
+		for storageErr, code := range map[error]int{
+			storage.ErrItemNotFound:     http.StatusNotFound,
+			storage.ErrOutOfStock:       http.StatusConflict,
+			storage.ErrItemNotAvailable: http.StatusConflict,
+		} {
+			controller.Storage = &mockStorage{
+				CreateOrderFunc: func(ctx context.Context, uuid string, lines []models.OrderLine) (*models.Order, error) {
+					return nil, fmt.Errorf("%w: %s", storageErr, "pink-hoody")
+				},
+			}
+
+			body := `{"items":[{"item":"pink-hoody","quantity":2}]}`
+			req := httptest.NewRequest(http.MethodPost, "/api/orders", bytes.NewBufferString(body))
+			req.Header.Set("Authorization", token)
+			w := httptest.NewRecorder()
+			controller.CreateOrder(w, req)
+
+			assert.Equal(t, code, w.Code, storageErr.Error())
+			var response models.ErrorResponse
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			assert.Equal(t, storageErr.Error()+": pink-hoody", response.Errors)
+		}
+	})
+}
+
+func TestOrderController_SendGift(t *testing.T) {
+	mockSt := &mockStorage{}
+	lfu := cache.NewLFUCache(10)
+	controller := &OrderController{Storage: mockSt, Lfu: lfu}
+	token, _ := jwtToken.BuidToken("user-uuid-123")
+
+	doRequest := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/gifts", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		controller.SendGift(w, req)
+		return w
+	}
+
+	t.Run("invalid params -> 400", func(t *testing.T) {
+		for _, body := range []string{
+			`{"items":[{"item":"hoodie","quantity":1}]}`,
+			`{"toUser":"bob","items":[]}`,
+			`{"toUser":"bob","items":[{"item":"hoodie","quantity":0}]}`,
+			`{"toUser":"bob","items":[{"item":"hoodie","quantity":10001}]}`,
+		} {
+			assert.Equal(t, http.StatusBadRequest, doRequest(body).Code, body)
+		}
+	})
+
+	t.Run("gift invalidates both users", func(t *testing.T) {
+		mockSt.SendGiftFunc = func(ctx context.Context, uuid string, toUser string, lines []models.OrderLine, message string) (*models.Order, error) {
+			assert.Equal(t, "user-uuid-123", uuid)
+			assert.Equal(t, "bob", toUser)
+			assert.Equal(t, []models.OrderLine{{Item: "hoodie", Quantity: 1}}, lines)
+			assert.Equal(t, "happy birthday", message)
+			return &models.Order{ID: 4, Items: []models.OrderLine{{Item: "hoodie", Quantity: 1, UnitPrice: 300}}, Total: 300, ToUser: toUser, Message: message}, nil
+		}
+		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
+			return "uuid-bob", nil
+		}
+		lfu.Set("user-uuid-123", "{}")
+		lfu.Set("uuid-bob", "{}")
+
+		w := doRequest(`{"toUser":"bob","items":[{"item":"hoodie","quantity":1}],"message":" happy birthday "}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var order models.Order
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&order))
+		assert.Equal(t, "bob", order.ToUser)
+		_, ok := lfu.Get("user-uuid-123")
+		assert.False(t, ok)
+		_, ok = lfu.Get("uuid-bob")
+		assert.False(t, ok)
+	})
+
+	t.Run("bad recipient -> 400", func(t *testing.T) {
+		for _, err := range []error{storage.ErrUserNotFound, storage.ErrGiftToYourself} {
+			mockSt.SendGiftFunc = func(ctx context.Context, uuid string, toUser string, lines []models.OrderLine, message string) (*models.Order, error) {
+				return nil, err
+			}
+			assert.Equal(t, http.StatusBadRequest, doRequest(`{"toUser":"bob","items":[{"item":"hoodie","quantity":1}]}`).Code, err.Error())
+		}
+	})
+	t.Run("cart line errors -> 4xx", func(t *testing.T) {
+		for storageErr, code := range map[error]int{
+			storage.ErrItemNotFound: http.StatusNotFound,
+			storage.ErrOutOfStock:   http.StatusConflict,
+		} {
+			mockSt.SendGiftFunc = func(ctx context.Context, uuid string, toUser string, lines []models.OrderLine, message string) (*models.Order, error) {
+				return nil, fmt.Errorf("%w: %s", storageErr, "hoodie")
+			}
+			assert.Equal(t, code, doRequest(`{"toUser":"bob","items":[{"item":"hoodie","quantity":1}]}`).Code, storageErr.Error())
+		}
+	})
+}
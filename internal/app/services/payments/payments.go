@@ -0,0 +1,151 @@
+package payments
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type Storage interface {
+	CreatePaymentRequest(ctx context.Context, uuid string, payers []string, amount int, note models.TransferNote) (*models.PaymentRequest, error)
+	GetPaymentRequests(ctx context.Context, uuid string) ([]models.PaymentRequest, error)
+	PayPaymentRequest(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error)
+	RejectPaymentRequest(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error)
+	GetUuidByUsername(ctx context.Context, username string) (string, error)
+}
+
+type PaymentController struct {
+	Storage Storage
+	Lfu     *cache.LFUCache
+}
+
+type PaymentRequestBody struct {
+	Payers   []string `json:"payers" validate:"required,min=1,max=100"`
+	Amount   int      `json:"amount" validate:"required,gt=0"`
+	Message  string   `json:"message" validate:"max=200"`
+	Category string   `json:"category" validate:"omitempty,oneof=thanks help birthday teamwork"`
+}
+
+func (pc *PaymentController) CreatePaymentRequest(w http.ResponseWriter, r *http.Request) {
+	var req PaymentRequestBody
+
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+	request, err := pc.Storage.CreatePaymentRequest(r.Context(), uuid, req.Payers, req.Amount, models.TransferNote{
+		Message:  strings.TrimSpace(req.Message),
+		Category: req.Category,
+	})
+	if err != nil {
+		paymentError(w, err)
+		return
+	}
+	utils.JsonResponse(w, http.StatusOK, request)
+}
+
+func (pc *PaymentController) PaymentRequests(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+	requests, err := pc.Storage.GetPaymentRequests(r.Context(), uuid)
+	if err != nil {
+		paymentError(w, err)
+		return
+	}
+	if requests == nil {
+		requests = []models.PaymentRequest{}
+	}
+	utils.JsonResponse(w, http.StatusOK, requests)
+}
+
+func (pc *PaymentController) PayPaymentRequest(w http.ResponseWriter, r *http.Request) {
+	pc.answer(w, r, pc.Storage.PayPaymentRequest)
+}
+
+func (pc *PaymentController) RejectPaymentRequest(w http.ResponseWriter, r *http.Request) {
+	pc.answer(w, r, pc.Storage.RejectPaymentRequest)
+}
+
+func (pc *PaymentController) answer(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error)) {
+	requestID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || requestID <= 0 {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+
+	request, err := fn(r.Context(), uuid, requestID)
+	if err != nil {
+		paymentError(w, err)
+		return
+	}
+
+	requesterUuid, err := pc.Storage.GetUuidByUsername(r.Context(), request.Requester)
+	if err != nil {
+		pc.Lfu.ClearCache()
+	}
+	pc.Lfu.Delete(requesterUuid)
+	pc.Lfu.Delete(uuid)
+	utils.JsonResponse(w, http.StatusOK, request)
+}
+
+func userUuid(w http.ResponseWriter, r *http.Request) (string, bool) {
+	uuid := jwtToken.GetUserID(r.Header.Get("Authorization"))
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return "", false
+	}
+	return uuid, true
+}
+
+func paymentError(w http.ResponseWriter, err error) {
+	var recipientsErr *storage.RecipientsError
+	var limitErr *storage.LimitError
+	switch {
+	case errors.As(err, &recipientsErr):
+		response := models.BulkErrorResponse{Errors: recipientsErr.Error(), Recipients: recipientsErr.Recipients}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+	case errors.As(err, &limitErr):
+		response := models.LimitErrorResponse{Errors: limitErr.Error(), Limit: limitErr.Limit, Remaining: limitErr.Remaining}
+		utils.JsonResponse(w, http.StatusTooManyRequests, response)
+	case errors.Is(err, storage.ErrPaymentRequestNotFound):
+		response := models.ErrorResponse{Errors: storage.ErrPaymentRequestNotFound.Error()}
+		utils.JsonResponse(w, http.StatusNotFound, response)
+	case errors.Is(err, storage.ErrUserNotFound), errors.Is(err, storage.ErrSendingToYourself), errors.Is(err, storage.ErrInvalidAmount):
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+	case errors.Is(err, storage.ErrNotEnoughBalance), errors.Is(err, storage.ErrPaymentRequestAnswered):
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusConflict, response)
+	default:
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+	}
+}
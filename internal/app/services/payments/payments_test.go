@@ -0,0 +1,156 @@
+package payments
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type mockStorage struct {
+	CreatePaymentRequestFunc func(ctx context.Context, uuid string, payers []string, amount int, note models.TransferNote) (*models.PaymentRequest, error)
+	GetPaymentRequestsFunc   func(ctx context.Context, uuid string) ([]models.PaymentRequest, error)
+	PayPaymentRequestFunc    func(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error)
+	RejectPaymentRequestFunc func(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error)
+	GetUuidByUsernameFunc    func(ctx context.Context, username string) (string, error)
+}
+
+func (m *mockStorage) CreatePaymentRequest(ctx context.Context, uuid string, payers []string, amount int, note models.TransferNote) (*models.PaymentRequest, error) {
+	return m.CreatePaymentRequestFunc(ctx, uuid, payers, amount, note)
+}
+
+func (m *mockStorage) GetPaymentRequests(ctx context.Context, uuid string) ([]models.PaymentRequest, error) {
+	return m.GetPaymentRequestsFunc(ctx, uuid)
+}
+
+func (m *mockStorage) PayPaymentRequest(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error) {
+	return m.PayPaymentRequestFunc(ctx, uuid, requestID)
+}
+
+func (m *mockStorage) RejectPaymentRequest(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error) {
+	return m.RejectPaymentRequestFunc(ctx, uuid, requestID)
+}
+
+func (m *mockStorage) GetUuidByUsername(ctx context.Context, username string) (string, error) {
+	return m.GetUuidByUsernameFunc(ctx, username)
+}
+
+func TestPaymentController(t *testing.T) {
+	mockSt := &mockStorage{}
+	lfu := cache.NewLFUCache(10)
+	controller := &PaymentController{Storage: mockSt, Lfu: lfu}
+	token, _ := jwtToken.BuidToken("uuid-123")
+
+	doRequest := func(handler http.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/paymentRequests", bytes.NewBufferString(body))
+		if id != "" {
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+		}
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	t.Run("create with invalid params -> 400", func(t *testing.T) {
+		for _, body := range []string{`{"payers":["bob"]}`, `{"payers":[],"amount":5}`, `{"amount":5}`, `{"payers":["bob"],"amount":5,"category":"lunch"}`} {
+			assert.Equal(t, http.StatusBadRequest, doRequest(controller.CreatePaymentRequest, http.MethodPost, "", body).Code, body)
+		}
+	})
+
+	t.Run("create asks every payer", func(t *testing.T) {
+		mockSt.CreatePaymentRequestFunc = func(ctx context.Context, uuid string, payers []string, amount int, note models.TransferNote) (*models.PaymentRequest, error) {
+			assert.Equal(t, "uuid-123", uuid)
+			assert.Equal(t, []string{"bob", "carol"}, payers)
+			assert.Equal(t, 25, amount)
+			assert.Equal(t, models.TransferNote{Message: "lunch", Category: "thanks"}, note)
+			return &models.PaymentRequest{ID: 1, Requester: "alice", Amount: amount, Payers: []models.Payer{
+				{User: "bob", Status: models.PaymentStatusPending},
+				{User: "carol", Status: models.PaymentStatusPending},
+			}}, nil
+		}
+
+		w := doRequest(controller.CreatePaymentRequest, http.MethodPost, "", `{"payers":["bob","carol"],"amount":25,"message":" lunch ","category":"thanks"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var request models.PaymentRequest
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&request))
+		assert.Len(t, request.Payers, 2)
+	})
+
+	t.Run("create with bad payers -> 400 with every payer", func(t *testing.T) {
+		mockSt.CreatePaymentRequestFunc = func(ctx context.Context, uuid string, payers []string, amount int, note models.TransferNote) (*models.PaymentRequest, error) {
+			return nil, &storage.RecipientsError{Recipients: []models.RecipientError{
+				{Index: 0, ToUser: "ghost", Error: storage.ErrUserNotFound.Error()},
+				{Index: 1, ToUser: "ghost", Error: storage.ErrDuplicateRecipient.Error()},
+			}}
+		}
+		w := doRequest(controller.CreatePaymentRequest, http.MethodPost, "", `{"payers":["ghost","ghost"],"amount":25}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response models.BulkErrorResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Len(t, response.Recipients, 2)
+	})
+
+	t.Run("list without requests -> empty list", func(t *testing.T) {
+		mockSt.GetPaymentRequestsFunc = func(ctx context.Context, uuid string) ([]models.PaymentRequest, error) {
+			return nil, nil
+		}
+		w := doRequest(controller.PaymentRequests, http.MethodGet, "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+	})
+
+	t.Run("pay invalidates both users", func(t *testing.T) {
+		mockSt.PayPaymentRequestFunc = func(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error) {
+			assert.Equal(t, 3, requestID)
+			return &models.PaymentRequest{ID: 3, Requester: "alice", Payers: []models.Payer{{User: "bob", Status: models.PaymentStatusPaid, TransactionID: 7}}}, nil
+		}
+		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
+			assert.Equal(t, "alice", username)
+			return "uuid-alice", nil
+		}
+		lfu.Set("uuid-123", "{}")
+		lfu.Set("uuid-alice", "{}")
+
+		assert.Equal(t, http.StatusBadRequest, doRequest(controller.PayPaymentRequest, http.MethodPost, "x", "").Code)
+		assert.Equal(t, http.StatusOK, doRequest(controller.PayPaymentRequest, http.MethodPost, "3", "").Code)
+		_, ok := lfu.Get("uuid-123")
+		assert.False(t, ok)
+		_, ok = lfu.Get("uuid-alice")
+		assert.False(t, ok)
+	})
+
+	t.Run("pay errors", func(t *testing.T) {
+		for err, code := range map[error]int{
+			storage.ErrPaymentRequestNotFound:   http.StatusNotFound,
+			storage.ErrPaymentRequestAnswered:   http.StatusConflict,
+			storage.ErrNotEnoughBalance:         http.StatusConflict,
+			&storage.LimitError{Limit: "daily"}: http.StatusTooManyRequests,
+		} {
+			mockSt.PayPaymentRequestFunc = func(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error) {
+				return nil, err
+			}
+			assert.Equal(t, code, doRequest(controller.PayPaymentRequest, http.MethodPost, "3", "").Code, err.Error())
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		mockSt.RejectPaymentRequestFunc = func(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error) {
+			return &models.PaymentRequest{ID: 3, Requester: "alice", Payers: []models.Payer{{User: "bob", Status: models.PaymentStatusRejected}}}, nil
+		}
+		assert.Equal(t, http.StatusOK, doRequest(controller.RejectPaymentRequest, http.MethodPost, "3", "").Code)
+
+		mockSt.RejectPaymentRequestFunc = func(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error) {
+			return nil, storage.ErrPaymentRequestAnswered
+		}
+		assert.Equal(t, http.StatusConflict, doRequest(controller.RejectPaymentRequest, http.MethodPost, "3", "").Code)
+	})
+}
@@ -0,0 +1,153 @@
+package pending
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Storage interface {
+	CreatePendingTransfer(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote, ttl time.Duration) (*models.PendingTransfer, error)
+	GetPendingTransfers(ctx context.Context, uuid string) ([]models.PendingTransfer, error)
+	AcceptPendingTransfer(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error)
+	DeclinePendingTransfer(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error)
+	GetUuidByUsername(ctx context.Context, username string) (string, error)
+}
+
+type PendingController struct {
+	Storage Storage
+	Lfu     *cache.LFUCache
+	// how long the recipient has to accept an offer
+	TTL time.Duration
+}
+
+type PendingRequest struct {
+	ToUser   string `json:"toUser" validate:"required"`
+	Amount   int    `json:"amount" validate:"required,gt=0"`
+	Message  string `json:"message" validate:"max=200"`
+	Category string `json:"category" validate:"omitempty,oneof=thanks help birthday teamwork"`
+}
+
+func (pc *PendingController) CreatePendingTransfer(w http.ResponseWriter, r *http.Request) {
+	var req PendingRequest
+
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+	pending, err := pc.Storage.CreatePendingTransfer(r.Context(), uuid, req.ToUser, req.Amount, models.TransferNote{
+		Message:  strings.TrimSpace(req.Message),
+		Category: req.Category,
+	}, pc.TTL)
+	if err != nil {
+		pendingError(w, err)
+		return
+	}
+
+	pc.Lfu.Delete(uuid)
+	utils.JsonResponse(w, http.StatusOK, pending)
+}
+
+func (pc *PendingController) PendingTransfers(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+	transfers, err := pc.Storage.GetPendingTransfers(r.Context(), uuid)
+	if err != nil {
+		pendingError(w, err)
+		return
+	}
+	if transfers == nil {
+		transfers = []models.PendingTransfer{}
+	}
+	utils.JsonResponse(w, http.StatusOK, transfers)
+}
+
+func (pc *PendingController) AcceptPendingTransfer(w http.ResponseWriter, r *http.Request) {
+	pc.resolve(w, r, pc.Storage.AcceptPendingTransfer)
+}
+
+func (pc *PendingController) DeclinePendingTransfer(w http.ResponseWriter, r *http.Request) {
+	pc.resolve(w, r, pc.Storage.DeclinePendingTransfer)
+}
+
+func (pc *PendingController) resolve(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error)) {
+	pendingID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || pendingID <= 0 {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+
+	pending, err := fn(r.Context(), uuid, pendingID)
+	if err != nil {
+		pendingError(w, err)
+		return
+	}
+
+	// the sender gets the coins back or sees the transfer in the history
+	senderUuid, err := pc.Storage.GetUuidByUsername(r.Context(), pending.FromUser)
+	if err != nil {
+		pc.Lfu.ClearCache()
+	}
+	pc.Lfu.Delete(senderUuid)
+	pc.Lfu.Delete(uuid)
+	utils.JsonResponse(w, http.StatusOK, pending)
+}
+
+func userUuid(w http.ResponseWriter, r *http.Request) (string, bool) {
+	uuid := jwtToken.GetUserID(r.Header.Get("Authorization"))
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return "", false
+	}
+	return uuid, true
+}
+
+func pendingError(w http.ResponseWriter, err error) {
+	var limitErr *storage.LimitError
+	switch {
+	case errors.As(err, &limitErr):
+		response := models.LimitErrorResponse{Errors: limitErr.Error(), Limit: limitErr.Limit, Remaining: limitErr.Remaining}
+		utils.JsonResponse(w, http.StatusTooManyRequests, response)
+	case errors.Is(err, storage.ErrPendingTransferNotFound):
+		response := models.ErrorResponse{Errors: storage.ErrPendingTransferNotFound.Error()}
+		utils.JsonResponse(w, http.StatusNotFound, response)
+	case errors.Is(err, storage.ErrUserNotFound), errors.Is(err, storage.ErrSendingToYourself):
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+	case errors.Is(err, storage.ErrNotEnoughBalance), errors.Is(err, storage.ErrPendingTransferClosed):
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusConflict, response)
+	default:
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+	}
+}
@@ -0,0 +1,143 @@
+package pending
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type mockStorage struct {
+	CreatePendingTransferFunc  func(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote, ttl time.Duration) (*models.PendingTransfer, error)
+	GetPendingTransfersFunc    func(ctx context.Context, uuid string) ([]models.PendingTransfer, error)
+	AcceptPendingTransferFunc  func(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error)
+	DeclinePendingTransferFunc func(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error)
+	GetUuidByUsernameFunc      func(ctx context.Context, username string) (string, error)
+}
+
+func (m *mockStorage) CreatePendingTransfer(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote, ttl time.Duration) (*models.PendingTransfer, error) {
+	return m.CreatePendingTransferFunc(ctx, uuid, toUser, amount, note, ttl)
+}
+
+func (m *mockStorage) GetPendingTransfers(ctx context.Context, uuid string) ([]models.PendingTransfer, error) {
+	return m.GetPendingTransfersFunc(ctx, uuid)
+}
+
+func (m *mockStorage) AcceptPendingTransfer(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error) {
+	return m.AcceptPendingTransferFunc(ctx, uuid, pendingID)
+}
+
+func (m *mockStorage) DeclinePendingTransfer(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error) {
+	return m.DeclinePendingTransferFunc(ctx, uuid, pendingID)
+}
+
+func (m *mockStorage) GetUuidByUsername(ctx context.Context, username string) (string, error) {
+	return m.GetUuidByUsernameFunc(ctx, username)
+}
+
+func TestPendingController(t *testing.T) {
+	mockSt := &mockStorage{}
+	lfu := cache.NewLFUCache(10)
+	controller := &PendingController{Storage: mockSt, Lfu: lfu, TTL: time.Hour}
+	token, _ := jwtToken.BuidToken("uuid-123")
+
+	doRequest := func(handler http.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/pendingTransfers", bytes.NewBufferString(body))
+		if id != "" {
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+		}
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	t.Run("create with invalid params -> 400", func(t *testing.T) {
+		for _, body := range []string{`{"toUser":"bob"}`, `{"toUser":"bob","amount":-5}`, `{"amount":5}`} {
+			assert.Equal(t, http.StatusBadRequest, doRequest(controller.CreatePendingTransfer, http.MethodPost, "", body).Code, body)
+		}
+	})
+
+	t.Run("create holds coins for the ttl", func(t *testing.T) {
+		mockSt.CreatePendingTransferFunc = func(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote, ttl time.Duration) (*models.PendingTransfer, error) {
+			assert.Equal(t, "uuid-123", uuid)
+			assert.Equal(t, "bob", toUser)
+			assert.Equal(t, 50, amount)
+			assert.Equal(t, models.TransferNote{Message: "lunch", Category: models.CategoryThanks}, note)
+			assert.Equal(t, time.Hour, ttl)
+			return &models.PendingTransfer{ID: 1, FromUser: "alice", ToUser: toUser, Amount: amount, Status: models.PendingStatusPending}, nil
+		}
+		lfu.Set("uuid-123", "{}")
+
+		w := doRequest(controller.CreatePendingTransfer, http.MethodPost, "", `{"toUser":"bob","amount":50,"message":" lunch ","category":"thanks"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var pending models.PendingTransfer
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&pending))
+		assert.Equal(t, models.PendingStatusPending, pending.Status)
+		_, ok := lfu.Get("uuid-123")
+		assert.False(t, ok)
+	})
+
+	t.Run("create errors", func(t *testing.T) {
+		for err, code := range map[error]int{
+			storage.ErrNotEnoughBalance:  http.StatusConflict,
+			storage.ErrUserNotFound:      http.StatusBadRequest,
+			storage.ErrSendingToYourself: http.StatusBadRequest,
+		} {
+			mockSt.CreatePendingTransferFunc = func(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote, ttl time.Duration) (*models.PendingTransfer, error) {
+				return nil, err
+			}
+			assert.Equal(t, code, doRequest(controller.CreatePendingTransfer, http.MethodPost, "", `{"toUser":"bob","amount":50}`).Code, err.Error())
+		}
+	})
+
+	t.Run("list without transfers -> empty list", func(t *testing.T) {
+		mockSt.GetPendingTransfersFunc = func(ctx context.Context, uuid string) ([]models.PendingTransfer, error) {
+			return nil, nil
+		}
+		w := doRequest(controller.PendingTransfers, http.MethodGet, "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+	})
+
+	t.Run("accept invalidates both users", func(t *testing.T) {
+		mockSt.AcceptPendingTransferFunc = func(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error) {
+			assert.Equal(t, 3, pendingID)
+			return &models.PendingTransfer{ID: 3, FromUser: "alice", Status: models.PendingStatusAccepted, TransactionID: 7}, nil
+		}
+		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
+			assert.Equal(t, "alice", username)
+			return "uuid-alice", nil
+		}
+		lfu.Set("uuid-123", "{}")
+		lfu.Set("uuid-alice", "{}")
+
+		assert.Equal(t, http.StatusBadRequest, doRequest(controller.AcceptPendingTransfer, http.MethodPost, "x", "").Code)
+		assert.Equal(t, http.StatusOK, doRequest(controller.AcceptPendingTransfer, http.MethodPost, "3", "").Code)
+		_, ok := lfu.Get("uuid-123")
+		assert.False(t, ok)
+		_, ok = lfu.Get("uuid-alice")
+		assert.False(t, ok)
+	})
+
+	t.Run("decline errors", func(t *testing.T) {
+		for err, code := range map[error]int{
+			storage.ErrPendingTransferNotFound: http.StatusNotFound,
+			storage.ErrPendingTransferClosed:   http.StatusConflict,
+		} {
+			mockSt.DeclinePendingTransferFunc = func(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error) {
+				return nil, err
+			}
+			assert.Equal(t, code, doRequest(controller.DeclinePendingTransfer, http.MethodPost, "3", "").Code, err.Error())
+		}
+	})
+}
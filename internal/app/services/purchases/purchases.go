@@ -0,0 +1,84 @@
+package purchases
+
+import (
+	"avito/internal/app/services/transactions"
+	"avito/internal/models"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/schema"
+	"net/http"
+)
+
+const defaultLimit = 20
+
+type Storage interface {
+	GetPurchases(ctx context.Context, uuid string, filter models.PurchaseFilter) ([]models.Order, error)
+}
+
+type PurchasesController struct {
+	Storage Storage
+}
+
+type PurchasesParams struct {
+	Item   string `schema:"item"`
+	Cursor string `schema:"cursor"`
+	Limit  int    `schema:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (pc *PurchasesController) Purchases(w http.ResponseWriter, r *http.Request) {
+	decoder := schema.NewDecoder()
+	validate := validator.New()
+
+	var params PurchasesParams
+	err := decoder.Decode(&params, r.URL.Query())
+	errValidate := validate.Struct(params)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	filter := models.PurchaseFilter{Item: params.Item, Limit: params.Limit}
+	if filter.Limit == 0 {
+		filter.Limit = defaultLimit
+	}
+	if params.Cursor != "" {
+		filter.BeforeID, err = transactions.DecodeCursor(params.Cursor)
+		if err != nil {
+			response := models.ErrorResponse{Errors: "invalid cursor"}
+			utils.JsonResponse(w, http.StatusBadRequest, response)
+			return
+		}
+	}
+
+	cookie := r.Header.Get("Authorization")
+	uuid := jwtToken.GetUserID(cookie)
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	// one extra row tells whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+	purchases, err := pc.Storage.GetPurchases(r.Context(), uuid, filter)
+	if err != nil {
+		response := models.ErrorResponse{Errors: "error getting purchases"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	page := models.PurchasePage{Purchases: purchases}
+	if len(purchases) > limit {
+		page.Purchases = purchases[:limit]
+		page.NextCursor = transactions.EncodeCursor(page.Purchases[limit-1].ID)
+	}
+	if page.Purchases == nil {
+		page.Purchases = []models.Order{}
+	}
+
+	utils.JsonResponse(w, http.StatusOK, page)
+}
@@ -0,0 +1,91 @@
+package purchases
+
+import (
+	"avito/internal/app/services/transactions"
+	"avito/internal/models"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type mockStorage struct {
+	GetPurchasesFunc func(ctx context.Context, uuid string, filter models.PurchaseFilter) ([]models.Order, error)
+}
+
+func (m *mockStorage) GetPurchases(ctx context.Context, uuid string, filter models.PurchaseFilter) ([]models.Order, error) {
+	return m.GetPurchasesFunc(ctx, uuid, filter)
+}
+
+func TestPurchasesController_Purchases(t *testing.T) {
+	mockSt := &mockStorage{}
+	controller := &PurchasesController{Storage: mockSt}
+	token, _ := jwtToken.BuidToken("uuid-123")
+
+	doRequest := func(url string, token string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		w := httptest.NewRecorder()
+		controller.Purchases(w, req)
+		return w.Result()
+	}
+
+	t.Run("invalid params -> 400", func(t *testing.T) {
+		for _, url := range []string{
+			"/api/purchases?limit=1000",
+			"/api/purchases?limit=abc",
+			"/api/purchases?cursor=!!!",
+		} {
+			resp := doRequest(url, token)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, url)
+		}
+	})
+
+	t.Run("no JWT -> 500", func(t *testing.T) {
+		resp := doRequest("/api/purchases", "")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+
+	t.Run("next page cursor", func(t *testing.T) {
+		mockSt.GetPurchasesFunc = func(ctx context.Context, uuid string, filter models.PurchaseFilter) ([]models.Order, error) {
+			assert.Equal(t, "uuid-123", uuid)
+			assert.Equal(t, models.PurchaseFilter{Item: "pen", BeforeID: 10, Limit: 3}, filter)
+			return []models.Order{
+				{ID: 9, Items: []models.OrderLine{{Item: "pen", Quantity: 1, UnitPrice: 10}}, Total: 10},
+				{ID: 7, Items: []models.OrderLine{{Item: "pen", Quantity: 2, UnitPrice: 10}}, Total: 20},
+				{ID: 3, Items: []models.OrderLine{{Item: "pen", Quantity: 1, UnitPrice: 5}}, Total: 5},
+			}, nil
+		}
+
+		resp := doRequest("/api/purchases?item=pen&limit=2&cursor="+transactions.EncodeCursor(10), token)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var page models.PurchasePage
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		assert.Len(t, page.Purchases, 2)
+		assert.Equal(t, transactions.EncodeCursor(7), page.NextCursor)
+	})
+
+	t.Run("no purchases -> empty list", func(t *testing.T) {
+		mockSt.GetPurchasesFunc = func(ctx context.Context, uuid string, filter models.PurchaseFilter) ([]models.Order, error) {
+			return nil, nil
+		}
+
+		resp := doRequest("/api/purchases", token)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var page map[string]any
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		assert.Equal(t, []any{}, page["purchases"])
+	})
+}
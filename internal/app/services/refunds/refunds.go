@@ -0,0 +1,144 @@
+package refunds
+
+import (
+	"avito/internal/app/services/catalog"
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Storage interface {
+	RefundPurchase(ctx context.Context, uuid string, orderID int, item string, quantity int, window time.Duration) (*models.Refund, error)
+	AdminRefundPurchase(ctx context.Context, adminUuid string, orderID int, item string, quantity int) (*models.Refund, error)
+	GetUuidByUsername(ctx context.Context, username string) (string, error)
+}
+
+type RefundController struct {
+	Storage Storage
+	Lfu     *cache.LFUCache
+	// zero leaves refunds to admins only
+	Window time.Duration
+}
+
+type RefundRequest struct {
+	Item string `json:"item" validate:"required"`
+	// omitted to return everything left of the purchase
+	Quantity int `json:"quantity" validate:"omitempty,gt=0"`
+}
+
+func (rc *RefundController) Refund(w http.ResponseWriter, r *http.Request) {
+	orderID, req, ok := parseRefund(w, r)
+	if !ok {
+		return
+	}
+
+	cookie := r.Header.Get("Authorization")
+	uuid := jwtToken.GetUserID(cookie)
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	refund, err := rc.Storage.RefundPurchase(r.Context(), uuid, orderID, req.Item, req.Quantity, rc.Window)
+	if err != nil {
+		refundError(w, err)
+		return
+	}
+
+	rc.Lfu.Delete(uuid)
+	rc.invalidateRecipient(r.Context(), refund)
+	rc.invalidateCatalog(refund.Item)
+	utils.JsonResponse(w, http.StatusOK, refund)
+}
+
+func (rc *RefundController) AdminRefund(w http.ResponseWriter, r *http.Request) {
+	orderID, req, ok := parseRefund(w, r)
+	if !ok {
+		return
+	}
+
+	adminUuid := jwtToken.GetUserID(r.Header.Get("Authorization"))
+	refund, err := rc.Storage.AdminRefundPurchase(r.Context(), adminUuid, orderID, req.Item, req.Quantity)
+	if err != nil {
+		refundError(w, err)
+		return
+	}
+
+	ownerUuid, err := rc.Storage.GetUuidByUsername(r.Context(), refund.User)
+	if err != nil {
+		rc.Lfu.ClearCache()
+	}
+	rc.Lfu.Delete(ownerUuid)
+	rc.invalidateRecipient(r.Context(), refund)
+	rc.invalidateCatalog(refund.Item)
+	utils.JsonResponse(w, http.StatusOK, refund)
+}
+
+// a refunded gift is taken back from the recipient's inventory
+func (rc *RefundController) invalidateRecipient(ctx context.Context, refund *models.Refund) {
+	if refund.ToUser == "" {
+		return
+	}
+	recipientUuid, err := rc.Storage.GetUuidByUsername(ctx, refund.ToUser)
+	if err != nil {
+		rc.Lfu.ClearCache()
+		return
+	}
+	rc.Lfu.Delete(recipientUuid)
+}
+
+// returned items go back to the stock of limited merch
+func (rc *RefundController) invalidateCatalog(item string) {
+	rc.Lfu.Delete(catalog.CacheKey)
+	rc.Lfu.Delete(catalog.ItemCacheKey(item))
+}
+
+func parseRefund(w http.ResponseWriter, r *http.Request) (int, RefundRequest, bool) {
+	var req RefundRequest
+
+	orderID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || orderID <= 0 {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return 0, req, false
+	}
+
+	validate := validator.New()
+	err = json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return 0, req, false
+	}
+	return orderID, req, true
+}
+
+func refundError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrOrderNotFound), errors.Is(err, storage.ErrItemNotFound):
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusNotFound, response)
+	case errors.Is(err, storage.ErrRefundWindowExpired),
+		errors.Is(err, storage.ErrRefundExceedsPurchase),
+		errors.Is(err, storage.ErrNotInInventory),
+		errors.Is(err, storage.ErrAuctionWinNotRefundable):
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusConflict, response)
+	default:
+		response := models.ErrorResponse{Errors: "error refunding purchase"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+	}
+}
@@ -0,0 +1,139 @@
+package refunds
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type mockStorage struct {
+	RefundPurchaseFunc      func(ctx context.Context, uuid string, orderID int, item string, quantity int, window time.Duration) (*models.Refund, error)
+	AdminRefundPurchaseFunc func(ctx context.Context, adminUuid string, orderID int, item string, quantity int) (*models.Refund, error)
+	GetUuidByUsernameFunc   func(ctx context.Context, username string) (string, error)
+}
+
+func (m *mockStorage) RefundPurchase(ctx context.Context, uuid string, orderID int, item string, quantity int, window time.Duration) (*models.Refund, error) {
+	return m.RefundPurchaseFunc(ctx, uuid, orderID, item, quantity, window)
+}
+
+func (m *mockStorage) AdminRefundPurchase(ctx context.Context, adminUuid string, orderID int, item string, quantity int) (*models.Refund, error) {
+	return m.AdminRefundPurchaseFunc(ctx, adminUuid, orderID, item, quantity)
+}
+
+func (m *mockStorage) GetUuidByUsername(ctx context.Context, username string) (string, error) {
+	return m.GetUuidByUsernameFunc(ctx, username)
+}
+
+func TestRefundController_Refund(t *testing.T) {
+	mockSt := &mockStorage{}
+	controller := &RefundController{Storage: mockSt, Lfu: cache.NewLFUCache(10), Window: time.Hour}
+	token, _ := jwtToken.BuidToken("user-uuid-123")
+
+	doRequest := func(handler http.HandlerFunc, id string, body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/api/purchases/"+id+"/refund", bytes.NewBufferString(body))
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w.Result()
+	}
+
+	t.Run("invalid params -> 400", func(t *testing.T) {
+		for _, tc := range []struct{ id, body string }{
+			{"abc", `{"item":"pen"}`},
+			{"0", `{"item":"pen"}`},
+			{"1", `{}`},
+			{"1", `{"item":"pen","quantity":-1}`},
+		} {
+			resp := doRequest(controller.Refund, tc.id, tc.body)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, tc)
+		}
+	})
+
+	t.Run("refund success -> 200", func(t *testing.T) {
+		mockSt.RefundPurchaseFunc = func(ctx context.Context, uuid string, orderID int, item string, quantity int, window time.Duration) (*models.Refund, error) {
+			assert.Equal(t, "user-uuid-123", uuid)
+			assert.Equal(t, 7, orderID)
+			assert.Equal(t, "pen", item)
+			assert.Equal(t, 0, quantity)
+			assert.Equal(t, time.Hour, window)
+			return &models.Refund{ID: 1, OrderID: 7, User: "user", Item: "pen", Quantity: 2, Amount: 20}, nil
+		}
+
+		resp := doRequest(controller.Refund, "7", `{"item":"pen"}`)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var refund models.Refund
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&refund))
+		assert.Equal(t, 20, refund.Amount)
+	})
+
+	t.Run("storage errors", func(t *testing.T) {
+		for err, status := range map[error]int{
+			storage.ErrOrderNotFound:           http.StatusNotFound,
+			storage.ErrItemNotFound:            http.StatusNotFound,
+			storage.ErrRefundWindowExpired:     http.StatusConflict,
+			storage.ErrRefundExceedsPurchase:   http.StatusConflict,
+			storage.ErrNotInInventory:          http.StatusConflict,
+			storage.ErrAuctionWinNotRefundable: http.StatusConflict,
+			context.DeadlineExceeded:           http.StatusInternalServerError,
+		} {
+			mockSt.RefundPurchaseFunc = func(ctx context.Context, uuid string, orderID int, item string, quantity int, window time.Duration) (*models.Refund, error) {
+				return nil, err
+			}
+			resp := doRequest(controller.Refund, "7", `{"item":"pen","quantity":1}`)
+			resp.Body.Close()
+			assert.Equal(t, status, resp.StatusCode, err.Error())
+		}
+	})
+
+	t.Run("admin refund invalidates owner", func(t *testing.T) {
+		mockSt.AdminRefundPurchaseFunc = func(ctx context.Context, adminUuid string, orderID int, item string, quantity int) (*models.Refund, error) {
+			assert.Equal(t, "user-uuid-123", adminUuid)
+			return &models.Refund{ID: 2, OrderID: 7, User: "bob", Item: "pen", Quantity: 1, Amount: 10}, nil
+		}
+		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
+			assert.Equal(t, "bob", username)
+			return "bob-uuid", nil
+		}
+		controller.Lfu.Set("bob-uuid", `{"coins":1}`)
+
+		resp := doRequest(controller.AdminRefund, "7", `{"item":"pen","quantity":1}`)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		_, ok := controller.Lfu.Get("bob-uuid")
+		assert.False(t, ok)
+	})
+
+	t.Run("gift refund invalidates recipient", func(t *testing.T) {
+		mockSt.RefundPurchaseFunc = func(ctx context.Context, uuid string, orderID int, item string, quantity int, window time.Duration) (*models.Refund, error) {
+			return &models.Refund{ID: 3, OrderID: 8, User: "user", Item: "pen", Quantity: 1, Amount: 10, ToUser: "carol"}, nil
+		}
+		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
+			assert.Equal(t, "carol", username)
+			return "carol-uuid", nil
+		}
+		controller.Lfu.Set("user-uuid-123", `{"coins":1}`)
+		controller.Lfu.Set("carol-uuid", `{"coins":1}`)
+
+		resp := doRequest(controller.Refund, "8", `{"item":"pen","quantity":1}`)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		_, ok := controller.Lfu.Get("user-uuid-123")
+		assert.False(t, ok)
+		_, ok = controller.Lfu.Get("carol-uuid")
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,191 @@
+package schedules
+
+import (
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Storage interface {
+	CreateSchedule(ctx context.Context, uuid string, schedule models.ScheduledTransfer) (*models.ScheduledTransfer, error)
+	GetSchedules(ctx context.Context, uuid string) ([]models.ScheduledTransfer, error)
+	UpdateSchedule(ctx context.Context, uuid string, scheduleID int, update models.ScheduleUpdate) (*models.ScheduledTransfer, error)
+	CancelSchedule(ctx context.Context, uuid string, scheduleID int) error
+	GetScheduleRuns(ctx context.Context, uuid string, scheduleID int) ([]models.ScheduledRun, error)
+}
+
+type ScheduleController struct {
+	Storage Storage
+}
+
+type ScheduleRequest struct {
+	ToUser   string `json:"toUser" validate:"required"`
+	Amount   int    `json:"amount" validate:"required,gt=0"`
+	Message  string `json:"message" validate:"max=200"`
+	Category string `json:"category" validate:"omitempty,oneof=thanks help birthday teamwork"`
+	Interval string `json:"interval" validate:"omitempty,oneof=once daily weekly monthly"`
+	// first run, RFC 3339
+	RunAt time.Time `json:"runAt" validate:"required"`
+}
+
+func (sc *ScheduleController) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req ScheduleRequest
+
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+	if req.Interval == "" {
+		req.Interval = models.IntervalOnce
+	}
+
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+	schedule, err := sc.Storage.CreateSchedule(r.Context(), uuid, models.ScheduledTransfer{
+		ToUser:    req.ToUser,
+		Amount:    req.Amount,
+		Message:   strings.TrimSpace(req.Message),
+		Category:  req.Category,
+		Interval:  req.Interval,
+		NextRunAt: &req.RunAt,
+	})
+	if err != nil {
+		scheduleError(w, err)
+		return
+	}
+	utils.JsonResponse(w, http.StatusOK, schedule)
+}
+
+func (sc *ScheduleController) Schedules(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+	schedules, err := sc.Storage.GetSchedules(r.Context(), uuid)
+	if err != nil {
+		scheduleError(w, err)
+		return
+	}
+	if schedules == nil {
+		schedules = []models.ScheduledTransfer{}
+	}
+	utils.JsonResponse(w, http.StatusOK, schedules)
+}
+
+func (sc *ScheduleController) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleID, ok := scheduleIDVar(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ScheduleUpdate
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+	schedule, err := sc.Storage.UpdateSchedule(r.Context(), uuid, scheduleID, req)
+	if err != nil {
+		scheduleError(w, err)
+		return
+	}
+	utils.JsonResponse(w, http.StatusOK, schedule)
+}
+
+func (sc *ScheduleController) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleID, ok := scheduleIDVar(w, r)
+	if !ok {
+		return
+	}
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+	err := sc.Storage.CancelSchedule(r.Context(), uuid, scheduleID)
+	if err != nil {
+		scheduleError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (sc *ScheduleController) ScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	scheduleID, ok := scheduleIDVar(w, r)
+	if !ok {
+		return
+	}
+	uuid, ok := userUuid(w, r)
+	if !ok {
+		return
+	}
+	runs, err := sc.Storage.GetScheduleRuns(r.Context(), uuid, scheduleID)
+	if err != nil {
+		scheduleError(w, err)
+		return
+	}
+	if runs == nil {
+		runs = []models.ScheduledRun{}
+	}
+	utils.JsonResponse(w, http.StatusOK, runs)
+}
+
+func userUuid(w http.ResponseWriter, r *http.Request) (string, bool) {
+	uuid := jwtToken.GetUserID(r.Header.Get("Authorization"))
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return "", false
+	}
+	return uuid, true
+}
+
+func scheduleIDVar(w http.ResponseWriter, r *http.Request) (int, bool) {
+	scheduleID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || scheduleID <= 0 {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return 0, false
+	}
+	return scheduleID, true
+}
+
+func scheduleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrScheduleNotFound):
+		response := models.ErrorResponse{Errors: storage.ErrScheduleNotFound.Error()}
+		utils.JsonResponse(w, http.StatusNotFound, response)
+	case errors.Is(err, storage.ErrUserNotFound), errors.Is(err, storage.ErrSendingToYourself):
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+	default:
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+	}
+}
@@ -0,0 +1,139 @@
+package schedules
+
+import (
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type mockStorage struct {
+	CreateScheduleFunc  func(ctx context.Context, uuid string, schedule models.ScheduledTransfer) (*models.ScheduledTransfer, error)
+	GetSchedulesFunc    func(ctx context.Context, uuid string) ([]models.ScheduledTransfer, error)
+	UpdateScheduleFunc  func(ctx context.Context, uuid string, scheduleID int, update models.ScheduleUpdate) (*models.ScheduledTransfer, error)
+	CancelScheduleFunc  func(ctx context.Context, uuid string, scheduleID int) error
+	GetScheduleRunsFunc func(ctx context.Context, uuid string, scheduleID int) ([]models.ScheduledRun, error)
+}
+
+func (m *mockStorage) CreateSchedule(ctx context.Context, uuid string, schedule models.ScheduledTransfer) (*models.ScheduledTransfer, error) {
+	return m.CreateScheduleFunc(ctx, uuid, schedule)
+}
+
+func (m *mockStorage) GetSchedules(ctx context.Context, uuid string) ([]models.ScheduledTransfer, error) {
+	return m.GetSchedulesFunc(ctx, uuid)
+}
+
+func (m *mockStorage) UpdateSchedule(ctx context.Context, uuid string, scheduleID int, update models.ScheduleUpdate) (*models.ScheduledTransfer, error) {
+	return m.UpdateScheduleFunc(ctx, uuid, scheduleID, update)
+}
+
+func (m *mockStorage) CancelSchedule(ctx context.Context, uuid string, scheduleID int) error {
+	return m.CancelScheduleFunc(ctx, uuid, scheduleID)
+}
+
+func (m *mockStorage) GetScheduleRuns(ctx context.Context, uuid string, scheduleID int) ([]models.ScheduledRun, error) {
+	return m.GetScheduleRunsFunc(ctx, uuid, scheduleID)
+}
+
+func TestScheduleController(t *testing.T) {
+	mockSt := &mockStorage{}
+	controller := &ScheduleController{Storage: mockSt}
+	token, _ := jwtToken.BuidToken("uuid-123")
+
+	doRequest := func(handler http.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/schedules", bytes.NewBufferString(body))
+		if id != "" {
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+		}
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	t.Run("create with invalid params -> 400", func(t *testing.T) {
+		for _, body := range []string{
+			`{"toUser":"bob","amount":50}`,
+			`{"toUser":"bob","amount":0,"runAt":"2025-05-02T10:00:00Z"}`,
+			`{"toUser":"bob","amount":50,"interval":"hourly","runAt":"2025-05-02T10:00:00Z"}`,
+			`{"toUser":"bob","amount":50,"runAt":"friday"}`,
+		} {
+			assert.Equal(t, http.StatusBadRequest, doRequest(controller.CreateSchedule, http.MethodPost, "", body).Code, body)
+		}
+	})
+
+	t.Run("create defaults to a one-off transfer", func(t *testing.T) {
+		mockSt.CreateScheduleFunc = func(ctx context.Context, uuid string, schedule models.ScheduledTransfer) (*models.ScheduledTransfer, error) {
+			assert.Equal(t, "uuid-123", uuid)
+			assert.Equal(t, models.IntervalOnce, schedule.Interval)
+			assert.Equal(t, "rent", schedule.Message)
+			assert.Equal(t, models.CategoryHelp, schedule.Category)
+			assert.True(t, schedule.NextRunAt.Equal(time.Date(2025, 5, 2, 10, 0, 0, 0, time.UTC)))
+			schedule.ID = 1
+			return &schedule, nil
+		}
+		w := doRequest(controller.CreateSchedule, http.MethodPost, "", `{"toUser":"bob","amount":50,"message":"rent","category":"help","runAt":"2025-05-02T10:00:00Z"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var schedule models.ScheduledTransfer
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&schedule))
+		assert.Equal(t, 1, schedule.ID)
+	})
+
+	t.Run("create to unknown user -> 400", func(t *testing.T) {
+		mockSt.CreateScheduleFunc = func(ctx context.Context, uuid string, schedule models.ScheduledTransfer) (*models.ScheduledTransfer, error) {
+			return nil, storage.ErrUserNotFound
+		}
+		w := doRequest(controller.CreateSchedule, http.MethodPost, "", `{"toUser":"nobody","amount":50,"runAt":"2025-05-02T10:00:00Z"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("list without schedules -> empty list", func(t *testing.T) {
+		mockSt.GetSchedulesFunc = func(ctx context.Context, uuid string) ([]models.ScheduledTransfer, error) {
+			return nil, nil
+		}
+		w := doRequest(controller.Schedules, http.MethodGet, "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+	})
+
+	t.Run("update", func(t *testing.T) {
+		mockSt.UpdateScheduleFunc = func(ctx context.Context, uuid string, scheduleID int, update models.ScheduleUpdate) (*models.ScheduledTransfer, error) {
+			assert.Equal(t, 3, scheduleID)
+			assert.Equal(t, 70, *update.Amount)
+			assert.False(t, *update.Active)
+			assert.Nil(t, update.Interval)
+			return &models.ScheduledTransfer{ID: 3, Amount: 70}, nil
+		}
+		assert.Equal(t, http.StatusBadRequest, doRequest(controller.UpdateSchedule, http.MethodPatch, "x", `{}`).Code)
+		assert.Equal(t, http.StatusBadRequest, doRequest(controller.UpdateSchedule, http.MethodPatch, "3", `{"amount":-1}`).Code)
+		assert.Equal(t, http.StatusOK, doRequest(controller.UpdateSchedule, http.MethodPatch, "3", `{"amount":70,"active":false}`).Code)
+	})
+
+	t.Run("cancel unknown -> 404", func(t *testing.T) {
+		mockSt.CancelScheduleFunc = func(ctx context.Context, uuid string, scheduleID int) error {
+			return storage.ErrScheduleNotFound
+		}
+		assert.Equal(t, http.StatusNotFound, doRequest(controller.CancelSchedule, http.MethodDelete, "3", "").Code)
+	})
+
+	t.Run("runs", func(t *testing.T) {
+		mockSt.GetScheduleRunsFunc = func(ctx context.Context, uuid string, scheduleID int) ([]models.ScheduledRun, error) {
+			return []models.ScheduledRun{{ID: 1, ScheduleID: 3, Status: models.RunFailed, Error: "not enough tokens"}}, nil
+		}
+		w := doRequest(controller.ScheduleRuns, http.MethodGet, "3", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var runs []models.ScheduledRun
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&runs))
+		assert.Equal(t, "not enough tokens", runs[0].Error)
+	})
+}
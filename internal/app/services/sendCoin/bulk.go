@@ -0,0 +1,118 @@
+package sendCoin
+
+import (
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"strings"
+)
+
+// BulkSendRequest lists the transfers one by one or splits Total evenly
+// between ToUsers, exactly one of the two forms must be used.
+type BulkSendRequest struct {
+	Transfers []models.BulkTransfer `json:"transfers" validate:"max=100"`
+	ToUsers   []string              `json:"toUsers" validate:"max=100"`
+	Total     int                   `json:"total" validate:"omitempty,gt=0"`
+	Message   string                `json:"message" validate:"max=200"`
+	Category  string                `json:"category" validate:"omitempty,oneof=thanks help birthday teamwork"`
+}
+
+func (sc *SendController) SendBulk(w http.ResponseWriter, r *http.Request) {
+	var req BulkSendRequest
+
+	validate := validator.New()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	defer r.Body.Close()
+	errValidate := validate.Struct(req)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect."}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	transfers, err := req.transfers()
+	if err != nil {
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	cookie := r.Header.Get("Authorization")
+	uuid := jwtToken.GetUserID(cookie)
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error."}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	sent, err := sc.Storage.SendBulk(r.Context(), uuid, transfers, models.TransferNote{
+		Message:  strings.TrimSpace(req.Message),
+		Category: req.Category,
+	})
+	if err != nil {
+		var recipientsErr *storage.RecipientsError
+		var limitErr *storage.LimitError
+		switch {
+		case errors.As(err, &recipientsErr):
+			response := models.BulkErrorResponse{Errors: recipientsErr.Error(), Recipients: recipientsErr.Recipients}
+			utils.JsonResponse(w, http.StatusBadRequest, response)
+		case errors.As(err, &limitErr):
+			response := models.LimitErrorResponse{Errors: limitErr.Error(), Limit: limitErr.Limit, Remaining: limitErr.Remaining}
+			utils.JsonResponse(w, http.StatusTooManyRequests, response)
+		case errors.Is(err, storage.ErrNotEnoughBalance):
+			response := models.ErrorResponse{Errors: storage.ErrNotEnoughBalance.Error()}
+			utils.JsonResponse(w, http.StatusConflict, response)
+		default:
+			response := models.ErrorResponse{Errors: "error sending tokens."}
+			utils.JsonResponse(w, http.StatusInternalServerError, response)
+		}
+		return
+	}
+
+	sc.Lfu.Delete(uuid)
+	for _, t := range sent {
+		receiverUuid, err := sc.Storage.GetUuidByUsername(r.Context(), t.ToUser)
+		if err != nil {
+			sc.Lfu.ClearCache()
+			break
+		}
+		sc.Lfu.Delete(receiverUuid)
+	}
+
+	utils.JsonResponse(w, http.StatusOK, models.BulkSendResult{Transfers: sent})
+}
+
+// transfers turns either form of the request into the list of transfers.
+// An even split gives the coins that don't divide evenly to the first
+// recipients, one each.
+func (req BulkSendRequest) transfers() ([]models.BulkTransfer, error) {
+	switch {
+	case len(req.Transfers) > 0 && (len(req.ToUsers) > 0 || req.Total > 0):
+		return nil, errors.New("use either transfers or toUsers with total")
+	case len(req.Transfers) > 0:
+		transfers := make([]models.BulkTransfer, len(req.Transfers))
+		for i, t := range req.Transfers {
+			transfers[i] = models.BulkTransfer{ToUser: t.ToUser, Amount: t.Amount}
+		}
+		return transfers, nil
+	case len(req.ToUsers) == 0 || req.Total == 0:
+		return nil, errors.New("no recipients")
+	case req.Total < len(req.ToUsers):
+		return nil, errors.New("total is too small to give every recipient a coin")
+	}
+
+	share, rest := req.Total/len(req.ToUsers), req.Total%len(req.ToUsers)
+	transfers := make([]models.BulkTransfer, len(req.ToUsers))
+	for i, user := range req.ToUsers {
+		transfers[i] = models.BulkTransfer{ToUser: user, Amount: share}
+		if i < rest {
+			transfers[i].Amount++
+		}
+	}
+	return transfers, nil
+}
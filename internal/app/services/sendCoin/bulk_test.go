@@ -0,0 +1,97 @@
+package sendCoin
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendController_SendBulk(t *testing.T) {
+	mockSt := &mockStorage{
+		GetUuidByUsernameFunc: func(ctx context.Context, username string) (string, error) {
+			return "uuid-" + username, nil
+		},
+	}
+	controller := &SendController{Storage: mockSt, Lfu: cache.NewLFUCache(10)}
+	token, _ := jwtToken.BuidToken("uuid-123")
+
+	doRequest := func(body any) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/sendCoin/bulk", bytes.NewReader(b))
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		controller.SendBulk(w, req)
+		return w
+	}
+
+	t.Run("invalid request -> 400", func(t *testing.T) {
+		for _, body := range []map[string]any{
+			{},
+			{"toUsers": []string{"bob"}},
+			{"toUsers": []string{"bob", "carol"}, "total": 1},
+			{"transfers": []map[string]any{{"toUser": "bob", "amount": 5}}, "total": 10},
+			{"toUsers": []string{"bob"}, "total": 10, "category": "cake"},
+		} {
+			w := doRequest(body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("even split gives the rest to the first recipients", func(t *testing.T) {
+		mockSt.SendBulkFunc = func(ctx context.Context, uuid string, transfers []models.BulkTransfer, note models.TransferNote) ([]models.BulkTransfer, error) {
+			assert.Equal(t, "uuid-123", uuid)
+			assert.Equal(t, []models.BulkTransfer{{ToUser: "bob", Amount: 34}, {ToUser: "carol", Amount: 33}, {ToUser: "dave", Amount: 33}}, transfers)
+			assert.Equal(t, models.CategoryTeamwork, note.Category)
+			for i := range transfers {
+				transfers[i].TransactionID = i + 1
+			}
+			return transfers, nil
+		}
+
+		w := doRequest(map[string]any{"toUsers": []string{"bob", "carol", "dave"}, "total": 100, "category": "teamwork"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result models.BulkSendResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Len(t, result.Transfers, 3)
+		assert.Equal(t, 3, result.Transfers[2].TransactionID)
+	})
+
+	t.Run("invalid recipients -> 400 with details", func(t *testing.T) {
+		mockSt.SendBulkFunc = func(ctx context.Context, uuid string, transfers []models.BulkTransfer, note models.TransferNote) ([]models.BulkTransfer, error) {
+			assert.Equal(t, []models.BulkTransfer{{ToUser: "bob", Amount: 10}, {ToUser: "nobody", Amount: 0}}, transfers)
+			return nil, &storage.RecipientsError{Recipients: []models.RecipientError{{Index: 1, ToUser: "nobody", Error: "user not found"}}}
+		}
+
+		w := doRequest(map[string]any{"transfers": []map[string]any{{"toUser": "bob", "amount": 10}, {"toUser": "nobody"}}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response models.BulkErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, []models.RecipientError{{Index: 1, ToUser: "nobody", Error: "user not found"}}, response.Recipients)
+	})
+
+	t.Run("not enough balance -> 409", func(t *testing.T) {
+		mockSt.SendBulkFunc = func(ctx context.Context, uuid string, transfers []models.BulkTransfer, note models.TransferNote) ([]models.BulkTransfer, error) {
+			return nil, storage.ErrNotEnoughBalance
+		}
+
+		w := doRequest(map[string]any{"toUsers": []string{"bob"}, "total": 5000})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("limit exceeded -> 429", func(t *testing.T) {
+		mockSt.SendBulkFunc = func(ctx context.Context, uuid string, transfers []models.BulkTransfer, note models.TransferNote) ([]models.BulkTransfer, error) {
+			return nil, &storage.LimitError{Limit: models.LimitDaily, Remaining: 20}
+		}
+
+		w := doRequest(map[string]any{"toUsers": []string{"bob"}, "total": 50})
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+}
@@ -11,10 +11,12 @@ import (
 	"errors"
 	"github.com/go-playground/validator/v10"
 	"net/http"
+	"strings"
 )
 
 type Storage interface {
-	Send(ctx context.Context, uuid string, toUser string, amount int) error
+	Send(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote) error
+	SendBulk(ctx context.Context, uuid string, transfers []models.BulkTransfer, note models.TransferNote) ([]models.BulkTransfer, error)
 	GetUuidByUsername(ctx context.Context, username string) (string, error)
 }
 
@@ -24,8 +26,10 @@ type SendController struct {
 }
 
 type SendRequest struct {
-	ToUser string `json:"toUser" validate:"required"`
-	Amount int    `json:"amount" validate:"required,ne=0"`
+	ToUser   string `json:"toUser" validate:"required"`
+	Amount   int    `json:"amount" validate:"required,gt=0"`
+	Message  string `json:"message" validate:"max=200"`
+	Category string `json:"category" validate:"omitempty,oneof=thanks help birthday teamwork"`
 }
 
 func (sc *SendController) SendCoin(w http.ResponseWriter, r *http.Request) {
@@ -48,9 +52,17 @@ func (sc *SendController) SendCoin(w http.ResponseWriter, r *http.Request) {
 		utils.JsonResponse(w, http.StatusInternalServerError, response)
 		return
 	}
-	err = sc.Storage.Send(r.Context(), uuid, req.ToUser, req.Amount)
+	err = sc.Storage.Send(r.Context(), uuid, req.ToUser, req.Amount, models.TransferNote{
+		Message:  strings.TrimSpace(req.Message),
+		Category: req.Category,
+	})
 	if err != nil {
+		var limitErr *storage.LimitError
 		switch {
+		case errors.As(err, &limitErr):
+			response := models.LimitErrorResponse{Errors: limitErr.Error(), Limit: limitErr.Limit, Remaining: limitErr.Remaining}
+			utils.JsonResponse(w, http.StatusTooManyRequests, response)
+			return
 		case errors.Is(err, storage.ErrNotEnoughBalance):
 			response := models.ErrorResponse{Errors: storage.ErrNotEnoughBalance.Error()}
 			utils.JsonResponse(w, http.StatusInternalServerError, response)
@@ -2,25 +2,33 @@ package sendCoin
 
 import (
 	"avito/internal/cache"
+	"avito/internal/models"
 	"avito/internal/storage"
 	"avito/internal/utils/jwtToken"
 	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
 type mockStorage struct {
-	SendFunc              func(ctx context.Context, uuid, toUser string, amount int) error
+	SendFunc              func(ctx context.Context, uuid, toUser string, amount int, note models.TransferNote) error
+	SendBulkFunc          func(ctx context.Context, uuid string, transfers []models.BulkTransfer, note models.TransferNote) ([]models.BulkTransfer, error)
 	GetUuidByUsernameFunc func(ctx context.Context, username string) (string, error)
 }
 
-func (m *mockStorage) Send(ctx context.Context, uuid, toUser string, amount int) error {
-	return m.SendFunc(ctx, uuid, toUser, amount)
+func (m *mockStorage) Send(ctx context.Context, uuid, toUser string, amount int, note models.TransferNote) error {
+	return m.SendFunc(ctx, uuid, toUser, amount, note)
+}
+
+func (m *mockStorage) SendBulk(ctx context.Context, uuid string, transfers []models.BulkTransfer, note models.TransferNote) ([]models.BulkTransfer, error) {
+	return m.SendBulkFunc(ctx, uuid, transfers, note)
 }
 
 func (m *mockStorage) GetUuidByUsername(ctx context.Context, username string) (string, error) {
@@ -82,7 +90,7 @@ func TestSendController_SendCoin(t *testing.T) {
 	})
 
 	t.Run("send success -> 200", func(t *testing.T) {
-		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int) error {
+		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int, note models.TransferNote) error {
 			assert.Equal(t, "uuid-123", uuid)
 			assert.Equal(t, "bob", toUser)
 			assert.Equal(t, 100, amount)
@@ -115,7 +123,7 @@ func TestSendController_SendCoin(t *testing.T) {
 	})
 
 	t.Run("not enough balance -> 500", func(t *testing.T) {
-		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int) error {
+		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int, note models.TransferNote) error {
 			return storage.ErrNotEnoughBalance
 		}
 		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
@@ -142,7 +150,7 @@ func TestSendController_SendCoin(t *testing.T) {
 	})
 
 	t.Run("sending to yourself -> 500", func(t *testing.T) {
-		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int) error {
+		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int, note models.TransferNote) error {
 			return storage.ErrSendingToYourself
 		}
 		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
@@ -169,7 +177,7 @@ func TestSendController_SendCoin(t *testing.T) {
 	})
 
 	t.Run("user not found -> 500", func(t *testing.T) {
-		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int) error {
+		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int, note models.TransferNote) error {
 			return storage.ErrUserNotFound
 		}
 		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
@@ -196,7 +204,7 @@ func TestSendController_SendCoin(t *testing.T) {
 	})
 
 	t.Run("other error -> 500", func(t *testing.T) {
-		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int) error {
+		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int, note models.TransferNote) error {
 			return errors.New("some error")
 		}
 		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
@@ -223,7 +231,7 @@ func TestSendController_SendCoin(t *testing.T) {
 	})
 
 	t.Run("GetUuidByUsername returns error but still 200", func(t *testing.T) {
-		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int) error {
+		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int, note models.TransferNote) error {
 			return nil
 		}
 		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
@@ -251,4 +259,64 @@ func TestSendController_SendCoin(t *testing.T) {
 		_, found := lfu.Get("any-key")
 		assert.False(t, found, "cache should be cleared")
 	})
+
+	t.Run("send with memo -> 200", func(t *testing.T) {
+		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int, note models.TransferNote) error {
+			assert.Equal(t, models.TransferNote{Message: "thanks for the review", Category: models.CategoryHelp}, note)
+			return nil
+		}
+		mockSt.GetUuidByUsernameFunc = func(ctx context.Context, username string) (string, error) {
+			return "receiver-uuid-456", nil
+		}
+
+		b, _ := json.Marshal(map[string]interface{}{
+			"toUser":   "bob",
+			"amount":   100,
+			"message":  "  thanks for the review ",
+			"category": "help",
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/sendCoin", bytes.NewBuffer(b))
+		token, _ := jwtToken.BuidToken("uuid-123")
+		req.Header.Set("Authorization", token)
+
+		w := httptest.NewRecorder()
+		controller.SendCoin(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("invalid memo -> 400", func(t *testing.T) {
+		for _, body := range []map[string]interface{}{
+			{"toUser": "bob", "amount": 100, "category": "bribe"},
+			{"toUser": "bob", "amount": 100, "message": strings.Repeat("a", 201)},
+		} {
+			b, _ := json.Marshal(body)
+			req := httptest.NewRequest(http.MethodPost, "/api/sendCoin", bytes.NewBuffer(b))
+			token, _ := jwtToken.BuidToken("uuid-123")
+			req.Header.Set("Authorization", token)
+
+			w := httptest.NewRecorder()
+			controller.SendCoin(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("limit exceeded -> 429 with the remaining allowance", func(t *testing.T) {
+		mockSt.SendFunc = func(ctx context.Context, uuid, toUser string, amount int, note models.TransferNote) error {
+			return fmt.Errorf("send: %w", &storage.LimitError{Limit: models.LimitDaily, Remaining: 30})
+		}
+
+		b, _ := json.Marshal(map[string]interface{}{"toUser": "bob", "amount": 100})
+		req := httptest.NewRequest(http.MethodPost, "/api/sendCoin", bytes.NewBuffer(b))
+		token, _ := jwtToken.BuidToken("uuid-123")
+		req.Header.Set("Authorization", token)
+
+		w := httptest.NewRecorder()
+		controller.SendCoin(w, req)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+		var response models.LimitErrorResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, models.LimitDaily, response.Limit)
+		assert.Equal(t, 30, response.Remaining)
+	})
 }
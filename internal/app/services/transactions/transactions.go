@@ -0,0 +1,150 @@
+package transactions
+
+import (
+	"avito/internal/models"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/schema"
+	"net/http"
+	"strconv"
+)
+
+const defaultLimit = 20
+
+type Storage interface {
+	GetTransactions(ctx context.Context, uuid string, filter models.TransactionFilter) ([]models.Transaction, error)
+}
+
+type TransactionsController struct {
+	Storage Storage
+}
+
+type TransactionsParams struct {
+	Direction    string `schema:"direction" validate:"omitempty,oneof=sent received"`
+	Counterparty string `schema:"counterparty"`
+	Category     string `schema:"category" validate:"omitempty,oneof=thanks help birthday teamwork"`
+	MinAmount    *int   `schema:"minAmount" validate:"omitempty,gt=0"`
+	MaxAmount    *int   `schema:"maxAmount" validate:"omitempty,gt=0"`
+	From         string `schema:"from"`
+	To           string `schema:"to"`
+	Cursor       string `schema:"cursor"`
+	Limit        int    `schema:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (tc *TransactionsController) Transactions(w http.ResponseWriter, r *http.Request) {
+	decoder := schema.NewDecoder()
+	validate := validator.New()
+
+	var params TransactionsParams
+	err := decoder.Decode(&params, r.URL.Query())
+	errValidate := validate.Struct(params)
+	if err != nil || errValidate != nil {
+		response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	filter, err := params.filter()
+	if err != nil {
+		response := models.ErrorResponse{Errors: err.Error()}
+		utils.JsonResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	cookie := r.Header.Get("Authorization")
+	uuid := jwtToken.GetUserID(cookie)
+	if uuid == "" {
+		response := models.ErrorResponse{Errors: "internal server error"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	// one extra row tells whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+	transactions, err := tc.Storage.GetTransactions(r.Context(), uuid, filter)
+	if err != nil {
+		response := models.ErrorResponse{Errors: "error getting transactions"}
+		utils.JsonResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	page := models.TransactionPage{Transactions: transactions}
+	if len(transactions) > limit {
+		page.Transactions = transactions[:limit]
+		page.NextCursor = EncodeCursor(page.Transactions[limit-1].ID)
+	}
+	if page.Transactions == nil {
+		page.Transactions = []models.Transaction{}
+	}
+
+	utils.JsonResponse(w, http.StatusOK, page)
+}
+
+func (p TransactionsParams) filter() (models.TransactionFilter, error) {
+	filter := models.TransactionFilter{
+		Direction:    p.Direction,
+		Counterparty: p.Counterparty,
+		Category:     p.Category,
+		MinAmount:    p.MinAmount,
+		MaxAmount:    p.MaxAmount,
+		Limit:        p.Limit,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultLimit
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		return filter, errors.New("minAmount is greater than maxAmount")
+	}
+
+	if p.From != "" {
+		from, _, err := utils.ParseTime(p.From)
+		if err != nil {
+			return filter, errors.New("invalid from date")
+		}
+		filter.From = &from
+	}
+	if p.To != "" {
+		to, dateOnly, err := utils.ParseTime(p.To)
+		if err != nil {
+			return filter, errors.New("invalid to date")
+		}
+		// a plain date includes the whole day
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = &to
+	}
+
+	if p.Cursor != "" {
+		id, err := DecodeCursor(p.Cursor)
+		if err != nil {
+			return filter, errors.New("invalid cursor")
+		}
+		filter.BeforeID = id
+	}
+	return filter, nil
+}
+
+func EncodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func DecodeCursor(cursor string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.Atoi(string(b))
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return id, nil
+}
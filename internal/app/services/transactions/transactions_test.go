@@ -0,0 +1,113 @@
+package transactions
+
+import (
+	"avito/internal/models"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type mockStorage struct {
+	GetTransactionsFunc func(ctx context.Context, uuid string, filter models.TransactionFilter) ([]models.Transaction, error)
+}
+
+func (m *mockStorage) GetTransactions(ctx context.Context, uuid string, filter models.TransactionFilter) ([]models.Transaction, error) {
+	return m.GetTransactionsFunc(ctx, uuid, filter)
+}
+
+func TestTransactionsController_Transactions(t *testing.T) {
+	mockSt := &mockStorage{}
+	controller := &TransactionsController{Storage: mockSt}
+	token, _ := jwtToken.BuidToken("uuid-123")
+
+	doRequest := func(url string, token string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		w := httptest.NewRecorder()
+		controller.Transactions(w, req)
+		return w.Result()
+	}
+
+	t.Run("invalid params -> 400", func(t *testing.T) {
+		for _, url := range []string{
+			"/api/transactions?direction=up",
+			"/api/transactions?category=bribe",
+			"/api/transactions?limit=1000",
+			"/api/transactions?minAmount=abc",
+			"/api/transactions?minAmount=10&maxAmount=5",
+			"/api/transactions?from=yesterday",
+			"/api/transactions?cursor=!!!",
+		} {
+			resp := doRequest(url, token)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, url)
+		}
+	})
+
+	t.Run("no JWT -> 500", func(t *testing.T) {
+		resp := doRequest("/api/transactions", "")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+
+	t.Run("filter is passed to storage", func(t *testing.T) {
+		mockSt.GetTransactionsFunc = func(ctx context.Context, uuid string, filter models.TransactionFilter) ([]models.Transaction, error) {
+			assert.Equal(t, "uuid-123", uuid)
+			assert.Equal(t, models.DirectionSent, filter.Direction)
+			assert.Equal(t, "bob", filter.Counterparty)
+			assert.Equal(t, models.CategoryHelp, filter.Category)
+			assert.Equal(t, 10, *filter.MinAmount)
+			assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), *filter.From)
+			assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), *filter.To)
+			assert.Equal(t, 42, filter.BeforeID)
+			assert.Equal(t, 6, filter.Limit)
+			return nil, nil
+		}
+
+		url := "/api/transactions?direction=sent&counterparty=bob&category=help&minAmount=10&from=2025-02-01&to=2025-02-28&limit=5&cursor=" + EncodeCursor(42)
+		resp := doRequest(url, token)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var page models.TransactionPage
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		assert.NotNil(t, page.Transactions)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("next cursor points to the last returned transaction", func(t *testing.T) {
+		mockSt.GetTransactionsFunc = func(ctx context.Context, uuid string, filter models.TransactionFilter) ([]models.Transaction, error) {
+			return []models.Transaction{{ID: 9}, {ID: 7}, {ID: 4}}, nil
+		}
+
+		resp := doRequest("/api/transactions?limit=2", token)
+		defer resp.Body.Close()
+
+		var page models.TransactionPage
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		assert.Len(t, page.Transactions, 2)
+		id, err := DecodeCursor(page.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, 7, id)
+	})
+
+	t.Run("storage error -> 500", func(t *testing.T) {
+		mockSt.GetTransactionsFunc = func(ctx context.Context, uuid string, filter models.TransactionFilter) ([]models.Transaction, error) {
+			return nil, errors.New("some error")
+		}
+
+		resp := doRequest("/api/transactions", token)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+}
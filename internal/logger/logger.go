@@ -22,7 +22,7 @@ func (L *LogResponseWriter) Write(b []byte) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	L.Log.Size = size
+	L.Log.Size += size
 	return size, nil
 }
 
@@ -31,6 +31,11 @@ func (L *LogResponseWriter) WriteHeader(statusCode int) {
 	L.Log.StatusCode = statusCode
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer
+func (L *LogResponseWriter) Unwrap() http.ResponseWriter {
+	return L.ResponseWriter
+}
+
 func InitLogger() error {
 	cfg := zap.NewProductionConfig()
 	cfg.Level.SetLevel(zap.InfoLevel)
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"avito/internal/models"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"context"
+	"net/http"
+)
+
+type AdminStorage interface {
+	GetUserRole(ctx context.Context, uuid string) (string, error)
+}
+
+// Admin lets through only users with the admin role. Must be placed after Cookie.
+func Admin(s AdminStorage, h http.HandlerFunc) http.HandlerFunc {
+	foo := func(w http.ResponseWriter, r *http.Request) {
+		uuid := jwtToken.GetUserID(r.Header.Get("Authorization"))
+		if uuid == "" {
+			response := models.ErrorResponse{Errors: "unauthorized"}
+			utils.JsonResponse(w, http.StatusUnauthorized, response)
+			return
+		}
+		role, err := s.GetUserRole(r.Context(), uuid)
+		if err != nil || role != models.RoleAdmin {
+			response := models.ErrorResponse{Errors: "forbidden"}
+			utils.JsonResponse(w, http.StatusForbidden, response)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	}
+	return foo
+}
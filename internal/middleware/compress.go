@@ -22,6 +22,12 @@ func (c *CompressWrite) Write(b []byte) (int, error) {
 	return res, err
 }
 
+// Flush sends what was compressed so far, streamed responses rely on it
+func (c *CompressWrite) Flush() {
+	c.zw.Flush()
+	http.NewResponseController(c.ResponseWriter).Flush()
+}
+
 type CompressRead struct {
 	io.ReadCloser
 	zr *gzip.Reader
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"avito/internal/logger"
+	"avito/internal/models"
+	"avito/internal/storage"
+	"avito/internal/utils"
+	"avito/internal/utils/jwtToken"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+)
+
+const IdempotencyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLength = 255
+
+type IdempotencyStorage interface {
+	ReserveIdempotencyKey(ctx context.Context, uuid, key, requestHash string) (*models.IdempotentResponse, error)
+	SaveIdempotentResponse(ctx context.Context, uuid, key string, response models.IdempotentResponse) error
+	ReleaseIdempotencyKey(ctx context.Context, uuid, key string) error
+}
+
+type recordWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordWriter) WriteHeader(statusCode int) {
+	rw.status = statusCode
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *recordWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Idempotency replays the stored response when a request is retried with the
+// same Idempotency-Key. Must be placed after Cookie, keys are scoped per user.
+func Idempotency(s IdempotencyStorage, h http.HandlerFunc) http.HandlerFunc {
+	foo := func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyHeader)
+		if key == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			response := models.ErrorResponse{Errors: "idempotency key is too long"}
+			utils.JsonResponse(w, http.StatusBadRequest, response)
+			return
+		}
+
+		uuid := jwtToken.GetUserID(r.Header.Get("Authorization"))
+		if uuid == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response := models.ErrorResponse{Errors: "the request parameters are incorrect"}
+			utils.JsonResponse(w, http.StatusBadRequest, response)
+			return
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		stored, err := s.ReserveIdempotencyKey(r.Context(), uuid, key, requestHash)
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrIdempotencyKeyReused):
+				response := models.ErrorResponse{Errors: storage.ErrIdempotencyKeyReused.Error()}
+				utils.JsonResponse(w, http.StatusUnprocessableEntity, response)
+			case errors.Is(err, storage.ErrIdempotencyKeyInProgress):
+				response := models.ErrorResponse{Errors: storage.ErrIdempotencyKeyInProgress.Error()}
+				utils.JsonResponse(w, http.StatusConflict, response)
+			default:
+				response := models.ErrorResponse{Errors: "internal server error"}
+				utils.JsonResponse(w, http.StatusInternalServerError, response)
+			}
+			return
+		}
+		if stored != nil {
+			if stored.ContentType != "" {
+				w.Header().Set("Content-Type", stored.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		// Send and Buy store the response in their own transaction, for the
+		// other operations it is saved below
+		rw := &recordWriter{ResponseWriter: w}
+		h.ServeHTTP(rw, r.WithContext(storage.WithIdempotencyKey(r.Context(), uuid, key)))
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+
+		// server errors are not stored so the client can retry with the same key
+		ctx := context.WithoutCancel(r.Context())
+		if rw.status >= http.StatusInternalServerError {
+			err = s.ReleaseIdempotencyKey(ctx, uuid, key)
+		} else {
+			err = s.SaveIdempotentResponse(ctx, uuid, key, models.IdempotentResponse{
+				Status:      rw.status,
+				ContentType: rw.Header().Get("Content-Type"),
+				Body:        rw.body.Bytes(),
+			})
+		}
+		// the key stays reserved until IdempotencyKeyTimeout
+		if err != nil {
+			logger.Log.Error("idempotency key not updated", zap.String("URI", r.RequestURI), zap.Error(err))
+		}
+	}
+	return foo
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// statuses of an auction
+const (
+	AuctionStatusOpen = "open"
+	// closed with a winner, who got the item as an order
+	AuctionStatusSold = "sold"
+	// closed without bids, the item went back to stock
+	AuctionStatusUnsold = "unsold"
+)
+
+// statuses of a bid
+const (
+	// the highest bid, its coins are reserved in escrow
+	BidStatusHeld     = "held"
+	BidStatusReleased = "released"
+	BidStatusWon      = "won"
+)
+
+// Auction sells one item to the highest bid placed before EndsAt.
+type Auction struct {
+	ID          int        `json:"id"`
+	Item        string     `json:"item"`
+	StartingBid int        `json:"startingBid"`
+	EndsAt      time.Time  `json:"endsAt"`
+	Status      string     `json:"status"`
+	TopBid      *Bid       `json:"topBid,omitempty"`
+	Winner      string     `json:"winner,omitempty"`
+	OrderID     int        `json:"orderId,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ClosedAt    *time.Time `json:"closedAt,omitempty"`
+	// highest first, only returned for a single auction
+	Bids []Bid `json:"bids,omitempty"`
+}
+
+type Bid struct {
+	ID        int       `json:"id"`
+	User      string    `json:"user"`
+	Amount    int       `json:"amount"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// BulkTransfer is one recipient of a bulk send, TransactionID is set once
+// the coins were sent.
+type BulkTransfer struct {
+	ToUser        string `json:"toUser"`
+	Amount        int    `json:"amount"`
+	TransactionID int    `json:"transactionId,omitempty"`
+}
+
+// RecipientError tells why one recipient of a bulk send was rejected, Index
+// is the recipient's position in the request.
+type RecipientError struct {
+	Index  int    `json:"index"`
+	ToUser string `json:"toUser"`
+	Error  string `json:"error"`
+}
+
+type BulkSendResult struct {
+	Transfers []BulkTransfer `json:"transfers"`
+}
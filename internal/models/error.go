@@ -3,3 +3,15 @@ package models
 type ErrorResponse struct {
 	Errors string `json:"errors"`
 }
+
+type LimitErrorResponse struct {
+	Errors string `json:"errors"`
+	Limit  string `json:"limit"`
+	// how many coins can still be sent right now
+	Remaining int `json:"remaining"`
+}
+
+type BulkErrorResponse struct {
+	Errors     string           `json:"errors"`
+	Recipients []RecipientError `json:"recipients"`
+}
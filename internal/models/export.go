@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// export formats
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+)
+
+// kinds of exported activity
+const (
+	ActivityTransfer = "transfer"
+	ActivityPurchase = "purchase"
+	ActivityRefund   = "refund"
+)
+
+// ExportFilter limits an export to [From, To), nil bounds are open.
+type ExportFilter struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// ActivityRecord is one exported row. Purchases have one row per item and
+// only From set, unless they are gifts, refunds only To.
+type ActivityRecord struct {
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+	// id of the transfer, the order or the refund
+	ID       int    `json:"id"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Item     string `json:"item,omitempty"`
+	Quantity int    `json:"quantity,omitempty"`
+	Amount   int    `json:"amount"`
+	Message  string `json:"message,omitempty"`
+	Category string `json:"category,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Gift is merch bought by FromUser for ToUser. Prices are left out, the
+// sender finds them in the purchase history.
+type Gift struct {
+	OrderID   int       `json:"orderId"`
+	FromUser  string    `json:"fromUser"`
+	ToUser    string    `json:"toUser"`
+	Items     []Item    `json:"items"`
+	Message   string    `json:"message,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type GiftHistory struct {
+	Received []Gift `json:"received"`
+	Sent     []Gift `json:"sent"`
+}
@@ -0,0 +1,7 @@
+package models
+
+type IdempotentResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
@@ -1,9 +1,19 @@
 package models
 
+import "time"
+
 type Info struct {
-	Coins        int     `json:"coins"`
-	CoinsHistory History `json:"coinHistory"`
-	Inventory    []Item  `json:"inventory"`
+	Coins        int         `json:"coins"`
+	CoinsHistory History     `json:"coinHistory"`
+	GiftHistory  GiftHistory `json:"giftHistory"`
+	Inventory    []Item      `json:"inventory"`
+	// coins that lapse unless spent, soonest first
+	Expiring []CoinLot `json:"expiring,omitempty"`
+}
+
+type CoinLot struct {
+	Amount    int       `json:"amount"`
+	ExpiresAt time.Time `json:"expiresAt"`
 }
 
 type Item struct {
@@ -17,7 +27,28 @@ type History struct {
 }
 
 type Transaction struct {
-	FromUser string `json:"fromUser"`
-	ToUser   string `json:"toUser"`
-	Amount   int    `json:"amount"`
+	ID        int       `json:"id"`
+	FromUser  string    `json:"fromUser"`
+	ToUser    string    `json:"toUser"`
+	Amount    int       `json:"amount"`
+	CreatedAt time.Time `json:"createdAt"`
+	// set on compensating transactions created by a reversal
+	ReversesID int    `json:"reversesId,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Category   string `json:"category,omitempty"`
+}
+
+// CounterpartyTotal sums the transfers between the user and one counterparty
+// in one direction.
+type CounterpartyTotal struct {
+	User   string `json:"user"`
+	Amount int    `json:"amount"`
+	Count  int    `json:"count"`
+}
+
+// Counterparties is the coin history grouped by counterparty, largest
+// totals first.
+type Counterparties struct {
+	Received []CounterpartyTotal `json:"received"`
+	Sent     []CounterpartyTotal `json:"sent"`
 }
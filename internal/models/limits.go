@@ -0,0 +1,26 @@
+package models
+
+// scopes of sending limits, a user override beats a role override which beats the global default
+const (
+	LimitScopeGlobal = "global"
+	LimitScopeRole   = "role"
+	LimitScopeUser   = "user"
+)
+
+// kinds of sending limits
+const (
+	LimitPerTransfer = "perTransfer"
+	LimitDaily       = "daily"
+	LimitWeekly      = "weekly"
+)
+
+// SendLimit caps outgoing coins. A nil field is inherited from the wider
+// scope, zero means no limit.
+type SendLimit struct {
+	Scope string `json:"scope" validate:"required,oneof=global role user"`
+	// role name or username, empty for the global default
+	Subject     string `json:"subject,omitempty"`
+	PerTransfer *int   `json:"perTransfer,omitempty" validate:"omitempty,gte=0"`
+	Daily       *int   `json:"daily,omitempty" validate:"omitempty,gte=0"`
+	Weekly      *int   `json:"weekly,omitempty" validate:"omitempty,gte=0"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// statuses of a marketplace listing
+const (
+	ListingStatusActive    = "active"
+	ListingStatusSold      = "sold"
+	ListingStatusCancelled = "cancelled"
+)
+
+// Listing offers Quantity of Item from the seller's inventory for Price coins
+// in total. Listed items are out of the inventory until the listing is sold
+// or cancelled.
+type Listing struct {
+	ID       int    `json:"id"`
+	Seller   string `json:"seller"`
+	Item     string `json:"item"`
+	Quantity int    `json:"quantity"`
+	Price    int    `json:"price"`
+	Status   string `json:"status"`
+	Buyer    string `json:"buyer,omitempty"`
+	// transfer from the buyer to the seller
+	TransactionID int        `json:"transactionId,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	ClosedAt      *time.Time `json:"closedAt,omitempty"`
+}
+
+type ListingFilter struct {
+	Item string
+	// active listings of everyone, or every listing the user sold or bought
+	Mine bool
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+type Merch struct {
+	Name        string `json:"name"`
+	Price       int    `json:"price"`
+	Description string `json:"description"`
+	Available   bool   `json:"available"`
+	Hidden      bool   `json:"hidden,omitempty"`
+	// nil means unlimited supply
+	Stock *int `json:"stock,omitempty"`
+}
+
+type MerchUpdate struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=100"`
+	Price       *int    `json:"price" validate:"omitempty,gt=0"`
+	Description *string `json:"description"`
+	Available   *bool   `json:"available"`
+}
+
+const (
+	MerchActionCreate  = "create"
+	MerchActionUpdate  = "update"
+	MerchActionHide    = "hide"
+	MerchActionUnhide  = "unhide"
+	MerchActionRetire  = "retire"
+	MerchActionRestock = "restock"
+)
+
+// MerchChange is a snapshot of an item after an admin change.
+type MerchChange struct {
+	Action      string    `json:"action"`
+	Name        string    `json:"name"`
+	Price       int       `json:"price"`
+	Description string    `json:"description"`
+	Available   bool      `json:"available"`
+	Hidden      bool      `json:"hidden"`
+	Stock       *int      `json:"stock,omitempty"`
+	ChangedBy   string    `json:"changedBy,omitempty"`
+	ChangedAt   time.Time `json:"changedAt"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+type OrderLine struct {
+	Item      string `json:"item"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int    `json:"unitPrice"`
+	// quantity already returned
+	Refunded int `json:"refunded,omitempty"`
+}
+
+type Order struct {
+	ID        int         `json:"id"`
+	Items     []OrderLine `json:"items"`
+	Total     int         `json:"total"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Refunds   []Refund    `json:"refunds,omitempty"`
+	// set on gifts, the items went to ToUser
+	ToUser  string `json:"toUser,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type Refund struct {
+	ID      int    `json:"id"`
+	OrderID int    `json:"orderId"`
+	User    string `json:"user"`
+	Item    string `json:"item"`
+	// quantity returned and coins credited back at the price paid
+	Quantity  int       `json:"quantity"`
+	Amount    int       `json:"amount"`
+	CreatedAt time.Time `json:"createdAt"`
+	// set when a gift is refunded, the items were taken back from ToUser
+	ToUser string `json:"toUser,omitempty"`
+}
+
+type PurchaseFilter struct {
+	Item string
+	// only orders with id lower than BeforeID are returned, 0 means from the newest one
+	BeforeID int
+	Limit    int
+}
+
+type PurchasePage struct {
+	Purchases  []Order `json:"purchases"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// statuses of one payer of a payment request
+const (
+	PaymentStatusPending  = "pending"
+	PaymentStatusPaid     = "paid"
+	PaymentStatusRejected = "rejected"
+)
+
+// PaymentRequest asks every payer for Amount coins. The requester sees all
+// payers, a payer only sees itself.
+type PaymentRequest struct {
+	ID        int       `json:"id"`
+	Requester string    `json:"requester"`
+	Amount    int       `json:"amount"`
+	Message   string    `json:"message,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	Payers    []Payer   `json:"payers"`
+}
+
+type Payer struct {
+	User       string     `json:"user"`
+	Status     string     `json:"status"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	// transfer made when the payer paid
+	TransactionID int `json:"transactionId,omitempty"`
+}
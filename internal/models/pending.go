@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	PendingStatusPending  = "pending"
+	PendingStatusAccepted = "accepted"
+	PendingStatusDeclined = "declined"
+	PendingStatusExpired  = "expired"
+)
+
+// PendingTransfer is an offer of coins held in escrow until the recipient
+// accepts or declines it
+type PendingTransfer struct {
+	ID         int        `json:"id"`
+	FromUser   string     `json:"fromUser"`
+	ToUser     string     `json:"toUser"`
+	Amount     int        `json:"amount"`
+	Message    string     `json:"message,omitempty"`
+	Category   string     `json:"category,omitempty"`
+	Status     string     `json:"status"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	// transfer made on accept
+	TransactionID int `json:"transactionId,omitempty"`
+}
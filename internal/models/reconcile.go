@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// wallet checks made by reconciliation
+const (
+	// users.balance must not be negative
+	CheckNegativeBalance = "negative_balance"
+	// users.balance must match the wallet's ledger postings
+	CheckLedger = "ledger"
+	// the ledger must match what transfers, purchases, refunds, holds and issuance add up to
+	CheckActivity = "activity"
+	// coin lots must add up to the balance
+	CheckLots = "lots"
+)
+
+type Discrepancy struct {
+	User     string `json:"user"`
+	Check    string `json:"check"`
+	Expected int    `json:"expected"`
+	Actual   int    `json:"actual"`
+	Repaired bool   `json:"repaired"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ReconcileReport struct {
+	// set when the run was recorded, which only happens with repair
+	RunID   int       `json:"runId,omitempty"`
+	Checked time.Time `json:"checkedAt"`
+	Wallets int       `json:"wallets"`
+	// ledger entries whose postings don't sum to zero
+	UnbalancedEntries []int         `json:"unbalancedEntries"`
+	Discrepancies     []Discrepancy `json:"discrepancies"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const (
+	IntervalOnce    = "once"
+	IntervalDaily   = "daily"
+	IntervalWeekly  = "weekly"
+	IntervalMonthly = "monthly"
+)
+
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+type ScheduledTransfer struct {
+	ID       int    `json:"id"`
+	ToUser   string `json:"toUser"`
+	Amount   int    `json:"amount"`
+	Message  string `json:"message,omitempty"`
+	Category string `json:"category,omitempty"`
+	Interval string `json:"interval"`
+	// nil once a one-off transfer has run
+	NextRunAt *time.Time `json:"nextRunAt"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// ScheduleUpdate changes only the fields that are set
+type ScheduleUpdate struct {
+	Amount    *int       `json:"amount" validate:"omitempty,gt=0"`
+	Interval  *string    `json:"interval" validate:"omitempty,oneof=once daily weekly monthly"`
+	NextRunAt *time.Time `json:"nextRunAt"`
+	Active    *bool      `json:"active"`
+}
+
+type ScheduledRun struct {
+	ID          int       `json:"id"`
+	ScheduleID  int       `json:"scheduleId"`
+	ScheduledAt time.Time `json:"scheduledAt"`
+	ExecutedAt  time.Time `json:"executedAt"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	// transfer made by a successful run
+	TransactionID int `json:"transactionId,omitempty"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+const (
+	DirectionSent     = "sent"
+	DirectionReceived = "received"
+)
+
+// transfer categories, coins are mostly sent as thanks
+const (
+	CategoryThanks   = "thanks"
+	CategoryHelp     = "help"
+	CategoryBirthday = "birthday"
+	CategoryTeamwork = "teamwork"
+)
+
+// TransferNote is the optional memo attached to a transfer
+type TransferNote struct {
+	Message  string
+	Category string
+}
+
+type TransactionFilter struct {
+	Direction    string
+	Counterparty string
+	Category     string
+	MinAmount    *int
+	MaxAmount    *int
+	From         *time.Time
+	To           *time.Time
+	// only transactions with id lower than BeforeID are returned, 0 means from the newest one
+	BeforeID int
+	Limit    int
+}
+
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"nextCursor,omitempty"`
+}
+
+// what to do when the recipient has already spent part of a reversed transfer
+const (
+	ReversalPolicyFail    = "fail"
+	ReversalPolicyPartial = "partial"
+)
+
+type Reversal struct {
+	// compensating transaction from the original recipient back to the sender
+	ID         int    `json:"id"`
+	OriginalID int    `json:"originalId"`
+	FromUser   string `json:"fromUser"`
+	ToUser     string `json:"toUser"`
+	Amount     int    `json:"amount"`
+	// part of the original transfer that is still not reversed
+	Remaining int       `json:"remaining"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -5,10 +5,18 @@ import (
 	"time"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+	// the issuer of allowances, it can't log in or receive coins
+	RoleSystem = "system"
+)
+
 type User struct {
 	Balance      int
 	Username     string
 	PasswordHash string
+	Role         string
 	UUID         uuid.UUID
 	CreatedAt    time.Time
 }
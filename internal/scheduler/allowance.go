@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"avito/internal/cache"
+	"avito/internal/logger"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+const allowanceBatch = 100
+
+type AllowanceStorage interface {
+	ResumeAllowancePeriod(ctx context.Context) (*time.Time, error)
+	IssueAllowances(ctx context.Context, period time.Time, amount int, expiresAt *time.Time, limit int) (int, error)
+}
+
+// Allowance credits amount coins to every user once a month. It starts from
+// the oldest month some user was skipped for, e.g. while a concurrent
+// transfer held their row, so partly issued months are finished and months
+// missed while the server was down are caught up.
+// Unless lifetime is zero the coins lapse that many months after the period
+// starts.
+func Allowance(storage AllowanceStorage, lfu *cache.LFUCache, amount int, lifetime int) Job {
+	return Job{
+		Name: "monthly allowance",
+		Run: func(ctx context.Context, now time.Time) error {
+			current := periodStart(now)
+			period := current
+			resume, err := storage.ResumeAllowancePeriod(ctx)
+			if err != nil {
+				return err
+			}
+			if resume != nil && resume.Before(current) {
+				period = periodStart(*resume)
+			}
+
+			for ; !period.After(current); period = period.AddDate(0, 1, 0) {
+				var expiresAt *time.Time
+				if lifetime > 0 {
+					at := period.AddDate(0, lifetime, 0)
+					expiresAt = &at
+				}
+				for {
+					issued, err := storage.IssueAllowances(ctx, period, amount, expiresAt, allowanceBatch)
+					if issued > 0 {
+						lfu.ClearCache()
+						logger.Log.Info("allowance issued",
+							zap.Time("period", period),
+							zap.Int("users", issued),
+						)
+					}
+					if err != nil {
+						return err
+					}
+					if issued < allowanceBatch {
+						break
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// periodStart returns the first day of the month in UTC
+func periodStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"context"
+	"time"
+)
+
+const auctionBatch = 100
+
+type AuctionStorage interface {
+	CloseAuctions(ctx context.Context, now time.Time, limit int) ([]models.Auction, error)
+}
+
+// CloseAuctions settles auctions that ended: the winner gets the item,
+// auctions without bids return it to stock. Auctions that failed to close
+// stay open and are retried on the next run.
+func CloseAuctions(storage AuctionStorage, lfu *cache.LFUCache) Job {
+	return Job{
+		Name: "auctions close",
+		Run: func(ctx context.Context, now time.Time) error {
+			for {
+				closed, err := storage.CloseAuctions(ctx, now, auctionBatch)
+				if len(closed) > 0 {
+					lfu.ClearCache()
+				}
+				if err != nil || len(closed) < auctionBatch {
+					return err
+				}
+			}
+		},
+	}
+}
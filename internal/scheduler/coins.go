@@ -0,0 +1,31 @@
+package scheduler
+
+import (
+	"avito/internal/cache"
+	"context"
+	"time"
+)
+
+const coinsBatch = 100
+
+type CoinsStorage interface {
+	ExpireCoins(ctx context.Context, now time.Time, limit int) (int, error)
+}
+
+// ExpireCoins burns the coins of lots that lapsed.
+func ExpireCoins(storage CoinsStorage, lfu *cache.LFUCache) Job {
+	return Job{
+		Name: "coins expiry",
+		Run: func(ctx context.Context, now time.Time) error {
+			for {
+				expired, err := storage.ExpireCoins(ctx, now, coinsBatch)
+				if expired > 0 {
+					lfu.ClearCache()
+				}
+				if err != nil || expired < coinsBatch {
+					return err
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,32 @@
+package scheduler
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"context"
+	"time"
+)
+
+const pendingBatch = 100
+
+type PendingStorage interface {
+	ExpirePendingTransfers(ctx context.Context, now time.Time, limit int) ([]models.PendingTransfer, error)
+}
+
+// ExpirePendingTransfers returns the coins of offers nobody accepted in time.
+func ExpirePendingTransfers(storage PendingStorage, lfu *cache.LFUCache) Job {
+	return Job{
+		Name: "pending transfers expiry",
+		Run: func(ctx context.Context, now time.Time) error {
+			for {
+				expired, err := storage.ExpirePendingTransfers(ctx, now, pendingBatch)
+				if len(expired) > 0 {
+					lfu.ClearCache()
+				}
+				if err != nil || len(expired) < pendingBatch {
+					return err
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"avito/internal/logger"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Job is background work done on every tick of the scheduler
+type Job struct {
+	Name string
+	Run  func(ctx context.Context, now time.Time) error
+}
+
+type Scheduler struct {
+	Interval time.Duration
+	Jobs     []Job
+}
+
+// Run runs all jobs right away and then every Interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Tick(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick runs every job once. A failing job is logged and does not stop the others.
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) {
+	for _, job := range s.Jobs {
+		if err := job.Run(ctx, now); err != nil {
+			logger.Log.Error("scheduler job failed", zap.String("job", job.Name), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package scheduler
+
+import (
+	"avito/internal/cache"
+	"avito/internal/models"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockTransfersStorage struct {
+	RunDueSchedulesFunc func(ctx context.Context, now time.Time, limit int) ([]models.ScheduledRun, error)
+}
+
+func (m *mockTransfersStorage) RunDueSchedules(ctx context.Context, now time.Time, limit int) ([]models.ScheduledRun, error) {
+	return m.RunDueSchedulesFunc(ctx, now, limit)
+}
+
+type mockPendingStorage struct {
+	ExpirePendingTransfersFunc func(ctx context.Context, now time.Time, limit int) ([]models.PendingTransfer, error)
+}
+
+func (m *mockPendingStorage) ExpirePendingTransfers(ctx context.Context, now time.Time, limit int) ([]models.PendingTransfer, error) {
+	return m.ExpirePendingTransfersFunc(ctx, now, limit)
+}
+
+func TestScheduler_Tick(t *testing.T) {
+	var ran []string
+	s := Scheduler{Jobs: []Job{
+		{Name: "failing", Run: func(ctx context.Context, now time.Time) error {
+			ran = append(ran, "failing")
+			return errors.New("boom")
+		}},
+		{Name: "next", Run: func(ctx context.Context, now time.Time) error {
+			ran = append(ran, "next")
+			return nil
+		}},
+	}}
+
+	s.Tick(context.Background(), time.Now())
+	assert.Equal(t, []string{"failing", "next"}, ran)
+}
+
+func TestScheduledTransfers(t *testing.T) {
+	now := time.Now()
+	lfu := cache.NewLFUCache(10)
+
+	t.Run("runs batches until there is nothing due", func(t *testing.T) {
+		calls := 0
+		storage := &mockTransfersStorage{
+			RunDueSchedulesFunc: func(ctx context.Context, at time.Time, limit int) ([]models.ScheduledRun, error) {
+				assert.Equal(t, now, at)
+				calls++
+				if calls == 1 {
+					return make([]models.ScheduledRun, limit), nil
+				}
+				return []models.ScheduledRun{{Status: models.RunSucceeded}}, nil
+			},
+		}
+		lfu.Set("uuid", "{}")
+
+		assert.NoError(t, ScheduledTransfers(storage, lfu).Run(context.Background(), now))
+		assert.Equal(t, 2, calls)
+		_, ok := lfu.Get("uuid")
+		assert.False(t, ok)
+	})
+
+	t.Run("failed runs keep the cache", func(t *testing.T) {
+		storage := &mockTransfersStorage{
+			RunDueSchedulesFunc: func(ctx context.Context, at time.Time, limit int) ([]models.ScheduledRun, error) {
+				return []models.ScheduledRun{{Status: models.RunFailed, Error: "not enough tokens"}}, nil
+			},
+		}
+		lfu.Set("uuid", "{}")
+
+		assert.NoError(t, ScheduledTransfers(storage, lfu).Run(context.Background(), now))
+		_, ok := lfu.Get("uuid")
+		assert.True(t, ok)
+	})
+}
+
+func TestExpirePendingTransfers(t *testing.T) {
+	now := time.Now()
+	lfu := cache.NewLFUCache(10)
+
+	calls := 0
+	storage := &mockPendingStorage{
+		ExpirePendingTransfersFunc: func(ctx context.Context, at time.Time, limit int) ([]models.PendingTransfer, error) {
+			calls++
+			if calls == 1 {
+				return make([]models.PendingTransfer, limit), nil
+			}
+			return nil, errors.New("boom")
+		},
+	}
+	lfu.Set("uuid", "{}")
+
+	assert.Error(t, ExpirePendingTransfers(storage, lfu).Run(context.Background(), now))
+	assert.Equal(t, 2, calls)
+	_, ok := lfu.Get("uuid")
+	assert.False(t, ok)
+}
+
+type mockAuctionStorage struct {
+	CloseAuctionsFunc func(ctx context.Context, now time.Time, limit int) ([]models.Auction, error)
+}
+
+func (m *mockAuctionStorage) CloseAuctions(ctx context.Context, now time.Time, limit int) ([]models.Auction, error) {
+	return m.CloseAuctionsFunc(ctx, now, limit)
+}
+
+func TestCloseAuctions(t *testing.T) {
+	now := time.Now()
+	lfu := cache.NewLFUCache(10)
+
+	calls := 0
+	storage := &mockAuctionStorage{
+		CloseAuctionsFunc: func(ctx context.Context, at time.Time, limit int) ([]models.Auction, error) {
+			calls++
+			assert.Equal(t, now, at)
+			if calls == 1 {
+				return make([]models.Auction, limit), nil
+			}
+			return []models.Auction{{ID: 1}}, nil
+		},
+	}
+	lfu.Set("uuid", "{}")
+
+	assert.NoError(t, CloseAuctions(storage, lfu).Run(context.Background(), now))
+	assert.Equal(t, 2, calls)
+	_, ok := lfu.Get("uuid")
+	assert.False(t, ok)
+}
+
+func TestCloseAuctionsFailures(t *testing.T) {
+	lfu := cache.NewLFUCache(10)
+	storage := &mockAuctionStorage{
+		CloseAuctionsFunc: func(ctx context.Context, at time.Time, limit int) ([]models.Auction, error) {
+			return []models.Auction{{ID: 2}}, errors.New("auction 1: boom")
+		},
+	}
+	lfu.Set("uuid", "{}")
+
+	// the auctions that did close are still reported to the cache
+	assert.Error(t, CloseAuctions(storage, lfu).Run(context.Background(), time.Now()))
+	_, ok := lfu.Get("uuid")
+	assert.False(t, ok)
+}
+
+type mockAllowanceStorage struct {
+	last    *time.Time
+	issued  []time.Time
+	expires []*time.Time
+}
+
+func (m *mockAllowanceStorage) ResumeAllowancePeriod(ctx context.Context) (*time.Time, error) {
+	return m.last, nil
+}
+
+func (m *mockAllowanceStorage) IssueAllowances(ctx context.Context, period time.Time, amount int, expiresAt *time.Time, limit int) (int, error) {
+	m.issued = append(m.issued, period)
+	m.expires = append(m.expires, expiresAt)
+	return 1, nil
+}
+
+func TestAllowance(t *testing.T) {
+	now := time.Date(2025, 5, 17, 10, 0, 0, 0, time.UTC)
+	lfu := cache.NewLFUCache(10)
+
+	t.Run("first run issues the current month only", func(t *testing.T) {
+		storage := &mockAllowanceStorage{}
+		assert.NoError(t, Allowance(storage, lfu, 100, 0).Run(context.Background(), now))
+		assert.Equal(t, []time.Time{time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)}, storage.issued)
+		assert.Nil(t, storage.expires[0])
+	})
+
+	t.Run("missed months are caught up", func(t *testing.T) {
+		last := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		storage := &mockAllowanceStorage{last: &last}
+		assert.NoError(t, Allowance(storage, lfu, 100, 3).Run(context.Background(), now))
+		assert.Equal(t, time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), *storage.expires[0])
+		assert.Equal(t, []time.Time{
+			last,
+			time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC),
+		}, storage.issued)
+	})
+}
+
+type mockCoinsStorage struct {
+	ExpireCoinsFunc func(ctx context.Context, now time.Time, limit int) (int, error)
+}
+
+func (m *mockCoinsStorage) ExpireCoins(ctx context.Context, now time.Time, limit int) (int, error) {
+	return m.ExpireCoinsFunc(ctx, now, limit)
+}
+
+func TestExpireCoins(t *testing.T) {
+	lfu := cache.NewLFUCache(10)
+	calls := 0
+	storage := &mockCoinsStorage{
+		ExpireCoinsFunc: func(ctx context.Context, now time.Time, limit int) (int, error) {
+			calls++
+			if calls == 1 {
+				return limit, nil
+			}
+			return 0, nil
+		},
+	}
+	lfu.Set("uuid", "{}")
+
+	assert.NoError(t, ExpireCoins(storage, lfu).Run(context.Background(), time.Now()))
+	assert.Equal(t, 2, calls)
+	_, ok := lfu.Get("uuid")
+	assert.False(t, ok)
+}
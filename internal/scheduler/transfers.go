@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"avito/internal/cache"
+	"avito/internal/logger"
+	"avito/internal/models"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+const transfersBatch = 100
+
+type TransfersStorage interface {
+	RunDueSchedules(ctx context.Context, now time.Time, limit int) ([]models.ScheduledRun, error)
+}
+
+// ScheduledTransfers makes the transfers that are due. Runs are recorded by
+// the storage, failed ones are also logged.
+func ScheduledTransfers(storage TransfersStorage, lfu *cache.LFUCache) Job {
+	return Job{
+		Name: "scheduled transfers",
+		Run: func(ctx context.Context, now time.Time) error {
+			for {
+				runs, err := storage.RunDueSchedules(ctx, now, transfersBatch)
+				succeeded := false
+				for _, run := range runs {
+					if run.Status == models.RunFailed {
+						logger.Log.Warn("scheduled transfer failed",
+							zap.Int("schedule", run.ScheduleID),
+							zap.String("error", run.Error),
+						)
+						continue
+					}
+					succeeded = true
+				}
+				// the cache is keyed by user and runs touch many of them
+				if succeeded {
+					lfu.ClearCache()
+				}
+				if err != nil || len(runs) < transfersBatch {
+					return err
+				}
+			}
+		},
+	}
+}
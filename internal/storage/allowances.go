@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var errNoAllowanceDue = errors.New("no allowance due")
+
+// ResumeAllowancePeriod returns the oldest period some user was skipped for,
+// or the latest period anything was issued for when every period is complete.
+// It is nil if allowances were never issued.
+func (db *DataBase) ResumeAllowancePeriod(ctx context.Context) (*time.Time, error) {
+	var period sql.NullTime
+	err := db.Tm.DB.QueryRowContext(ctx, `
+		WITH bounds AS (
+		    SELECT MIN(period) AS first, MAX(period) AS last
+		      FROM allowance_issuances
+		)
+		SELECT COALESCE(
+		           (SELECT MIN(p.period)
+		              FROM bounds b,
+		                   generate_series(b.first, b.last, INTERVAL '1 month') AS p(period)
+		             WHERE EXISTS (SELECT 1
+		                             FROM users u
+		                            WHERE u.role <> 'system'
+		                              AND u.created_at < p.period + INTERVAL '1 month'
+		                              AND NOT EXISTS (SELECT 1
+		                                                FROM allowance_issuances a
+		                                               WHERE a.period = p.period
+		                                                 AND a.user_id = u.id))),
+		           (SELECT last FROM bounds))
+	`).Scan(&period)
+	if err != nil {
+		return nil, err
+	}
+	if !period.Valid {
+		return nil, nil
+	}
+	p := period.Time.UTC()
+	return &p, nil
+}
+
+// IssueAllowances credits amount coins for the period to up to limit users
+// registered before the period ended that did not get it yet. Every user is
+// credited in its own transaction, the issuance row makes it idempotent.
+// The coins lapse at expiresAt unless it is nil.
+func (db *DataBase) IssueAllowances(ctx context.Context, period time.Time, amount int, expiresAt *time.Time, limit int) (int, error) {
+	if amount <= 0 {
+		return 0, ErrInvalidAmount
+	}
+	issued := 0
+	for issued < limit {
+		err := db.Tm.WriteTX(ctx, func(tx *sql.Tx) error {
+			return db.issueAllowanceTx(ctx, tx, period, amount, expiresAt)
+		})
+		if errors.Is(err, errNoAllowanceDue) {
+			break
+		}
+		if err != nil {
+			return issued, err
+		}
+		issued++
+	}
+	return issued, nil
+}
+
+func (db *DataBase) issueAllowanceTx(ctx context.Context, tx *sql.Tx, period time.Time, amount int, expiresAt *time.Time) error {
+	var uuid string
+	err := tx.QueryRowContext(ctx, `
+		SELECT u.id
+		  FROM users u
+		 WHERE u.role <> 'system'
+		   AND u.created_at < $2
+		   AND NOT EXISTS (SELECT 1
+		                     FROM allowance_issuances a
+		                    WHERE a.period = $1
+		                      AND a.user_id = u.id)
+		 ORDER BY u.id
+		 LIMIT 1
+		   FOR UPDATE OF u SKIP LOCKED
+	`, period, period.AddDate(0, 1, 0)).Scan(&uuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errNoAllowanceDue
+		}
+		return err
+	}
+
+	transactionID, err := db.createTransaction(ctx, tx, IssuerUUID, uuid, amount, allowanceNote(period))
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO allowance_issuances (period, user_id, amount, transaction_id)
+		VALUES ($1, $2, $3, $4)
+	`, period, uuid, amount, transactionID)
+	if err != nil {
+		return err
+	}
+	_, err = db.postEntry(ctx, tx, EntryIssuance, allowanceReference(period),
+		posting{account: AccountIssuance, amount: -amount},
+		posting{account: WalletAccount(uuid), amount: amount, expiresAt: expiresAt},
+	)
+	return err
+}
+
+func allowanceReference(period time.Time) string {
+	return "allowance:" + period.Format("2006-01")
+}
+
+func allowanceNote(period time.Time) models.TransferNote {
+	return models.TransferNote{Message: "allowance for " + period.Format("2006-01")}
+}
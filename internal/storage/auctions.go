@@ -0,0 +1,433 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errNoAuctionDue = errors.New("no auction due")
+
+// the top bid is the highest one, the earlier of equal bids wins
+const auctionColumns = `
+		SELECT a.id, m.name, a.starting_bid, a.ends_at, a.status, COALESCE(a.order_id, 0), a.created_at, a.closed_at,
+		       COALESCE(b.id, 0), COALESCE(bidder.username, ''), COALESCE(b.amount, 0), COALESCE(b.status, ''), b.created_at
+		  FROM auctions a
+		  JOIN merchandise m ON a.merchandise_id = m.id
+		  LEFT JOIN LATERAL (SELECT id, bidder_id, amount, status, created_at
+		                       FROM auction_bids
+		                      WHERE auction_id = a.id
+		                      ORDER BY amount DESC, id
+		                      LIMIT 1) b ON TRUE
+		  LEFT JOIN users bidder ON b.bidder_id = bidder.id`
+
+// heldBid is the current top bid of a locked auction
+type heldBid struct {
+	id         int
+	bidderUuid string
+	amount     int
+}
+
+// CreateAuction takes one item out of stock and sells it to the highest bid
+// placed before endsAt.
+func (db *DataBase) CreateAuction(ctx context.Context, adminUuid string, item string, startingBid int, endsAt time.Time) (*models.Auction, error) {
+	if startingBid <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if !endsAt.After(time.Now()) {
+		return nil, ErrAuctionEndInPast
+	}
+	var auction *models.Auction
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		var merchID int
+		err := tx.QueryRowContext(ctx, `
+		SELECT id
+		  FROM merchandise
+		 WHERE name = $1
+		   AND retired_at IS NULL
+	`, item).Scan(&merchID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrItemNotFound
+			}
+			return err
+		}
+		err = db.takeStockTx(ctx, tx, merchID, 1)
+		if err != nil {
+			return err
+		}
+
+		var auctionID int
+		err = tx.QueryRowContext(ctx, `
+		INSERT INTO auctions (merchandise_id, starting_bid, ends_at, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, merchID, startingBid, endsAt.UTC(), adminUuid).Scan(&auctionID)
+		if err != nil {
+			return err
+		}
+		auction, err = db.getAuctionTx(ctx, tx, auctionID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return auction, nil
+}
+
+// GetAuctions returns the open auctions, the ones ending first first.
+func (db *DataBase) GetAuctions(ctx context.Context) ([]models.Auction, error) {
+	rows, err := db.Tm.DB.QueryContext(ctx, auctionColumns+`
+		 WHERE a.status = $1
+		 ORDER BY a.ends_at, a.id
+	`, models.AuctionStatusOpen)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Auction
+	for rows.Next() {
+		a, scanErr := scanAuction(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		result = append(result, *a)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return result, nil
+}
+
+// GetAuction returns the auction with all its bids.
+func (db *DataBase) GetAuction(ctx context.Context, auctionID int) (*models.Auction, error) {
+	var auction *models.Auction
+	err := db.Tm.ReadTX(ctx, func(tx *sql.Tx) error {
+		var err error
+		auction, err = db.getAuctionTx(ctx, tx, auctionID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return auction, nil
+}
+
+// PlaceBid reserves amount coins of the bidder in escrow and releases the
+// bid it outbids. A bid must be higher than the current one, so of equal
+// bids the first stays on top. A bid counts only if its transaction started
+// before the end, bids after it fail even if the auction is not closed yet.
+func (db *DataBase) PlaceBid(ctx context.Context, uuid string, auctionID int, amount int) (*models.Auction, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	var auction *models.Auction
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		var startingBid int
+		var status string
+		var ended bool
+		err := tx.QueryRowContext(ctx, `
+		SELECT starting_bid, status, ends_at <= CURRENT_TIMESTAMP
+		  FROM auctions
+		 WHERE id = $1
+		   FOR UPDATE
+	`, auctionID).Scan(&startingBid, &status, &ended)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrAuctionNotFound
+			}
+			return err
+		}
+		if status != models.AuctionStatusOpen || ended {
+			return ErrAuctionClosed
+		}
+		top, err := db.getHeldBidTx(ctx, tx, auctionID)
+		if err != nil {
+			return err
+		}
+		if amount < startingBid || (top != nil && amount <= top.amount) {
+			return ErrBidTooLow
+		}
+
+		// raising your own bid only needs the difference
+		balance, err := db.getBalanceTx(ctx, tx, uuid)
+		if err != nil {
+			return err
+		}
+		if top != nil && top.bidderUuid == uuid {
+			balance += top.amount
+		}
+		if balance < amount {
+			return ErrNotEnoughBalance
+		}
+
+		if top != nil {
+			err = db.releaseBidTx(ctx, tx, *top)
+			if err != nil {
+				return err
+			}
+		}
+		var bidID int
+		err = tx.QueryRowContext(ctx, `
+		INSERT INTO auction_bids (auction_id, bidder_id, amount)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, auctionID, uuid, amount).Scan(&bidID)
+		if err != nil {
+			return err
+		}
+		_, err = db.postEntry(ctx, tx, EntryHold, bidReference(bidID),
+			posting{account: WalletAccount(uuid), amount: -amount},
+			posting{account: AccountEscrow, amount: amount},
+		)
+		if err != nil {
+			return err
+		}
+		auction, err = db.getAuctionTx(ctx, tx, auctionID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return auction, nil
+}
+
+// CloseAuctions closes up to limit auctions that ended by now, each one in
+// its own transaction. The top bid pays for the item from escrow and the
+// winner gets it as an order, an auction without bids puts the item back in
+// stock. An auction that fails to close is skipped and stays open for the
+// next run, the failures are returned together with the closed auctions.
+// Rows are taken with SKIP LOCKED, so several instances can run it at once.
+func (db *DataBase) CloseAuctions(ctx context.Context, now time.Time, limit int) ([]models.Auction, error) {
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT id
+		  FROM auctions
+		 WHERE status = $1
+		   AND ends_at <= $2
+		 ORDER BY ends_at, id
+		 LIMIT $3
+	`, models.AuctionStatusOpen, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	var dueIDs []int
+	for rows.Next() {
+		var id int
+		if scanErr := rows.Scan(&id); scanErr != nil {
+			rows.Close()
+			return nil, scanErr
+		}
+		dueIDs = append(dueIDs, id)
+	}
+	rows.Close()
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+
+	var closed []models.Auction
+	var failures []error
+	for _, id := range dueIDs {
+		var auction *models.Auction
+		err = db.Tm.WriteTX(ctx, func(tx *sql.Tx) error {
+			var merchID int
+			err := tx.QueryRowContext(ctx, `
+			SELECT merchandise_id
+			  FROM auctions
+			 WHERE id = $1
+			   AND status = $2
+			   FOR UPDATE SKIP LOCKED
+		`, id, models.AuctionStatusOpen).Scan(&merchID)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return errNoAuctionDue
+				}
+				return err
+			}
+			err = db.closeAuctionTx(ctx, tx, id, merchID)
+			if err != nil {
+				return err
+			}
+			auction, err = db.getAuctionTx(ctx, tx, id)
+			return err
+		})
+		// closed or being closed by another instance
+		if errors.Is(err, errNoAuctionDue) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return closed, err
+			}
+			failures = append(failures, fmt.Errorf("auction %d: %w", id, err))
+			continue
+		}
+		closed = append(closed, *auction)
+	}
+	return closed, errors.Join(failures...)
+}
+
+func (db *DataBase) closeAuctionTx(ctx context.Context, tx *sql.Tx, auctionID int, merchID int) error {
+	top, err := db.getHeldBidTx(ctx, tx, auctionID)
+	if err != nil {
+		return err
+	}
+	if top == nil {
+		_, err = tx.ExecContext(ctx, `
+		UPDATE merchandise
+		   SET stock = stock + 1
+		 WHERE id = $1
+		   AND stock IS NOT NULL
+	`, merchID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+		UPDATE auctions
+		   SET status = $1,
+		       closed_at = CURRENT_TIMESTAMP
+		 WHERE id = $2
+	`, models.AuctionStatusUnsold, auctionID)
+		return err
+	}
+
+	var orderID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO orders (user_id, total)
+		VALUES ($1, $2)
+		RETURNING id
+	`, top.bidderUuid, top.amount).Scan(&orderID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO order_items (order_id, merchandise_id, quantity, unit_price)
+		VALUES ($1, $2, 1, $3)
+	`, orderID, merchID, top.amount)
+	if err != nil {
+		return err
+	}
+	err = db.addInventoryTx(ctx, tx, top.bidderUuid, merchID, 1)
+	if err != nil {
+		return err
+	}
+	_, err = db.postEntry(ctx, tx, EntryPurchase, orderReference(orderID),
+		posting{account: AccountEscrow, amount: -top.amount},
+		posting{account: AccountStore, amount: top.amount},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE auction_bids
+		   SET status = $1
+		 WHERE id = $2
+	`, models.BidStatusWon, top.id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE auctions
+		   SET status = $1,
+		       order_id = $2,
+		       closed_at = CURRENT_TIMESTAMP
+		 WHERE id = $3
+	`, models.AuctionStatusSold, orderID, auctionID)
+	return err
+}
+
+// getHeldBidTx returns the top bid of a locked auction, nil if there are no bids
+func (db *DataBase) getHeldBidTx(ctx context.Context, tx *sql.Tx, auctionID int) (*heldBid, error) {
+	var b heldBid
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, bidder_id, amount
+		  FROM auction_bids
+		 WHERE auction_id = $1
+		   AND status = $2
+	`, auctionID, models.BidStatusHeld).Scan(&b.id, &b.bidderUuid, &b.amount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (db *DataBase) releaseBidTx(ctx context.Context, tx *sql.Tx, bid heldBid) error {
+	_, err := db.postEntry(ctx, tx, EntryRelease, bidReference(bid.id),
+		posting{account: AccountEscrow, amount: -bid.amount},
+		posting{account: WalletAccount(bid.bidderUuid), amount: bid.amount, returns: &entryRef{kind: EntryHold, reference: bidReference(bid.id)}},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE auction_bids
+		   SET status = $1
+		 WHERE id = $2
+	`, models.BidStatusReleased, bid.id)
+	return err
+}
+
+func (db *DataBase) getAuctionTx(ctx context.Context, tx *sql.Tx, auctionID int) (*models.Auction, error) {
+	row := tx.QueryRowContext(ctx, auctionColumns+`
+		 WHERE a.id = $1
+	`, auctionID)
+	a, err := scanAuction(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAuctionNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT b.id, u.username, b.amount, b.status, b.created_at
+		  FROM auction_bids b
+		  JOIN users u ON b.bidder_id = u.id
+		 WHERE b.auction_id = $1
+		 ORDER BY b.amount DESC, b.id
+	`, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var b models.Bid
+		if scanErr := rows.Scan(&b.ID, &b.User, &b.Amount, &b.Status, &b.CreatedAt); scanErr != nil {
+			return nil, scanErr
+		}
+		a.Bids = append(a.Bids, b)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return a, nil
+}
+
+func scanAuction(row rowScanner) (*models.Auction, error) {
+	var a models.Auction
+	var bid models.Bid
+	var bidCreatedAt *time.Time
+	err := row.Scan(&a.ID, &a.Item, &a.StartingBid, &a.EndsAt, &a.Status, &a.OrderID, &a.CreatedAt, &a.ClosedAt,
+		&bid.ID, &bid.User, &bid.Amount, &bid.Status, &bidCreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if bid.ID != 0 {
+		bid.CreatedAt = *bidCreatedAt
+		a.TopBid = &bid
+		if a.Status == models.AuctionStatusSold {
+			a.Winner = bid.User
+		}
+	}
+	return &a, nil
+}
+
+func bidReference(bidID int) string {
+	return fmt.Sprintf("bid:%d", bidID)
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// RecipientsError is returned by SendBulk when some recipients can't be
+// paid, nothing is sent then.
+type RecipientsError struct {
+	Recipients []models.RecipientError
+}
+
+func (e *RecipientsError) Error() string {
+	return ErrInvalidRecipients.Error()
+}
+
+func (e *RecipientsError) Unwrap() error {
+	return ErrInvalidRecipients
+}
+
+// SendBulk sends every transfer in one serializable transaction. Invalid
+// recipients are all reported at once in a RecipientsError, a short balance
+// or a sending limit fails the whole batch.
+func (db *DataBase) SendBulk(ctx context.Context, uuid string, transfers []models.BulkTransfer, note models.TransferNote) ([]models.BulkTransfer, error) {
+	var result []models.BulkTransfer
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		_, err := validateRecipients(uuid, transfers, func(username string) (string, error) {
+			return db.getUuidByUsernameTx(ctx, tx, username)
+		})
+		if err != nil {
+			return err
+		}
+
+		balance, err := db.getBalanceTx(ctx, tx, uuid)
+		if err != nil {
+			return err
+		}
+		if balance < bulkTotal(transfers) {
+			return ErrNotEnoughBalance
+		}
+
+		result = make([]models.BulkTransfer, 0, len(transfers))
+		for _, t := range transfers {
+			t.TransactionID, err = db.sendTx(ctx, tx, uuid, t.ToUser, t.Amount, note)
+			if err != nil {
+				return err
+			}
+			result = append(result, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// validateRecipients checks every transfer on its own so all problems are
+// reported together and returns the recipients' uuids, resolve maps a
+// username to the user's uuid
+func validateRecipients(uuid string, transfers []models.BulkTransfer, resolve func(string) (string, error)) ([]string, error) {
+	var problems []models.RecipientError
+	receivers := make([]string, len(transfers))
+	seen := make(map[string]bool)
+	for i, t := range transfers {
+		var err error
+		receiverUuid, resolveErr := resolve(t.ToUser)
+		if resolveErr != nil && !errors.Is(resolveErr, ErrUserNotFound) {
+			return nil, resolveErr
+		}
+		receivers[i] = receiverUuid
+		switch {
+		case t.Amount <= 0:
+			err = ErrInvalidAmount
+		case resolveErr != nil:
+			err = resolveErr
+		case receiverUuid == uuid:
+			err = ErrSendingToYourself
+		case seen[receiverUuid]:
+			err = ErrDuplicateRecipient
+		}
+		if resolveErr == nil {
+			seen[receiverUuid] = true
+		}
+		if err != nil {
+			problems = append(problems, models.RecipientError{Index: i, ToUser: t.ToUser, Error: err.Error()})
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &RecipientsError{Recipients: problems}
+	}
+	return receivers, nil
+}
+
+func bulkTotal(transfers []models.BulkTransfer) int {
+	total := 0
+	for _, t := range transfers {
+		total += t.Amount
+	}
+	return total
+}
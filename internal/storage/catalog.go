@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+func (db *DataBase) GetCatalog(ctx context.Context) ([]models.Merch, error) {
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT name, price, description, available AND COALESCE(stock > 0, TRUE), stock
+		  FROM merchandise
+		 WHERE NOT hidden
+		   AND retired_at IS NULL
+		 ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var catalog []models.Merch
+	for rows.Next() {
+		var merch models.Merch
+		if scanErr := rows.Scan(&merch.Name, &merch.Price, &merch.Description, &merch.Available, &merch.Stock); scanErr != nil {
+			return nil, scanErr
+		}
+		catalog = append(catalog, merch)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return catalog, nil
+}
+
+func (db *DataBase) GetMerch(ctx context.Context, item string) (*models.Merch, error) {
+	merch := &models.Merch{}
+	err := db.Tm.DB.QueryRowContext(ctx, `
+		SELECT name, price, description, available AND COALESCE(stock > 0, TRUE), stock
+		  FROM merchandise
+		 WHERE name = $1
+		   AND NOT hidden
+		   AND retired_at IS NULL
+	`, item).Scan(&merch.Name, &merch.Price, &merch.Description, &merch.Available, &merch.Stock)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrItemNotFound
+		}
+		return nil, err
+	}
+	return merch, nil
+}
+
+// takeStockTx decrements stock of a limited item, items without stock are unlimited.
+func (db *DataBase) takeStockTx(ctx context.Context, tx *sql.Tx, merchID int, quantity int) error {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE merchandise
+		   SET stock = stock - $1
+		 WHERE id = $2
+		   AND (stock IS NULL OR stock >= $1)
+	`, quantity, merchID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrOutOfStock
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	uniqueViolation      = "23505"
+	serializationFailure = "40001"
+)
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == serializationFailure
+}
+
+func (db *DataBase) CreateMerch(ctx context.Context, adminUuid string, merch models.Merch) error {
+	return db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		var merchID int
+		err := tx.QueryRowContext(ctx, `
+		INSERT INTO merchandise (name, price, description, available, stock)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, merch.Name, merch.Price, merch.Description, merch.Available, merch.Stock).Scan(&merchID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrItemAlreadyExists
+			}
+			return err
+		}
+		return db.recordMerchChange(ctx, tx, merchID, models.MerchActionCreate, adminUuid)
+	})
+}
+
+func (db *DataBase) UpdateMerch(ctx context.Context, adminUuid string, item string, update models.MerchUpdate) (*models.Merch, error) {
+	var merch *models.Merch
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		merchID, err := db.getActiveMerchIDTx(ctx, tx, item)
+		if err != nil {
+			return err
+		}
+		// purchases keep the price paid in their ledger postings, so repricing
+		// only affects future purchases
+		_, err = tx.ExecContext(ctx, `
+		UPDATE merchandise
+		   SET name = COALESCE($1, name),
+		       price = COALESCE($2, price),
+		       description = COALESCE($3, description),
+		       available = COALESCE($4, available)
+		 WHERE id = $5
+	`, update.Name, update.Price, update.Description, update.Available, merchID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrItemAlreadyExists
+			}
+			return err
+		}
+		err = db.recordMerchChange(ctx, tx, merchID, models.MerchActionUpdate, adminUuid)
+		if err != nil {
+			return err
+		}
+		merch, err = db.getMerchByIDTx(ctx, tx, merchID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merch, nil
+}
+
+func (db *DataBase) SetMerchHidden(ctx context.Context, adminUuid string, item string, hidden bool) error {
+	return db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		merchID, err := db.getActiveMerchIDTx(ctx, tx, item)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+		UPDATE merchandise
+		   SET hidden = $1
+		 WHERE id = $2
+	`, hidden, merchID)
+		if err != nil {
+			return err
+		}
+		action := models.MerchActionUnhide
+		if hidden {
+			action = models.MerchActionHide
+		}
+		return db.recordMerchChange(ctx, tx, merchID, action, adminUuid)
+	})
+}
+
+// RetireMerch removes an item from sale for good. The row is kept because
+// inventories and history still reference it.
+func (db *DataBase) RetireMerch(ctx context.Context, adminUuid string, item string) error {
+	return db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		merchID, err := db.getActiveMerchIDTx(ctx, tx, item)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+		UPDATE merchandise
+		   SET retired_at = CURRENT_TIMESTAMP,
+		       available = FALSE
+		 WHERE id = $1
+	`, merchID)
+		if err != nil {
+			return err
+		}
+		return db.recordMerchChange(ctx, tx, merchID, models.MerchActionRetire, adminUuid)
+	})
+}
+
+// RestockMerch adds quantity to the item stock. An unlimited item becomes
+// limited to the given quantity.
+func (db *DataBase) RestockMerch(ctx context.Context, adminUuid string, item string, quantity int) (*models.Merch, error) {
+	var merch *models.Merch
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		merchID, err := db.getActiveMerchIDTx(ctx, tx, item)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+		UPDATE merchandise
+		   SET stock = COALESCE(stock, 0) + $1
+		 WHERE id = $2
+	`, quantity, merchID)
+		if err != nil {
+			return err
+		}
+		err = db.recordMerchChange(ctx, tx, merchID, models.MerchActionRestock, adminUuid)
+		if err != nil {
+			return err
+		}
+		merch, err = db.getMerchByIDTx(ctx, tx, merchID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merch, nil
+}
+
+func (db *DataBase) GetMerchHistory(ctx context.Context, item string) ([]models.MerchChange, error) {
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT h.action, h.name, h.price, h.description, h.available, h.hidden, h.stock,
+		       COALESCE(u.username, ''), h.changed_at
+		  FROM merchandise_history h
+		  JOIN merchandise m ON h.merchandise_id = m.id
+		  LEFT JOIN users u  ON h.changed_by = u.id
+		 WHERE m.name = $1
+		 ORDER BY h.id
+	`, item)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.MerchChange
+	for rows.Next() {
+		var c models.MerchChange
+		scanErr := rows.Scan(&c.Action, &c.Name, &c.Price, &c.Description, &c.Available, &c.Hidden, &c.Stock, &c.ChangedBy, &c.ChangedAt)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		history = append(history, c)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	if len(history) == 0 {
+		return nil, ErrItemNotFound
+	}
+	return history, nil
+}
+
+func (db *DataBase) getActiveMerchIDTx(ctx context.Context, tx *sql.Tx, item string) (int, error) {
+	var merchID int
+	err := tx.QueryRowContext(ctx, `
+		SELECT id
+		  FROM merchandise
+		 WHERE name = $1
+		   AND retired_at IS NULL
+	`, item).Scan(&merchID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrItemNotFound
+		}
+		return 0, err
+	}
+	return merchID, nil
+}
+
+func (db *DataBase) getMerchByIDTx(ctx context.Context, tx *sql.Tx, merchID int) (*models.Merch, error) {
+	merch := &models.Merch{}
+	err := tx.QueryRowContext(ctx, `
+		SELECT name, price, description, available AND COALESCE(stock > 0, TRUE), hidden, stock
+		  FROM merchandise
+		 WHERE id = $1
+	`, merchID).Scan(&merch.Name, &merch.Price, &merch.Description, &merch.Available, &merch.Hidden, &merch.Stock)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrItemNotFound
+		}
+		return nil, err
+	}
+	return merch, nil
+}
+
+func (db *DataBase) recordMerchChange(ctx context.Context, tx *sql.Tx, merchID int, action string, adminUuid string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO merchandise_history (merchandise_id, action, name, price, description, available, hidden, stock, changed_by)
+		SELECT id, $1, name, price, description, available, hidden, stock, $2
+		  FROM merchandise
+		 WHERE id = $3
+	`, action, adminUuid, merchID)
+	return err
+}
@@ -6,3 +6,40 @@ var ErrUserNotFound = errors.New("user not found")
 var ErrItemNotFound = errors.New("item not found")
 var ErrNotEnoughBalance = errors.New("not enough tokens")
 var ErrSendingToYourself = errors.New("can't send tokens to yourself")
+var ErrAccountNotFound = errors.New("ledger account not found")
+var ErrUnbalancedEntry = errors.New("ledger entry is not balanced")
+var ErrInvalidAmount = errors.New("amount must be positive")
+var ErrUserAlreadyExists = errors.New("user already exists")
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+var ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is still in progress")
+var ErrItemAlreadyExists = errors.New("item already exists")
+var ErrItemNotAvailable = errors.New("item is not available")
+var ErrOutOfStock = errors.New("item is out of stock")
+var ErrEmptyOrder = errors.New("order has no items")
+var ErrInvalidQuantity = errors.New("quantity is out of range")
+var ErrOrderNotFound = errors.New("order not found")
+var ErrRefundWindowExpired = errors.New("refund window has expired")
+var ErrRefundExceedsPurchase = errors.New("refund quantity exceeds what is left of the purchase")
+var ErrNotInInventory = errors.New("item is no longer in the inventory")
+var ErrTransactionNotFound = errors.New("transaction not found")
+var ErrAlreadyReversed = errors.New("transaction is already reversed")
+var ErrReversalOfReversal = errors.New("a reversal can't be reversed")
+var ErrSystemTransaction = errors.New("allowances and expiries can't be reversed")
+var ErrScheduleNotFound = errors.New("scheduled transfer not found")
+var ErrPendingTransferNotFound = errors.New("pending transfer not found")
+var ErrPendingTransferClosed = errors.New("transfer is no longer pending")
+var ErrSendLimitExceeded = errors.New("sending limit exceeded")
+var ErrSendLimitNotFound = errors.New("sending limit not found")
+var ErrDuplicateRecipient = errors.New("recipient is listed more than once")
+var ErrInvalidRecipients = errors.New("some recipients are invalid")
+var ErrPaymentRequestNotFound = errors.New("payment request not found")
+var ErrGiftToYourself = errors.New("can't gift merch to yourself")
+var ErrPaymentRequestAnswered = errors.New("payment request is already paid or rejected")
+var ErrListingNotFound = errors.New("listing not found")
+var ErrListingClosed = errors.New("listing is already sold or cancelled")
+var ErrBuyingOwnListing = errors.New("can't buy your own listing")
+var ErrAuctionNotFound = errors.New("auction not found")
+var ErrAuctionClosed = errors.New("auction is closed")
+var ErrAuctionEndInPast = errors.New("auction must end in the future")
+var ErrAuctionWinNotRefundable = errors.New("auction wins can't be refunded")
+var ErrBidTooLow = errors.New("bid must be at least the starting bid and higher than the current bid")
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"strconv"
+	"strings"
+)
+
+// ExportActivity streams the transfers, purchases and refunds of the user,
+// or of everyone when uuid is empty, oldest first. Rows are passed to fn as
+// they are read, an error from fn stops the export and is returned.
+func (db *DataBase) ExportActivity(ctx context.Context, uuid string, filter models.ExportFilter, fn func(models.ActivityRecord) error) error {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var from, to string
+	if filter.From != nil {
+		from = arg(filter.From.UTC())
+	}
+	if filter.To != nil {
+		to = arg(filter.To.UTC())
+	}
+	var user string
+	if uuid != "" {
+		user = arg(uuid)
+	}
+	// every part of the union filters on its own columns with the same arguments
+	where := func(createdAt string, owners ...string) string {
+		conditions := []string{"TRUE"}
+		if from != "" {
+			conditions = append(conditions, createdAt+" >= "+from)
+		}
+		if to != "" {
+			conditions = append(conditions, createdAt+" < "+to)
+		}
+		if user != "" {
+			var matches []string
+			for _, owner := range owners {
+				matches = append(matches, owner+" = "+user)
+			}
+			conditions = append(conditions, "("+strings.Join(matches, " OR ")+")")
+		}
+		return strings.Join(conditions, "\n\t\t   AND ")
+	}
+
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT t.created_at,
+		       'transfer',
+		       t.id,
+		       sender.username,
+		       receiver.username,
+		       '',
+		       0,
+		       t.amount,
+		       COALESCE(t.message, ''),
+		       COALESCE(t.category, '')
+		  FROM transactions t
+		  JOIN users sender   ON t.sender_id = sender.id
+		  JOIN users receiver ON t.receiver_id = receiver.id
+		 WHERE `+where("t.created_at", "t.sender_id", "t.receiver_id")+`
+		 UNION ALL
+		SELECT o.created_at,
+		       'purchase',
+		       o.id,
+		       u.username,
+		       COALESCE(r.username, ''),
+		       m.name,
+		       i.quantity,
+		       i.quantity * i.unit_price,
+		       COALESCE(o.message, ''),
+		       ''
+		  FROM orders o
+		  JOIN order_items i ON i.order_id = o.id
+		  JOIN merchandise m ON i.merchandise_id = m.id
+		  JOIN users u ON o.user_id = u.id
+		  LEFT JOIN users r ON o.recipient_id = r.id
+		 WHERE `+where("o.created_at", "o.user_id", "o.recipient_id")+`
+		 UNION ALL
+		SELECT r.created_at,
+		       'refund',
+		       r.id,
+		       '',
+		       u.username,
+		       m.name,
+		       r.quantity,
+		       r.amount,
+		       '',
+		       ''
+		  FROM refunds r
+		  JOIN order_items i ON r.order_item_id = i.id
+		  JOIN orders o ON i.order_id = o.id
+		  JOIN merchandise m ON i.merchandise_id = m.id
+		  JOIN users u ON o.user_id = u.id
+		 WHERE `+where("r.created_at", "o.user_id")+`
+		 ORDER BY 1, 2, 3
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rec models.ActivityRecord
+		err = rows.Scan(&rec.Time, &rec.Type, &rec.ID, &rec.From, &rec.To, &rec.Item, &rec.Quantity, &rec.Amount, &rec.Message, &rec.Category)
+		if err != nil {
+			return err
+		}
+		if err = fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+)
+
+// SendGift buys the lines for toUser: the buyer pays and the items go to the
+// recipient's inventory in the same transaction.
+func (db *DataBase) SendGift(ctx context.Context, uuid string, toUser string, lines []models.OrderLine, message string) (*models.Order, error) {
+	lines, err := mergeOrderLines(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	var order *models.Order
+	err = db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		recipientUuid, err := db.getUuidByUsernameTx(ctx, tx, toUser)
+		if err != nil {
+			return err
+		}
+		if recipientUuid == uuid {
+			return ErrGiftToYourself
+		}
+		order, err = db.placeOrderForTx(ctx, tx, uuid, recipientUuid, lines, message)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	order.ToUser = toUser
+	return order, nil
+}
+
+// GiftHistoryLimit caps the sent and the received gifts shown in the info,
+// older ones are in the purchases and the export.
+const GiftHistoryLimit = 20
+
+// getGiftHistory returns the latest gifts the user sent and received, newest first
+func (db *DataBase) getGiftHistory(ctx context.Context, uuid string) (models.GiftHistory, error) {
+	var history models.GiftHistory
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT o.id,
+		       sender.username,
+		       recipient.username,
+		       m.name,
+		       oi.quantity,
+		       COALESCE(o.message, ''),
+		       o.created_at,
+		       o.recipient_id = $1
+		  FROM orders o
+		  JOIN users sender    ON o.user_id = sender.id
+		  JOIN users recipient ON o.recipient_id = recipient.id
+		  JOIN order_items oi  ON oi.order_id = o.id
+		  JOIN merchandise m   ON oi.merchandise_id = m.id
+		 WHERE o.id IN (
+		        SELECT id
+		          FROM orders
+		         WHERE user_id = $1
+		           AND recipient_id IS NOT NULL
+		         ORDER BY id DESC
+		         LIMIT $2
+		       )
+		    OR o.id IN (
+		        SELECT id
+		          FROM orders
+		         WHERE recipient_id = $1
+		         ORDER BY id DESC
+		         LIMIT $2
+		       )
+		 ORDER BY o.id DESC, oi.id
+	`, uuid, GiftHistoryLimit)
+	if err != nil {
+		return history, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var g models.Gift
+		var item models.Item
+		var received bool
+		scanErr := rows.Scan(&g.OrderID, &g.FromUser, &g.ToUser, &item.Type, &item.Quantity, &g.Message, &g.CreatedAt, &received)
+		if scanErr != nil {
+			return history, scanErr
+		}
+		gifts := &history.Sent
+		if received {
+			gifts = &history.Received
+		}
+		if n := len(*gifts); n > 0 && (*gifts)[n-1].OrderID == g.OrderID {
+			(*gifts)[n-1].Items = append((*gifts)[n-1].Items, item)
+			continue
+		}
+		g.Items = []models.Item{item}
+		*gifts = append(*gifts, g)
+	}
+	return history, rows.Err()
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+)
+
+// IdempotencyKeyTimeout is how long a reserved key may stay without a
+// response. After that the request is assumed to have died and the key can be
+// reserved again.
+const IdempotencyKeyTimeout = 5 * time.Minute
+
+// completedResponse is what Send and Buy answer on success. They store it
+// with the operation, so a completed request is never executed twice even if
+// the response is lost before it is saved.
+var completedResponse = models.IdempotentResponse{Status: http.StatusOK, ContentType: "application/json"}
+
+// execer is either the database or a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type idempotencyContextKey struct{}
+
+type idempotencyClaim struct {
+	uuid string
+	key  string
+}
+
+// WithIdempotencyKey tells the storage that the request in ctx runs under the
+// reserved key.
+func WithIdempotencyKey(ctx context.Context, uuid, key string) context.Context {
+	return context.WithValue(ctx, idempotencyContextKey{}, idempotencyClaim{uuid: uuid, key: key})
+}
+
+func idempotencyClaimFrom(ctx context.Context) (idempotencyClaim, bool) {
+	claim, ok := ctx.Value(idempotencyContextKey{}).(idempotencyClaim)
+	return claim, ok
+}
+
+// ReserveIdempotencyKey claims the key for the request. It returns the stored
+// response if the same request was already completed, and nil if the caller
+// owns the key now and has to execute the request.
+func (db *DataBase) ReserveIdempotencyKey(ctx context.Context, uuid, key, requestHash string) (*models.IdempotentResponse, error) {
+	// a stale reservation is taken over as if the key were new
+	res, err := db.Tm.DB.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, request_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, key) DO UPDATE
+		   SET request_hash = EXCLUDED.request_hash,
+		       created_at = CURRENT_TIMESTAMP
+		 WHERE idempotency_keys.response_status IS NULL
+		   AND idempotency_keys.created_at < CURRENT_TIMESTAMP - $4 * INTERVAL '1 second'
+	`, uuid, key, requestHash, int(IdempotencyKeyTimeout.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 1 {
+		return nil, nil
+	}
+
+	var storedHash string
+	var status sql.NullInt64
+	var contentType sql.NullString
+	var body []byte
+	err = db.Tm.DB.QueryRowContext(ctx, `
+		SELECT request_hash, response_status, response_content_type, response_body
+		  FROM idempotency_keys
+		 WHERE user_id = $1
+		   AND key = $2
+	`, uuid, key).Scan(&storedHash, &status, &contentType, &body)
+	if err != nil {
+		return nil, err
+	}
+	if storedHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !status.Valid {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	return &models.IdempotentResponse{
+		Status:      int(status.Int64),
+		ContentType: contentType.String,
+		Body:        body,
+	}, nil
+}
+
+func (db *DataBase) SaveIdempotentResponse(ctx context.Context, uuid, key string, response models.IdempotentResponse) error {
+	return saveIdempotentResponse(ctx, db.Tm.DB, uuid, key, response)
+}
+
+// completeIdempotencyKeyTx stores the success response in the transaction of
+// the operation, if the request runs under an idempotency key.
+func (db *DataBase) completeIdempotencyKeyTx(ctx context.Context, tx *sql.Tx) error {
+	claim, ok := idempotencyClaimFrom(ctx)
+	if !ok {
+		return nil
+	}
+	return saveIdempotentResponse(ctx, tx, claim.uuid, claim.key, completedResponse)
+}
+
+func saveIdempotentResponse(ctx context.Context, q execer, uuid, key string, response models.IdempotentResponse) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		   SET response_status = $1,
+		       response_content_type = $2,
+		       response_body = $3
+		 WHERE user_id = $4
+		   AND key = $5
+	`, response.Status, response.ContentType, response.Body, uuid, key)
+	return err
+}
+
+// ReleaseIdempotencyKey forgets a reserved key so the request can be retried.
+func (db *DataBase) ReleaseIdempotencyKey(ctx context.Context, uuid, key string) error {
+	_, err := db.Tm.DB.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		 WHERE user_id = $1
+		   AND key = $2
+		   AND response_status IS NULL
+	`, uuid, key)
+	return err
+}
@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// system ledger accounts, user wallets are "wallet:<uuid>"
+const (
+	AccountIssuance = "system:issuance"
+	AccountStore    = "system:store"
+	// coins held for pending transfers
+	AccountEscrow = "system:escrow"
+)
+
+// the system user allowances are sent from, it has no wallet
+const (
+	IssuerUUID     = "00000000-0000-0000-0000-000000000000"
+	IssuerUsername = "System"
+)
+
+// ledger entry kinds
+const (
+	EntryOpening  = "opening"
+	EntryIssuance = "issuance"
+	EntryTransfer = "transfer"
+	EntryPurchase = "purchase"
+	EntryRefund   = "refund"
+	EntryReversal = "reversal"
+	EntryHold     = "hold"
+	EntryRelease  = "release"
+	EntryExpiry   = "expiry"
+	// corrections made by reconciliation
+	EntryAdjustment = "adjustment"
+)
+
+const walletPrefix = "wallet:"
+
+type posting struct {
+	account string
+	amount  int
+	// a wallet credit becomes a lot that lapses at expiresAt, nil never lapses
+	expiresAt *time.Time
+	// a wallet debit takes coins from this lot instead of the oldest ones
+	lot int
+	// a wallet credit that gives back coins taken by this entry restores the
+	// lots they came from, so returned coins keep their expiry
+	returns *entryRef
+}
+
+// entryRef names a ledger entry by its kind and reference
+type entryRef struct {
+	kind      string
+	reference string
+}
+
+func WalletAccount(uuid string) string {
+	return walletPrefix + uuid
+}
+
+func isWalletAccount(account string) bool {
+	return strings.HasPrefix(account, walletPrefix)
+}
+
+// postEntry writes a balanced entry (postings must sum to zero) and applies
+// wallet postings to users.balance, which is never allowed to go negative,
+// and to the user's coin lots
+func (db *DataBase) postEntry(ctx context.Context, tx *sql.Tx, kind, reference string, postings ...posting) (int, error) {
+	sum := 0
+	for _, p := range postings {
+		if p.amount == 0 {
+			return 0, ErrUnbalancedEntry
+		}
+		sum += p.amount
+	}
+	if len(postings) < 2 || sum != 0 {
+		return 0, ErrUnbalancedEntry
+	}
+
+	var entryID int
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO ledger_entries (kind, reference)
+		VALUES ($1, NULLIF($2, ''))
+		RETURNING id
+	`, kind, reference).Scan(&entryID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, p := range postings {
+		var accountID int
+		var userID sql.NullString
+		err = tx.QueryRowContext(ctx, `
+			SELECT id, user_id
+			  FROM ledger_accounts
+			 WHERE code = $1
+		`, p.account).Scan(&accountID, &userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, ErrAccountNotFound
+			}
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO ledger_postings (entry_id, account_id, amount)
+			VALUES ($1, $2, $3)
+		`, entryID, accountID, p.amount)
+		if err != nil {
+			return 0, err
+		}
+
+		if userID.Valid {
+			err = db.applyToWallet(ctx, tx, entryID, userID.String, p)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	return entryID, nil
+}
+
+// applyToWallet updates the balance first, so the user row lock serializes
+// all changes of the user's lots
+func (db *DataBase) applyToWallet(ctx context.Context, tx *sql.Tx, entryID int, uuid string, p posting) error {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE users
+		   SET balance = balance + $1
+		 WHERE id = $2
+		   AND balance + $1 >= 0
+	`, p.amount, uuid)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotEnoughBalance
+	}
+
+	if p.amount > 0 && p.returns != nil {
+		return db.restoreLotsTx(ctx, tx, uuid, p.amount, *p.returns)
+	}
+	if p.amount > 0 {
+		return db.addLotTx(ctx, tx, uuid, p.amount, p.expiresAt)
+	}
+	if p.lot > 0 {
+		res, err = tx.ExecContext(ctx, `
+			UPDATE coin_lots
+			   SET remaining = remaining - $1
+			 WHERE id = $2
+			   AND user_id = $3
+			   AND remaining >= $1
+		`, -p.amount, p.lot, uuid)
+		if err != nil {
+			return err
+		}
+		affected, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrNotEnoughBalance
+		}
+		return nil
+	}
+	return db.takeLotsTx(ctx, tx, uuid, -p.amount, entryID)
+}
+
+func (db *DataBase) addLotTx(ctx context.Context, tx *sql.Tx, uuid string, amount int, expiresAt *time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO coin_lots (user_id, amount, remaining, expires_at)
+		VALUES ($1, $2, $2, $3)
+	`, uuid, amount, expiresAt)
+	return err
+}
+
+// takeLotsTx spends the oldest lots first, each one gives what is still
+// needed after the lots before it. What the entry took from each lot is
+// recorded, unless entryID is zero, so it can be restored.
+func (db *DataBase) takeLotsTx(ctx context.Context, tx *sql.Tx, uuid string, amount int, entryID int) error {
+	_, err := tx.ExecContext(ctx, `
+		WITH ordered AS (
+			SELECT id,
+			       remaining,
+			       SUM(remaining) OVER (ORDER BY created_at, id) - remaining AS before
+			  FROM coin_lots
+			 WHERE user_id = $1
+			   AND remaining > 0
+		), taken AS (
+			UPDATE coin_lots l
+			   SET remaining = l.remaining - LEAST(o.remaining, $2 - o.before)
+			  FROM ordered o
+			 WHERE l.id = o.id
+			   AND o.before < $2
+			RETURNING l.id, LEAST(o.remaining, $2 - o.before) AS amount
+		)
+		INSERT INTO coin_lot_uses (entry_id, lot_id, amount)
+		SELECT $3, id, amount
+		  FROM taken
+		 WHERE $3 > 0
+	`, uuid, amount, entryID)
+	return err
+}
+
+type lotUse struct {
+	entryID int
+	lotID   int
+	amount  int
+}
+
+// restoreLotsTx puts amount coins back into the lots the returned entry took
+// them from, in the order they were taken. Lots that lapsed meanwhile are
+// burned again by the expiry job. Anything beyond what the entry took
+// becomes a lot that never lapses.
+func (db *DataBase) restoreLotsTx(ctx context.Context, tx *sql.Tx, uuid string, amount int, from entryRef) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT u.entry_id, u.lot_id, SUM(u.amount)
+		  FROM coin_lot_uses u
+		  JOIN ledger_entries e ON u.entry_id = e.id
+		  JOIN coin_lots l ON u.lot_id = l.id
+		 WHERE e.kind = $1
+		   AND e.reference = $2
+		   AND l.user_id = $3
+		 GROUP BY u.entry_id, u.lot_id
+		HAVING SUM(u.amount) > 0
+		 ORDER BY MIN(u.id)
+	`, from.kind, from.reference, uuid)
+	if err != nil {
+		return err
+	}
+	var uses []lotUse
+	for rows.Next() {
+		var use lotUse
+		if scanErr := rows.Scan(&use.entryID, &use.lotID, &use.amount); scanErr != nil {
+			rows.Close()
+			return scanErr
+		}
+		uses = append(uses, use)
+	}
+	rows.Close()
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return rowsErr
+	}
+
+	for _, use := range uses {
+		if amount == 0 {
+			return nil
+		}
+		restored := min(use.amount, amount)
+		_, err = tx.ExecContext(ctx, `
+			UPDATE coin_lots
+			   SET remaining = remaining + $1
+			 WHERE id = $2
+		`, restored, use.lotID)
+		if err != nil {
+			return err
+		}
+		// restorations are recorded against the entry that took the coins
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO coin_lot_uses (entry_id, lot_id, amount)
+			VALUES ($1, $2, $3)
+		`, use.entryID, use.lotID, -restored)
+		if err != nil {
+			return err
+		}
+		amount -= restored
+	}
+	if amount > 0 {
+		return db.addLotTx(ctx, tx, uuid, amount, nil)
+	}
+	return nil
+}
+
+func (db *DataBase) createWalletAccount(ctx context.Context, tx *sql.Tx, uuid string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO ledger_accounts (code, user_id)
+		VALUES ($1, $2)
+	`, WalletAccount(uuid), uuid)
+	return err
+}
@@ -0,0 +1,283 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// sending limits are counted over rolling windows
+const (
+	limitDay  = 24 * time.Hour
+	limitWeek = 7 * 24 * time.Hour
+)
+
+// LimitError is returned when a transfer does not fit one of the sender's limits.
+type LimitError struct {
+	Limit string
+	// how many coins can still be sent right now
+	Remaining int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit exceeded, %d coins remaining", e.Limit, e.Remaining)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrSendLimitExceeded
+}
+
+// GetSendLimits returns the global default and all overrides.
+func (db *DataBase) GetSendLimits(ctx context.Context) ([]models.SendLimit, error) {
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT l.scope,
+		       COALESCE(u.username, l.subject),
+		       l.per_transfer,
+		       l.daily,
+		       l.weekly
+		  FROM send_limits l
+		  LEFT JOIN users u ON l.scope = 'user' AND u.id::text = l.subject
+		 ORDER BY CASE l.scope WHEN 'global' THEN 0 WHEN 'role' THEN 1 ELSE 2 END, 2
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.SendLimit
+	for rows.Next() {
+		limit, scanErr := scanSendLimit(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		result = append(result, *limit)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return result, nil
+}
+
+// SetSendLimit creates or replaces the limit of a scope. Users are referenced
+// by username.
+func (db *DataBase) SetSendLimit(ctx context.Context, adminUuid string, limit models.SendLimit) (*models.SendLimit, error) {
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		subject, err := db.limitSubjectTx(ctx, tx, limit)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO send_limits (scope, subject, per_transfer, daily, weekly, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (scope, subject) DO UPDATE
+		   SET per_transfer = EXCLUDED.per_transfer,
+		       daily = EXCLUDED.daily,
+		       weekly = EXCLUDED.weekly,
+		       updated_by = EXCLUDED.updated_by,
+		       updated_at = CURRENT_TIMESTAMP
+	`, limit.Scope, subject, nullInt(limit.PerTransfer), nullInt(limit.Daily), nullInt(limit.Weekly), adminUuid)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+func (db *DataBase) DeleteSendLimit(ctx context.Context, scope string, subject string) error {
+	return db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		key, err := db.limitSubjectTx(ctx, tx, models.SendLimit{Scope: scope, Subject: subject})
+		if err != nil {
+			if errors.Is(err, ErrUserNotFound) {
+				return ErrSendLimitNotFound
+			}
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `
+		DELETE FROM send_limits
+		 WHERE scope = $1
+		   AND subject = $2
+	`, scope, key)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrSendLimitNotFound
+		}
+		return nil
+	})
+}
+
+// limitSubjectTx returns the stored subject, user limits are keyed by user id
+func (db *DataBase) limitSubjectTx(ctx context.Context, tx *sql.Tx, limit models.SendLimit) (string, error) {
+	if limit.Scope != models.LimitScopeUser {
+		return limit.Subject, nil
+	}
+	return db.getUuidByUsernameTx(ctx, tx, limit.Subject)
+}
+
+// checkSendLimitTx fails with a LimitError if amount does not fit the sender's
+// limits. Coins sent during the windows include offers still held in escrow,
+// expired coins and the reversed part of transfers are not counted.
+func (db *DataBase) checkSendLimitTx(ctx context.Context, tx *sql.Tx, uuid string, amount int) error {
+	// the user row lock serializes concurrent transfers of one sender
+	var role string
+	err := tx.QueryRowContext(ctx, `
+		SELECT role
+		  FROM users
+		 WHERE id = $1
+		   FOR UPDATE
+	`, uuid).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT scope, subject, per_transfer, daily, weekly
+		  FROM send_limits
+		 WHERE scope = 'global'
+		    OR (scope = 'role' AND subject = $1)
+		    OR (scope = 'user' AND subject = $2)
+	`, role, uuid)
+	if err != nil {
+		return err
+	}
+	var limits []models.SendLimit
+	for rows.Next() {
+		limit, scanErr := scanSendLimit(rows)
+		if scanErr != nil {
+			rows.Close()
+			return scanErr
+		}
+		limits = append(limits, *limit)
+	}
+	rows.Close()
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return rowsErr
+	}
+	limit := effectiveLimit(limits)
+	if isUnlimited(limit) {
+		return nil
+	}
+
+	var sentDay, sentWeek int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount) FILTER (WHERE created_at > CURRENT_TIMESTAMP - make_interval(secs => $2)), 0),
+		       COALESCE(SUM(amount), 0)
+		  FROM (SELECT t.amount - COALESCE((SELECT SUM(r.amount)
+		                                      FROM transactions r
+		                                     WHERE r.reverses_id = t.id), 0) AS amount,
+		               t.created_at
+		          FROM transactions t
+		         WHERE t.sender_id = $1
+		           AND t.receiver_id <> '`+IssuerUUID+`'
+		           AND t.reverses_id IS NULL
+		        UNION ALL
+		        SELECT amount, created_at
+		          FROM pending_transfers
+		         WHERE sender_id = $1
+		           AND status = 'pending') sent
+		 WHERE created_at > CURRENT_TIMESTAMP - make_interval(secs => $3)
+	`, uuid, limitDay.Seconds(), limitWeek.Seconds()).Scan(&sentDay, &sentWeek)
+	if err != nil {
+		return err
+	}
+	return checkLimit(limit, sentDay, sentWeek, amount)
+}
+
+// effectiveLimit merges the limits that apply to a user field by field,
+// narrower scopes win
+func effectiveLimit(limits []models.SendLimit) models.SendLimit {
+	var result models.SendLimit
+	for _, scope := range []string{models.LimitScopeGlobal, models.LimitScopeRole, models.LimitScopeUser} {
+		for _, l := range limits {
+			if l.Scope != scope {
+				continue
+			}
+			if l.PerTransfer != nil {
+				result.PerTransfer = l.PerTransfer
+			}
+			if l.Daily != nil {
+				result.Daily = l.Daily
+			}
+			if l.Weekly != nil {
+				result.Weekly = l.Weekly
+			}
+		}
+	}
+	return result
+}
+
+func isUnlimited(limit models.SendLimit) bool {
+	for _, value := range []*int{limit.PerTransfer, limit.Daily, limit.Weekly} {
+		if value != nil && *value > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// checkLimit reports the first limit amount does not fit into and the
+// smallest allowance left
+func checkLimit(limit models.SendLimit, sentDay, sentWeek, amount int) error {
+	remaining := -1
+	exceeded := ""
+	check := func(name string, value *int, used int) {
+		if value == nil || *value == 0 {
+			return
+		}
+		left := max(*value-used, 0)
+		if remaining < 0 || left < remaining {
+			remaining = left
+		}
+		if amount > left && exceeded == "" {
+			exceeded = name
+		}
+	}
+	check(models.LimitPerTransfer, limit.PerTransfer, 0)
+	check(models.LimitDaily, limit.Daily, sentDay)
+	check(models.LimitWeekly, limit.Weekly, sentWeek)
+
+	if exceeded == "" {
+		return nil
+	}
+	return &LimitError{Limit: exceeded, Remaining: remaining}
+}
+
+func scanSendLimit(row rowScanner) (*models.SendLimit, error) {
+	var limit models.SendLimit
+	var perTransfer, daily, weekly sql.NullInt64
+	err := row.Scan(&limit.Scope, &limit.Subject, &perTransfer, &daily, &weekly)
+	if err != nil {
+		return nil, err
+	}
+	limit.PerTransfer = intPtr(perTransfer)
+	limit.Daily = intPtr(daily)
+	limit.Weekly = intPtr(weekly)
+	return &limit, nil
+}
+
+func nullInt(value *int) sql.NullInt64 {
+	if value == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*value), Valid: true}
+}
+
+func intPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	v := int(value.Int64)
+	return &v
+}
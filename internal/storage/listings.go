@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const listingColumns = `
+		SELECT l.id, seller.username, m.name, l.quantity, l.price, l.status,
+		       COALESCE(buyer.username, ''), COALESCE(l.transaction_id, 0), l.created_at, l.closed_at
+		  FROM listings l
+		  JOIN users seller     ON l.seller_id = seller.id
+		  JOIN merchandise m    ON l.merchandise_id = m.id
+		  LEFT JOIN users buyer ON l.buyer_id = buyer.id`
+
+// lockedListing is an active listing locked for the rest of the transaction
+type lockedListing struct {
+	sellerUuid string
+	merchID    int
+	item       string
+	quantity   int
+	price      int
+}
+
+// CreateListing takes quantity of item out of the seller's inventory and
+// offers it for price coins. The items stay locked in the listing until it
+// is sold or cancelled, so they can't be listed, refunded or sold twice.
+func (db *DataBase) CreateListing(ctx context.Context, uuid string, item string, quantity int, price int) (*models.Listing, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if price <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	var listing *models.Listing
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		var merchID int
+		err := tx.QueryRowContext(ctx, `
+		SELECT id
+		  FROM merchandise
+		 WHERE name = $1
+	`, item).Scan(&merchID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrItemNotFound
+			}
+			return err
+		}
+		err = db.removeInventoryTx(ctx, tx, uuid, merchID, quantity)
+		if err != nil {
+			return err
+		}
+
+		var listingID int
+		err = tx.QueryRowContext(ctx, `
+		INSERT INTO listings (seller_id, merchandise_id, quantity, price)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, uuid, merchID, quantity, price).Scan(&listingID)
+		if err != nil {
+			return err
+		}
+		listing, err = db.getListingTx(ctx, tx, listingID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return listing, nil
+}
+
+// GetListings returns the active listings of everyone or, with filter.Mine,
+// the listings the user sold or bought, newest first.
+func (db *DataBase) GetListings(ctx context.Context, uuid string, filter models.ListingFilter) ([]models.Listing, error) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var conditions []string
+	if filter.Mine {
+		user := arg(uuid)
+		conditions = append(conditions, "(l.seller_id = "+user+" OR l.buyer_id = "+user+")")
+	} else {
+		conditions = append(conditions, "l.status = "+arg(models.ListingStatusActive))
+	}
+	if filter.Item != "" {
+		conditions = append(conditions, "m.name = "+arg(filter.Item))
+	}
+
+	rows, err := db.Tm.DB.QueryContext(ctx, listingColumns+`
+		 WHERE `+strings.Join(conditions, "\n\t\t   AND ")+`
+		 ORDER BY l.id DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Listing
+	for rows.Next() {
+		l, scanErr := scanListing(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		result = append(result, *l)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return result, nil
+}
+
+// BuyListing moves the price from the buyer to the seller and the items to
+// the buyer's inventory in one transaction. The payment is a regular
+// transfer and counts towards the buyer's sending limits.
+func (db *DataBase) BuyListing(ctx context.Context, uuid string, listingID int) (*models.Listing, error) {
+	var listing *models.Listing
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		l, err := db.lockListingTx(ctx, tx, listingID)
+		if err != nil {
+			return err
+		}
+		if l.sellerUuid == uuid {
+			return ErrBuyingOwnListing
+		}
+		balance, err := db.getBalanceTx(ctx, tx, uuid)
+		if err != nil {
+			return err
+		}
+		if balance-l.price < 0 {
+			return ErrNotEnoughBalance
+		}
+		err = db.checkSendLimitTx(ctx, tx, uuid, l.price)
+		if err != nil {
+			return err
+		}
+
+		transactionID, err := db.createTransaction(ctx, tx, uuid, l.sellerUuid, l.price, listingNote(listingID, l.item, l.quantity))
+		if err != nil {
+			return err
+		}
+		_, err = db.postEntry(ctx, tx, EntryTransfer, strconv.Itoa(transactionID),
+			posting{account: WalletAccount(uuid), amount: -l.price},
+			posting{account: WalletAccount(l.sellerUuid), amount: l.price},
+		)
+		if err != nil {
+			return err
+		}
+		err = db.addInventoryTx(ctx, tx, uuid, l.merchID, l.quantity)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+		UPDATE listings
+		   SET status = $1,
+		       buyer_id = $2,
+		       transaction_id = $3,
+		       closed_at = CURRENT_TIMESTAMP
+		 WHERE id = $4
+	`, models.ListingStatusSold, uuid, transactionID, listingID)
+		if err != nil {
+			return err
+		}
+		listing, err = db.getListingTx(ctx, tx, listingID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return listing, nil
+}
+
+// CancelListing returns the items to the seller's inventory.
+func (db *DataBase) CancelListing(ctx context.Context, uuid string, listingID int) (*models.Listing, error) {
+	var listing *models.Listing
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		l, err := db.lockListingTx(ctx, tx, listingID)
+		if err != nil {
+			return err
+		}
+		// other users don't learn about the listing from the error
+		if l.sellerUuid != uuid {
+			return ErrListingNotFound
+		}
+		err = db.addInventoryTx(ctx, tx, uuid, l.merchID, l.quantity)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+		UPDATE listings
+		   SET status = $1,
+		       closed_at = CURRENT_TIMESTAMP
+		 WHERE id = $2
+	`, models.ListingStatusCancelled, listingID)
+		if err != nil {
+			return err
+		}
+		listing, err = db.getListingTx(ctx, tx, listingID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return listing, nil
+}
+
+// lockListingTx checks that the listing is still active
+func (db *DataBase) lockListingTx(ctx context.Context, tx *sql.Tx, listingID int) (*lockedListing, error) {
+	var l lockedListing
+	var status string
+	err := tx.QueryRowContext(ctx, `
+		SELECT l.seller_id, l.merchandise_id, m.name, l.quantity, l.price, l.status
+		  FROM listings l
+		  JOIN merchandise m ON l.merchandise_id = m.id
+		 WHERE l.id = $1
+		   FOR UPDATE OF l
+	`, listingID).Scan(&l.sellerUuid, &l.merchID, &l.item, &l.quantity, &l.price, &status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrListingNotFound
+		}
+		return nil, err
+	}
+	if status != models.ListingStatusActive {
+		return nil, ErrListingClosed
+	}
+	return &l, nil
+}
+
+func (db *DataBase) getListingTx(ctx context.Context, tx *sql.Tx, listingID int) (*models.Listing, error) {
+	row := tx.QueryRowContext(ctx, listingColumns+`
+		 WHERE l.id = $1
+	`, listingID)
+	l, err := scanListing(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrListingNotFound
+		}
+		return nil, err
+	}
+	return l, nil
+}
+
+func scanListing(row rowScanner) (*models.Listing, error) {
+	var l models.Listing
+	err := row.Scan(&l.ID, &l.Seller, &l.Item, &l.Quantity, &l.Price, &l.Status, &l.Buyer, &l.TransactionID, &l.CreatedAt, &l.ClosedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// listingNote is the message of the payment, so the coin history shows what
+// was bought
+func listingNote(listingID int, item string, quantity int) models.TransferNote {
+	return models.TransferNote{Message: fmt.Sprintf("marketplace listing %d: %s x%d", listingID, item, quantity)}
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var errNoLapsedLot = errors.New("no lapsed coin lot")
+
+// expired coins show up in the history as a transfer to the issuer
+var expiryNote = models.TransferNote{Message: "coins expired"}
+
+// ExpireCoins burns what is left of up to limit lots that lapsed by now,
+// one lot per transaction.
+func (db *DataBase) ExpireCoins(ctx context.Context, now time.Time, limit int) (int, error) {
+	expired := 0
+	for expired < limit {
+		err := db.Tm.WriteTX(ctx, func(tx *sql.Tx) error {
+			return db.expireLotTx(ctx, tx, now)
+		})
+		if errors.Is(err, errNoLapsedLot) {
+			break
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+func (db *DataBase) expireLotTx(ctx context.Context, tx *sql.Tx, now time.Time) error {
+	// lots only change under the user row lock, so it is taken instead of the lot's
+	var lotID int
+	var uuid string
+	err := tx.QueryRowContext(ctx, `
+		SELECT l.id, l.user_id
+		  FROM coin_lots l
+		  JOIN users u ON l.user_id = u.id
+		 WHERE l.expires_at <= $1
+		   AND l.remaining > 0
+		 ORDER BY l.expires_at, l.id
+		 LIMIT 1
+		   FOR UPDATE OF u SKIP LOCKED
+	`, now).Scan(&lotID, &uuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errNoLapsedLot
+		}
+		return err
+	}
+
+	var remaining int
+	err = tx.QueryRowContext(ctx, `
+		SELECT remaining
+		  FROM coin_lots
+		 WHERE id = $1
+	`, lotID).Scan(&remaining)
+	if err != nil {
+		return err
+	}
+	if remaining == 0 {
+		return nil
+	}
+
+	_, err = db.createTransaction(ctx, tx, uuid, IssuerUUID, remaining, expiryNote)
+	if err != nil {
+		return err
+	}
+	_, err = db.postEntry(ctx, tx, EntryExpiry, lotReference(lotID),
+		posting{account: WalletAccount(uuid), amount: -remaining, lot: lotID},
+		posting{account: AccountIssuance, amount: remaining},
+	)
+	return err
+}
+
+func (db *DataBase) getExpiringLots(ctx context.Context, uuid string) ([]models.CoinLot, error) {
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT remaining, expires_at
+		  FROM coin_lots
+		 WHERE user_id = $1
+		   AND remaining > 0
+		   AND expires_at IS NOT NULL
+		 ORDER BY expires_at, id
+	`, uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.CoinLot
+	for rows.Next() {
+		var lot models.CoinLot
+		if scanErr := rows.Scan(&lot.Amount, &lot.ExpiresAt); scanErr != nil {
+			return nil, scanErr
+		}
+		result = append(result, lot)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return result, nil
+}
+
+func lotReference(lotID int) string {
+	return "lot:" + strconv.Itoa(lotID)
+}
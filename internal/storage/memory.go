@@ -0,0 +1,423 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"github.com/google/uuid"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type memItem struct {
+	id          int
+	name        string
+	price       int
+	description string
+	available   bool
+	hidden      bool
+	retired     bool
+	stock       *int
+	history     []models.MerchChange
+}
+
+type memTransaction struct {
+	id         int
+	senderId   string
+	receiverId string
+	amount     int
+	createdAt  time.Time
+	reversesID int
+	reversedBy string
+	message    string
+	category   string
+}
+
+type memEntry struct {
+	id        int
+	kind      string
+	reference string
+	postings  []posting
+	createdAt time.Time
+}
+
+// MemoryStorage keeps all data in process memory. It has the same semantics
+// as DataBase and is used for tests and local runs without Postgres.
+type MemoryStorage struct {
+	mu sync.RWMutex
+
+	users       map[string]*models.User
+	usersByName map[string]string
+
+	items       []*memItem
+	itemsByName map[string]*memItem
+
+	// user uuid -> merchandise id -> quantity, order keeps first purchase order
+	inventory      map[string]map[int]int
+	inventoryOrder map[string][]int
+
+	transactions []memTransaction
+	orders       []memOrder
+	refundCount  int
+	schedules    []memSchedule
+	pending      []memPending
+	payments     []memPaymentRequest
+	listings     []memListing
+	auctions     []memAuction
+	bidCount     int
+	limits       []models.SendLimit
+	allowances   []memAllowance
+	lots         []memLot
+	lotUses      []memLotUse
+	// audit trail of reconciliation repairs
+	reconcileRuns int
+	adjustments   []memAdjustment
+	runs          []models.ScheduledRun
+
+	accounts map[string]int
+	entries  []memEntry
+
+	idempotency map[string]*memIdempotencyKey
+}
+
+// same catalog as in the initial migration
+var defaultMerchandise = []struct {
+	name        string
+	price       int
+	description string
+	// 0 means unlimited
+	stock int
+}{
+	{"t-shirt", 80, "Cotton t-shirt with the company logo", 0},
+	{"cup", 20, "Ceramic mug for coffee or tea", 0},
+	{"book", 50, "Notebook with a branded cover", 0},
+	{"pen", 10, "Ballpoint pen", 0},
+	{"powerbank", 200, "Portable 10000 mAh battery", 0},
+	{"hoody", 300, "Warm hoody with the company logo", 0},
+	{"umbrella", 200, "Folding umbrella", 0},
+	{"socks", 10, "A pair of branded socks", 0},
+	{"wallet", 50, "Leather wallet", 0},
+	{"pink-hoody", 500, "Limited edition pink hoody", 10},
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	m := &MemoryStorage{
+		users:          make(map[string]*models.User),
+		usersByName:    make(map[string]string),
+		itemsByName:    make(map[string]*memItem),
+		inventory:      make(map[string]map[int]int),
+		inventoryOrder: make(map[string][]int),
+		idempotency:    make(map[string]*memIdempotencyKey),
+		accounts: map[string]int{
+			AccountIssuance: 0,
+			AccountStore:    0,
+			AccountEscrow:   0,
+		},
+	}
+	// the issuer is not in usersByName, so nobody can log in as it or send to it
+	m.users[IssuerUUID] = &models.User{
+		UUID:     uuid.MustParse(IssuerUUID),
+		Username: IssuerUsername,
+		Role:     models.RoleSystem,
+	}
+	for i, merch := range defaultMerchandise {
+		item := &memItem{
+			id:          i + 1,
+			name:        merch.name,
+			price:       merch.price,
+			description: merch.description,
+			available:   true,
+		}
+		if merch.stock > 0 {
+			stock := merch.stock
+			item.stock = &stock
+		}
+		item.recordChange(models.MerchActionCreate, "")
+		m.items = append(m.items, item)
+		m.itemsByName[item.name] = item
+	}
+	return m
+}
+
+func (m *MemoryStorage) GetUser(ctx context.Context, username string) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	uuid, ok := m.usersByName[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return m.userCopy(uuid), nil
+}
+
+func (m *MemoryStorage) CreateUser(ctx context.Context, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	uuid := user.UUID.String()
+	if _, ok := m.usersByName[user.Username]; ok {
+		return ErrUserAlreadyExists
+	}
+	if _, ok := m.users[uuid]; ok {
+		return ErrUserAlreadyExists
+	}
+	if user.Balance < 0 {
+		return ErrNotEnoughBalance
+	}
+
+	createdAt := user.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+	m.users[uuid] = &models.User{
+		UUID:         user.UUID,
+		Username:     user.Username,
+		PasswordHash: user.PasswordHash,
+		Role:         role,
+		CreatedAt:    createdAt,
+	}
+	m.usersByName[user.Username] = uuid
+	m.accounts[WalletAccount(uuid)] = 0
+
+	if user.Balance == 0 {
+		return nil
+	}
+	_, err := m.postEntry(EntryIssuance, "signup",
+		posting{account: AccountIssuance, amount: -user.Balance},
+		posting{account: WalletAccount(uuid), amount: user.Balance},
+	)
+	return err
+}
+
+func (m *MemoryStorage) GetUserRole(ctx context.Context, uuid string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[uuid]
+	if !ok {
+		return "", ErrUserNotFound
+	}
+	return user.Role, nil
+}
+
+func (m *MemoryStorage) SetUserRole(ctx context.Context, uuid string, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[uuid]
+	if !ok || user.Role == models.RoleSystem {
+		return ErrUserNotFound
+	}
+	user.Role = role
+	return nil
+}
+
+func (m *MemoryStorage) GetInfo(ctx context.Context, uuid string) (*models.Info, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.users[uuid]; !ok {
+		return nil, ErrUserNotFound
+	}
+
+	info := &models.Info{}
+	info.Coins = m.accounts[WalletAccount(uuid)]
+
+	var received, sent []models.Transaction
+	for i := len(m.transactions) - 1; i >= 0; i-- {
+		t := m.transactions[i]
+		tr := m.transactionModel(t)
+		if t.receiverId == uuid {
+			received = append(received, tr)
+		}
+		if t.senderId == uuid {
+			sent = append(sent, tr)
+		}
+	}
+	info.CoinsHistory = models.History{
+		Received: received,
+		Sent:     sent,
+	}
+
+	info.GiftHistory = m.giftHistory(uuid)
+
+	var inventory []models.Item
+	for _, id := range m.inventoryOrder[uuid] {
+		inventory = append(inventory, models.Item{
+			Type:     m.items[id-1].name,
+			Quantity: m.inventory[uuid][id],
+		})
+	}
+	info.Inventory = inventory
+	info.Expiring = m.expiringLots(uuid)
+
+	return info, nil
+}
+
+func (m *MemoryStorage) Send(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.send(uuid, toUser, amount, note)
+	if err != nil {
+		return err
+	}
+	m.completeIdempotencyKey(ctx)
+	return nil
+}
+
+// send must be called with the write lock held, it returns the transaction id
+func (m *MemoryStorage) send(uuid string, toUser string, amount int, note models.TransferNote) (int, error) {
+	if _, ok := m.users[uuid]; !ok {
+		return 0, ErrUserNotFound
+	}
+	if m.accounts[WalletAccount(uuid)]-amount < 0 {
+		return 0, ErrNotEnoughBalance
+	}
+	receiverUuid, ok := m.usersByName[toUser]
+	if !ok {
+		return 0, ErrUserNotFound
+	}
+	if uuid == receiverUuid {
+		return 0, ErrSendingToYourself
+	}
+	if err := m.checkSendLimit(uuid, amount); err != nil {
+		return 0, err
+	}
+	return m.transfer(uuid, receiverUuid, amount, note)
+}
+
+// transfer records a transfer that was already checked. It must be called
+// with the write lock held.
+func (m *MemoryStorage) transfer(senderUuid, receiverUuid string, amount int, note models.TransferNote) (int, error) {
+	transactionID := len(m.transactions) + 1
+	_, err := m.postEntry(EntryTransfer, strconv.Itoa(transactionID),
+		posting{account: WalletAccount(senderUuid), amount: -amount},
+		posting{account: WalletAccount(receiverUuid), amount: amount},
+	)
+	if err != nil {
+		return 0, err
+	}
+	m.transactions = append(m.transactions, memTransaction{
+		id:         transactionID,
+		senderId:   senderUuid,
+		receiverId: receiverUuid,
+		amount:     amount,
+		createdAt:  time.Now(),
+		message:    note.Message,
+		category:   note.Category,
+	})
+	return transactionID, nil
+}
+
+func (m *MemoryStorage) Buy(ctx context.Context, uuid string, item string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.placeOrder(uuid, []models.OrderLine{{Item: item, Quantity: 1}})
+	if err != nil {
+		return err
+	}
+	m.completeIdempotencyKey(ctx)
+	return nil
+}
+
+func (m *MemoryStorage) GetUuidByUsername(ctx context.Context, username string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	uuid, ok := m.usersByName[username]
+	if !ok {
+		return "", ErrUserNotFound
+	}
+	return uuid, nil
+}
+
+// postEntry must be called with the write lock held. Nothing is changed
+// unless every posting can be applied.
+func (m *MemoryStorage) postEntry(kind, reference string, postings ...posting) (int, error) {
+	sum := 0
+	for _, p := range postings {
+		if p.amount == 0 {
+			return 0, ErrUnbalancedEntry
+		}
+		sum += p.amount
+	}
+	if len(postings) < 2 || sum != 0 {
+		return 0, ErrUnbalancedEntry
+	}
+
+	changes := make(map[string]int)
+	for _, p := range postings {
+		if _, ok := m.accounts[p.account]; !ok {
+			return 0, ErrAccountNotFound
+		}
+		changes[p.account] += p.amount
+	}
+	for account, change := range changes {
+		if isWalletAccount(account) && m.accounts[account]+change < 0 {
+			return 0, ErrNotEnoughBalance
+		}
+	}
+	for _, p := range postings {
+		if p.lot > 0 && !m.lotCovers(p) {
+			return 0, ErrNotEnoughBalance
+		}
+	}
+	for account, change := range changes {
+		m.accounts[account] += change
+	}
+	entryID := len(m.entries) + 1
+	for _, p := range postings {
+		if isWalletAccount(p.account) {
+			m.applyToLots(entryID, p)
+		}
+	}
+
+	entry := memEntry{
+		id:        entryID,
+		kind:      kind,
+		reference: reference,
+		postings:  postings,
+		createdAt: time.Now(),
+	}
+	m.entries = append(m.entries, entry)
+	return entry.id, nil
+}
+
+func (m *MemoryStorage) addInventory(uuid string, merchID int, quantity int) {
+	if m.inventory[uuid] == nil {
+		m.inventory[uuid] = make(map[int]int)
+	}
+	if _, ok := m.inventory[uuid][merchID]; !ok {
+		m.inventoryOrder[uuid] = append(m.inventoryOrder[uuid], merchID)
+	}
+	m.inventory[uuid][merchID] += quantity
+}
+
+func (m *MemoryStorage) transactionModel(t memTransaction) models.Transaction {
+	return models.Transaction{
+		ID:         t.id,
+		FromUser:   m.users[t.senderId].Username,
+		ToUser:     m.users[t.receiverId].Username,
+		Amount:     t.amount,
+		CreatedAt:  t.createdAt,
+		ReversesID: t.reversesID,
+		Message:    t.message,
+		Category:   t.category,
+	}
+}
+
+func (m *MemoryStorage) userCopy(uuid string) *models.User {
+	u := *m.users[uuid]
+	u.Balance = m.accounts[WalletAccount(uuid)]
+	return &u
+}
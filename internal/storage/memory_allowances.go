@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"sort"
+	"time"
+)
+
+type memAllowance struct {
+	period        time.Time
+	userId        string
+	amount        int
+	transactionID int
+}
+
+func (m *MemoryStorage) ResumeAllowancePeriod(ctx context.Context) (*time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.allowances) == 0 {
+		return nil, nil
+	}
+	first, last := m.allowances[0].period, m.allowances[0].period
+	for _, a := range m.allowances {
+		if a.period.Before(first) {
+			first = a.period
+		}
+		if a.period.After(last) {
+			last = a.period
+		}
+	}
+	for period := first; period.Before(last); period = period.AddDate(0, 1, 0) {
+		if len(m.allowanceDue(period)) > 0 {
+			return &period, nil
+		}
+	}
+	return &last, nil
+}
+
+// allowanceDue returns the users that did not get the allowance for the
+// period yet in uuid order. It must be called with the read lock held.
+func (m *MemoryStorage) allowanceDue(period time.Time) []string {
+	issuedTo := make(map[string]bool)
+	for _, a := range m.allowances {
+		if a.period.Equal(period) {
+			issuedTo[a.userId] = true
+		}
+	}
+	var due []string
+	for uuid, user := range m.users {
+		if user.Role != models.RoleSystem && !issuedTo[uuid] && user.CreatedAt.Before(period.AddDate(0, 1, 0)) {
+			due = append(due, uuid)
+		}
+	}
+	sort.Strings(due)
+	return due
+}
+
+func (m *MemoryStorage) IssueAllowances(ctx context.Context, period time.Time, amount int, expiresAt *time.Time, limit int) (int, error) {
+	if amount <= 0 {
+		return 0, ErrInvalidAmount
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	issued := 0
+	for _, uuid := range m.allowanceDue(period) {
+		if issued == limit {
+			break
+		}
+		transactionID := len(m.transactions) + 1
+		_, err := m.postEntry(EntryIssuance, allowanceReference(period),
+			posting{account: AccountIssuance, amount: -amount},
+			posting{account: WalletAccount(uuid), amount: amount, expiresAt: expiresAt},
+		)
+		if err != nil {
+			return issued, err
+		}
+		note := allowanceNote(period)
+		m.transactions = append(m.transactions, memTransaction{
+			id:         transactionID,
+			senderId:   IssuerUUID,
+			receiverId: uuid,
+			amount:     amount,
+			createdAt:  time.Now(),
+			message:    note.Message,
+		})
+		m.allowances = append(m.allowances, memAllowance{
+			period:        period,
+			userId:        uuid,
+			amount:        amount,
+			transactionID: transactionID,
+		})
+		issued++
+	}
+	return issued, nil
+}
@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type memAuction struct {
+	id          int
+	merchID     int
+	startingBid int
+	endsAt      time.Time
+	status      string
+	createdBy   string
+	createdAt   time.Time
+	closedAt    *time.Time
+	orderID     int
+	// in the order they were placed
+	bids []memBid
+}
+
+type memBid struct {
+	id        int
+	userId    string
+	amount    int
+	status    string
+	createdAt time.Time
+}
+
+// held returns the top bid, nil if there are no bids
+func (a *memAuction) held() *memBid {
+	for i := range a.bids {
+		if a.bids[i].status == models.BidStatusHeld {
+			return &a.bids[i]
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStorage) CreateAuction(ctx context.Context, adminUuid string, item string, startingBid int, endsAt time.Time) (*models.Auction, error) {
+	if startingBid <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if !endsAt.After(time.Now()) {
+		return nil, ErrAuctionEndInPast
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	merch, ok := m.itemsByName[item]
+	if !ok || merch.retired {
+		return nil, ErrItemNotFound
+	}
+	if !merch.inStock(1) {
+		return nil, ErrOutOfStock
+	}
+	merch.takeStock(1)
+
+	a := memAuction{
+		id:          len(m.auctions) + 1,
+		merchID:     merch.id,
+		startingBid: startingBid,
+		endsAt:      endsAt,
+		status:      models.AuctionStatusOpen,
+		createdBy:   adminUuid,
+		createdAt:   time.Now(),
+	}
+	m.auctions = append(m.auctions, a)
+	return m.auctionModel(a, true), nil
+}
+
+func (m *MemoryStorage) GetAuctions(ctx context.Context) ([]models.Auction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.Auction
+	for _, a := range m.auctions {
+		if a.status == models.AuctionStatusOpen {
+			result = append(result, *m.auctionModel(a, false))
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].EndsAt.Before(result[j].EndsAt)
+	})
+	return result, nil
+}
+
+func (m *MemoryStorage) GetAuction(ctx context.Context, auctionID int) (*models.Auction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if auctionID <= 0 || auctionID > len(m.auctions) {
+		return nil, ErrAuctionNotFound
+	}
+	return m.auctionModel(m.auctions[auctionID-1], true), nil
+}
+
+func (m *MemoryStorage) PlaceBid(ctx context.Context, uuid string, auctionID int, amount int) (*models.Auction, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if auctionID <= 0 || auctionID > len(m.auctions) {
+		return nil, ErrAuctionNotFound
+	}
+	a := &m.auctions[auctionID-1]
+	if a.status != models.AuctionStatusOpen || !time.Now().Before(a.endsAt) {
+		return nil, ErrAuctionClosed
+	}
+	top := a.held()
+	if amount < a.startingBid || (top != nil && amount <= top.amount) {
+		return nil, ErrBidTooLow
+	}
+	if _, ok := m.users[uuid]; !ok {
+		return nil, ErrUserNotFound
+	}
+	balance := m.accounts[WalletAccount(uuid)]
+	if top != nil && top.userId == uuid {
+		balance += top.amount
+	}
+	if balance < amount {
+		return nil, ErrNotEnoughBalance
+	}
+
+	if top != nil {
+		_, err := m.postEntry(EntryRelease, bidReference(top.id),
+			posting{account: AccountEscrow, amount: -top.amount},
+			posting{account: WalletAccount(top.userId), amount: top.amount, returns: &entryRef{kind: EntryHold, reference: bidReference(top.id)}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		top.status = models.BidStatusReleased
+	}
+	m.bidCount++
+	bid := memBid{id: m.bidCount, userId: uuid, amount: amount, status: models.BidStatusHeld, createdAt: time.Now()}
+	_, err := m.postEntry(EntryHold, bidReference(bid.id),
+		posting{account: WalletAccount(uuid), amount: -amount},
+		posting{account: AccountEscrow, amount: amount},
+	)
+	if err != nil {
+		return nil, err
+	}
+	a.bids = append(a.bids, bid)
+	return m.auctionModel(*a, true), nil
+}
+
+func (m *MemoryStorage) CloseAuctions(ctx context.Context, now time.Time, limit int) ([]models.Auction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*memAuction
+	for i := range m.auctions {
+		a := &m.auctions[i]
+		if a.status == models.AuctionStatusOpen && !a.endsAt.After(now) {
+			due = append(due, a)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].endsAt.Before(due[j].endsAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	var closed []models.Auction
+	var failures []error
+	for _, a := range due {
+		if err := m.closeAuction(a); err != nil {
+			failures = append(failures, fmt.Errorf("auction %d: %w", a.id, err))
+			continue
+		}
+		closed = append(closed, *m.auctionModel(*a, true))
+	}
+	return closed, errors.Join(failures...)
+}
+
+// closeAuction must be called with the write lock held
+func (m *MemoryStorage) closeAuction(a *memAuction) error {
+	now := time.Now()
+	top := a.held()
+	if top == nil {
+		if merch := m.items[a.merchID-1]; merch.stock != nil {
+			*merch.stock++
+		}
+		a.status = models.AuctionStatusUnsold
+		a.closedAt = &now
+		return nil
+	}
+
+	order := memOrder{
+		id:        len(m.orders) + 1,
+		userId:    top.userId,
+		lines:     []memOrderLine{{merchID: a.merchID, quantity: 1, unitPrice: top.amount}},
+		total:     top.amount,
+		createdAt: now,
+		auctionID: a.id,
+	}
+	_, err := m.postEntry(EntryPurchase, orderReference(order.id),
+		posting{account: AccountEscrow, amount: -top.amount},
+		posting{account: AccountStore, amount: top.amount},
+	)
+	if err != nil {
+		return err
+	}
+	m.orders = append(m.orders, order)
+	m.addInventory(top.userId, a.merchID, 1)
+	top.status = models.BidStatusWon
+	a.status = models.AuctionStatusSold
+	a.orderID = order.id
+	a.closedAt = &now
+	return nil
+}
+
+// auctionModel must be called with the read lock held
+func (m *MemoryStorage) auctionModel(a memAuction, withBids bool) *models.Auction {
+	result := &models.Auction{
+		ID:          a.id,
+		Item:        m.items[a.merchID-1].name,
+		StartingBid: a.startingBid,
+		EndsAt:      a.endsAt,
+		Status:      a.status,
+		OrderID:     a.orderID,
+		CreatedAt:   a.createdAt,
+		ClosedAt:    copyTime(a.closedAt),
+	}
+	var bids []models.Bid
+	for _, b := range a.bids {
+		bids = append(bids, models.Bid{
+			ID:        b.id,
+			User:      m.users[b.userId].Username,
+			Amount:    b.amount,
+			Status:    b.status,
+			CreatedAt: b.createdAt,
+		})
+	}
+	// highest first, bids are placed in id order so equal bids keep it
+	sort.SliceStable(bids, func(i, j int) bool {
+		return bids[i].Amount > bids[j].Amount
+	})
+	if len(bids) > 0 {
+		top := bids[0]
+		result.TopBid = &top
+		if a.status == models.AuctionStatusSold {
+			result.Winner = top.User
+		}
+	}
+	if withBids {
+		result.Bids = bids
+	}
+	return result
+}
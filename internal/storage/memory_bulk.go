@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+)
+
+func (m *MemoryStorage) SendBulk(ctx context.Context, uuid string, transfers []models.BulkTransfer, note models.TransferNote) ([]models.BulkTransfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[uuid]; !ok {
+		return nil, ErrUserNotFound
+	}
+	_, err := validateRecipients(uuid, transfers, func(username string) (string, error) {
+		receiverUuid, ok := m.usersByName[username]
+		if !ok {
+			return "", ErrUserNotFound
+		}
+		return receiverUuid, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if m.accounts[WalletAccount(uuid)] < bulkTotal(transfers) {
+		return nil, ErrNotEnoughBalance
+	}
+	amounts := make([]int, len(transfers))
+	for i, t := range transfers {
+		amounts[i] = t.Amount
+	}
+	// there is no rollback here, so everything that can fail is checked first
+	if err = m.checkSendLimits(uuid, amounts); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.BulkTransfer, 0, len(transfers))
+	for _, t := range transfers {
+		t.TransactionID, err = m.send(uuid, t.ToUser, t.Amount, note)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+)
+
+func (m *MemoryStorage) GetCatalog(ctx context.Context) ([]models.Merch, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var catalog []models.Merch
+	for _, item := range m.items {
+		if item.hidden || item.retired {
+			continue
+		}
+		catalog = append(catalog, item.model())
+	}
+	return catalog, nil
+}
+
+func (m *MemoryStorage) GetMerch(ctx context.Context, item string) (*models.Merch, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	merch, ok := m.itemsByName[item]
+	if !ok || merch.hidden || merch.retired {
+		return nil, ErrItemNotFound
+	}
+	result := merch.model()
+	return &result, nil
+}
+
+func (i *memItem) model() models.Merch {
+	return models.Merch{
+		Name:        i.name,
+		Price:       i.price,
+		Description: i.description,
+		Available:   i.available && i.inStock(1),
+		Hidden:      i.hidden,
+		Stock:       copyStock(i.stock),
+	}
+}
+
+func (i *memItem) inStock(quantity int) bool {
+	return i.stock == nil || *i.stock >= quantity
+}
+
+func (i *memItem) takeStock(quantity int) {
+	if i.stock != nil {
+		*i.stock -= quantity
+	}
+}
+
+func copyStock(stock *int) *int {
+	if stock == nil {
+		return nil
+	}
+	s := *stock
+	return &s
+}
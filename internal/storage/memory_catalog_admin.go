@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"time"
+)
+
+func (m *MemoryStorage) CreateMerch(ctx context.Context, adminUuid string, merch models.Merch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.itemsByName[merch.Name]; ok {
+		return ErrItemAlreadyExists
+	}
+	item := &memItem{
+		id:          len(m.items) + 1,
+		name:        merch.Name,
+		price:       merch.Price,
+		description: merch.Description,
+		available:   merch.Available,
+		stock:       copyStock(merch.Stock),
+	}
+	item.recordChange(models.MerchActionCreate, m.usernameOf(adminUuid))
+	m.items = append(m.items, item)
+	m.itemsByName[item.name] = item
+	return nil
+}
+
+func (m *MemoryStorage) UpdateMerch(ctx context.Context, adminUuid string, item string, update models.MerchUpdate) (*models.Merch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	merch, ok := m.itemsByName[item]
+	if !ok || merch.retired {
+		return nil, ErrItemNotFound
+	}
+	if update.Name != nil && *update.Name != merch.name {
+		if _, exists := m.itemsByName[*update.Name]; exists {
+			return nil, ErrItemAlreadyExists
+		}
+		delete(m.itemsByName, merch.name)
+		merch.name = *update.Name
+		m.itemsByName[merch.name] = merch
+	}
+	if update.Price != nil {
+		merch.price = *update.Price
+	}
+	if update.Description != nil {
+		merch.description = *update.Description
+	}
+	if update.Available != nil {
+		merch.available = *update.Available
+	}
+	merch.recordChange(models.MerchActionUpdate, m.usernameOf(adminUuid))
+
+	result := merch.model()
+	return &result, nil
+}
+
+func (m *MemoryStorage) SetMerchHidden(ctx context.Context, adminUuid string, item string, hidden bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	merch, ok := m.itemsByName[item]
+	if !ok || merch.retired {
+		return ErrItemNotFound
+	}
+	merch.hidden = hidden
+	action := models.MerchActionUnhide
+	if hidden {
+		action = models.MerchActionHide
+	}
+	merch.recordChange(action, m.usernameOf(adminUuid))
+	return nil
+}
+
+func (m *MemoryStorage) RetireMerch(ctx context.Context, adminUuid string, item string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	merch, ok := m.itemsByName[item]
+	if !ok || merch.retired {
+		return ErrItemNotFound
+	}
+	merch.retired = true
+	merch.available = false
+	merch.recordChange(models.MerchActionRetire, m.usernameOf(adminUuid))
+	return nil
+}
+
+func (m *MemoryStorage) RestockMerch(ctx context.Context, adminUuid string, item string, quantity int) (*models.Merch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	merch, ok := m.itemsByName[item]
+	if !ok || merch.retired {
+		return nil, ErrItemNotFound
+	}
+	stock := quantity
+	if merch.stock != nil {
+		stock += *merch.stock
+	}
+	merch.stock = &stock
+	merch.recordChange(models.MerchActionRestock, m.usernameOf(adminUuid))
+
+	result := merch.model()
+	return &result, nil
+}
+
+func (m *MemoryStorage) GetMerchHistory(ctx context.Context, item string) ([]models.MerchChange, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	merch, ok := m.itemsByName[item]
+	if !ok {
+		return nil, ErrItemNotFound
+	}
+	return append([]models.MerchChange(nil), merch.history...), nil
+}
+
+func (i *memItem) recordChange(action string, changedBy string) {
+	i.history = append(i.history, models.MerchChange{
+		Action:      action,
+		Name:        i.name,
+		Price:       i.price,
+		Description: i.description,
+		Available:   i.available,
+		Hidden:      i.hidden,
+		Stock:       copyStock(i.stock),
+		ChangedBy:   changedBy,
+		ChangedAt:   time.Now(),
+	})
+}
+
+func (m *MemoryStorage) usernameOf(uuid string) string {
+	if user, ok := m.users[uuid]; ok {
+		return user.Username
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"sort"
+	"time"
+)
+
+func (m *MemoryStorage) ExportActivity(ctx context.Context, uuid string, filter models.ExportFilter, fn func(models.ActivityRecord) error) error {
+	records := m.activity(uuid, filter)
+	for _, rec := range records {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activity copies the records out so fn runs without the lock
+func (m *MemoryStorage) activity(uuid string, filter models.ExportFilter) []models.ActivityRecord {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	inRange := func(t time.Time) bool {
+		if filter.From != nil && t.Before(*filter.From) {
+			return false
+		}
+		return filter.To == nil || t.Before(*filter.To)
+	}
+
+	var result []models.ActivityRecord
+	for _, t := range m.transactions {
+		if (uuid != "" && t.senderId != uuid && t.receiverId != uuid) || !inRange(t.createdAt) {
+			continue
+		}
+		result = append(result, models.ActivityRecord{
+			Time:     t.createdAt,
+			Type:     models.ActivityTransfer,
+			ID:       t.id,
+			From:     m.users[t.senderId].Username,
+			To:       m.users[t.receiverId].Username,
+			Amount:   t.amount,
+			Message:  t.message,
+			Category: t.category,
+		})
+	}
+	for _, o := range m.orders {
+		if uuid != "" && o.userId != uuid && o.recipientId != uuid {
+			continue
+		}
+		username := m.users[o.userId].Username
+		var recipient string
+		if o.recipientId != "" {
+			recipient = m.users[o.recipientId].Username
+		}
+		if inRange(o.createdAt) {
+			for _, line := range o.lines {
+				result = append(result, models.ActivityRecord{
+					Time:     o.createdAt,
+					Type:     models.ActivityPurchase,
+					ID:       o.id,
+					From:     username,
+					To:       recipient,
+					Item:     m.items[line.merchID-1].name,
+					Quantity: line.quantity,
+					Amount:   line.quantity * line.unitPrice,
+					Message:  o.message,
+				})
+			}
+		}
+		// refunds are paid back to the buyer
+		for _, r := range o.refunds {
+			if (uuid != "" && o.userId != uuid) || !inRange(r.createdAt) {
+				continue
+			}
+			result = append(result, models.ActivityRecord{
+				Time:     r.createdAt,
+				Type:     models.ActivityRefund,
+				ID:       r.id,
+				To:       username,
+				Item:     m.items[r.merchID-1].name,
+				Quantity: r.quantity,
+				Amount:   r.amount,
+			})
+		}
+	}
+
+	// same order as the Postgres union
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+	return result
+}
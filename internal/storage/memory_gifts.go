@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+)
+
+func (m *MemoryStorage) SendGift(ctx context.Context, uuid string, toUser string, lines []models.OrderLine, message string) (*models.Order, error) {
+	lines, err := mergeOrderLines(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	recipientUuid, ok := m.usersByName[toUser]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	if recipientUuid == uuid {
+		return nil, ErrGiftToYourself
+	}
+	return m.placeOrderFor(uuid, recipientUuid, lines, message)
+}
+
+// giftHistory must be called with the read lock held
+func (m *MemoryStorage) giftHistory(uuid string) models.GiftHistory {
+	var history models.GiftHistory
+	for i := len(m.orders) - 1; i >= 0; i-- {
+		o := m.orders[i]
+		if o.recipientId == "" || (o.userId != uuid && o.recipientId != uuid) {
+			continue
+		}
+		g := models.Gift{
+			OrderID:   o.id,
+			FromUser:  m.users[o.userId].Username,
+			ToUser:    m.users[o.recipientId].Username,
+			Message:   o.message,
+			CreatedAt: o.createdAt,
+		}
+		for _, line := range o.lines {
+			g.Items = append(g.Items, models.Item{Type: m.items[line.merchID-1].name, Quantity: line.quantity})
+		}
+		gifts := &history.Sent
+		if o.recipientId == uuid {
+			gifts = &history.Received
+		}
+		if len(*gifts) < GiftHistoryLimit {
+			*gifts = append(*gifts, g)
+		}
+	}
+	return history
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"time"
+)
+
+type memIdempotencyKey struct {
+	requestHash string
+	response    *models.IdempotentResponse
+	createdAt   time.Time
+}
+
+func idempotencyMapKey(uuid, key string) string {
+	return uuid + "\x00" + key
+}
+
+func (m *MemoryStorage) ReserveIdempotencyKey(ctx context.Context, uuid, key, requestHash string) (*models.IdempotentResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.idempotency[idempotencyMapKey(uuid, key)]
+	if !ok || stored.response == nil && time.Since(stored.createdAt) > IdempotencyKeyTimeout {
+		m.idempotency[idempotencyMapKey(uuid, key)] = &memIdempotencyKey{requestHash: requestHash, createdAt: time.Now()}
+		return nil, nil
+	}
+	if stored.requestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if stored.response == nil {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	response := *stored.response
+	return &response, nil
+}
+
+func (m *MemoryStorage) SaveIdempotentResponse(ctx context.Context, uuid, key string, response models.IdempotentResponse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.idempotency[idempotencyMapKey(uuid, key)]; ok {
+		stored.response = &response
+	}
+	return nil
+}
+
+func (m *MemoryStorage) ReleaseIdempotencyKey(ctx context.Context, uuid, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.idempotency[idempotencyMapKey(uuid, key)]; ok && stored.response == nil {
+		delete(m.idempotency, idempotencyMapKey(uuid, key))
+	}
+	return nil
+}
+
+// completeIdempotencyKey must be called with the write lock held
+func (m *MemoryStorage) completeIdempotencyKey(ctx context.Context) {
+	claim, ok := idempotencyClaimFrom(ctx)
+	if !ok {
+		return
+	}
+	if stored, ok := m.idempotency[idempotencyMapKey(claim.uuid, claim.key)]; ok {
+		response := completedResponse
+		stored.response = &response
+	}
+}
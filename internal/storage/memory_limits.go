@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"time"
+)
+
+func (m *MemoryStorage) GetSendLimits(ctx context.Context) ([]models.SendLimit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.SendLimit
+	for _, scope := range []string{models.LimitScopeGlobal, models.LimitScopeRole, models.LimitScopeUser} {
+		for _, l := range m.limits {
+			if l.Scope != scope {
+				continue
+			}
+			if scope == models.LimitScopeUser {
+				l.Subject = m.users[l.Subject].Username
+			}
+			result = append(result, l)
+		}
+	}
+	return result, nil
+}
+
+func (m *MemoryStorage) SetSendLimit(ctx context.Context, adminUuid string, limit models.SendLimit) (*models.SendLimit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := limit
+	if limit.Scope == models.LimitScopeUser {
+		uuid, ok := m.usersByName[limit.Subject]
+		if !ok {
+			return nil, ErrUserNotFound
+		}
+		stored.Subject = uuid
+	}
+	for i, l := range m.limits {
+		if l.Scope == stored.Scope && l.Subject == stored.Subject {
+			m.limits[i] = stored
+			return &limit, nil
+		}
+	}
+	m.limits = append(m.limits, stored)
+	return &limit, nil
+}
+
+func (m *MemoryStorage) DeleteSendLimit(ctx context.Context, scope string, subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if scope == models.LimitScopeUser {
+		subject = m.usersByName[subject]
+	}
+	for i, l := range m.limits {
+		if l.Scope == scope && l.Subject == subject {
+			m.limits = append(m.limits[:i], m.limits[i+1:]...)
+			return nil
+		}
+	}
+	return ErrSendLimitNotFound
+}
+
+// checkSendLimit must be called with the write lock held
+func (m *MemoryStorage) checkSendLimit(uuid string, amount int) error {
+	return m.checkSendLimits(uuid, []int{amount})
+}
+
+// checkSendLimits checks transfers made one after another, each one counts
+// towards the windows of the next. It must be called with the write lock held.
+func (m *MemoryStorage) checkSendLimits(uuid string, amounts []int) error {
+	user, ok := m.users[uuid]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	var limits []models.SendLimit
+	for _, l := range m.limits {
+		if l.Scope == models.LimitScopeGlobal ||
+			(l.Scope == models.LimitScopeRole && l.Subject == user.Role) ||
+			(l.Scope == models.LimitScopeUser && l.Subject == uuid) {
+			limits = append(limits, l)
+		}
+	}
+	limit := effectiveLimit(limits)
+	if isUnlimited(limit) {
+		return nil
+	}
+
+	now := time.Now()
+	sentDay, sentWeek := 0, 0
+	count := func(amount int, createdAt time.Time) {
+		if now.Sub(createdAt) < limitWeek {
+			sentWeek += amount
+		}
+		if now.Sub(createdAt) < limitDay {
+			sentDay += amount
+		}
+	}
+	reversed := make(map[int]int)
+	for _, t := range m.transactions {
+		if t.reversesID != 0 {
+			reversed[t.reversesID] += t.amount
+		}
+	}
+	for _, t := range m.transactions {
+		if t.senderId == uuid && t.receiverId != IssuerUUID && t.reversesID == 0 {
+			count(t.amount-reversed[t.id], t.createdAt)
+		}
+	}
+	for _, p := range m.pending {
+		if p.senderId == uuid && p.status == models.PendingStatusPending {
+			count(p.amount, p.createdAt)
+		}
+	}
+	for _, amount := range amounts {
+		if err := checkLimit(limit, sentDay, sentWeek, amount); err != nil {
+			return err
+		}
+		sentDay += amount
+		sentWeek += amount
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"time"
+)
+
+type memListing struct {
+	id            int
+	sellerId      string
+	merchID       int
+	quantity      int
+	price         int
+	status        string
+	buyerId       string
+	transactionID int
+	createdAt     time.Time
+	closedAt      *time.Time
+}
+
+func (m *MemoryStorage) CreateListing(ctx context.Context, uuid string, item string, quantity int, price int) (*models.Listing, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if price <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	merch, ok := m.itemsByName[item]
+	if !ok {
+		return nil, ErrItemNotFound
+	}
+	if m.inventory[uuid][merch.id] < quantity {
+		return nil, ErrNotInInventory
+	}
+	m.removeInventory(uuid, merch.id, quantity)
+
+	l := memListing{
+		id:        len(m.listings) + 1,
+		sellerId:  uuid,
+		merchID:   merch.id,
+		quantity:  quantity,
+		price:     price,
+		status:    models.ListingStatusActive,
+		createdAt: time.Now(),
+	}
+	m.listings = append(m.listings, l)
+	return m.listingModel(l), nil
+}
+
+func (m *MemoryStorage) GetListings(ctx context.Context, uuid string, filter models.ListingFilter) ([]models.Listing, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.Listing
+	for i := len(m.listings) - 1; i >= 0; i-- {
+		l := m.listings[i]
+		if filter.Mine && l.sellerId != uuid && l.buyerId != uuid {
+			continue
+		}
+		if !filter.Mine && l.status != models.ListingStatusActive {
+			continue
+		}
+		if filter.Item != "" && m.items[l.merchID-1].name != filter.Item {
+			continue
+		}
+		result = append(result, *m.listingModel(l))
+	}
+	return result, nil
+}
+
+func (m *MemoryStorage) BuyListing(ctx context.Context, uuid string, listingID int) (*models.Listing, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, err := m.activeListing(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if l.sellerId == uuid {
+		return nil, ErrBuyingOwnListing
+	}
+	if _, ok := m.users[uuid]; !ok {
+		return nil, ErrUserNotFound
+	}
+	if m.accounts[WalletAccount(uuid)]-l.price < 0 {
+		return nil, ErrNotEnoughBalance
+	}
+	if err = m.checkSendLimit(uuid, l.price); err != nil {
+		return nil, err
+	}
+
+	transactionID, err := m.transfer(uuid, l.sellerId, l.price, listingNote(l.id, m.items[l.merchID-1].name, l.quantity))
+	if err != nil {
+		return nil, err
+	}
+	m.addInventory(uuid, l.merchID, l.quantity)
+	now := time.Now()
+	l.status = models.ListingStatusSold
+	l.buyerId = uuid
+	l.transactionID = transactionID
+	l.closedAt = &now
+	return m.listingModel(*l), nil
+}
+
+func (m *MemoryStorage) CancelListing(ctx context.Context, uuid string, listingID int) (*models.Listing, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, err := m.activeListing(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if l.sellerId != uuid {
+		return nil, ErrListingNotFound
+	}
+	m.addInventory(uuid, l.merchID, l.quantity)
+	now := time.Now()
+	l.status = models.ListingStatusCancelled
+	l.closedAt = &now
+	return m.listingModel(*l), nil
+}
+
+// activeListing must be called with the write lock held
+func (m *MemoryStorage) activeListing(listingID int) (*memListing, error) {
+	if listingID <= 0 || listingID > len(m.listings) {
+		return nil, ErrListingNotFound
+	}
+	l := &m.listings[listingID-1]
+	if l.status != models.ListingStatusActive {
+		return nil, ErrListingClosed
+	}
+	return l, nil
+}
+
+func (m *MemoryStorage) listingModel(l memListing) *models.Listing {
+	result := &models.Listing{
+		ID:            l.id,
+		Seller:        m.users[l.sellerId].Username,
+		Item:          m.items[l.merchID-1].name,
+		Quantity:      l.quantity,
+		Price:         l.price,
+		Status:        l.status,
+		TransactionID: l.transactionID,
+		CreatedAt:     l.createdAt,
+		ClosedAt:      copyTime(l.closedAt),
+	}
+	if l.buyerId != "" {
+		result.Buyer = m.users[l.buyerId].Username
+	}
+	return result
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+type memLot struct {
+	id        int
+	userId    string
+	amount    int
+	remaining int
+	expiresAt *time.Time
+	createdAt time.Time
+}
+
+// memLotUse is what an entry took from a lot, restorations are negative
+type memLotUse struct {
+	entryID int
+	lotID   int
+	amount  int
+}
+
+func (m *MemoryStorage) ExpireCoins(ctx context.Context, now time.Time, limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expired := 0
+	for i := range m.lots {
+		if expired == limit {
+			break
+		}
+		lot := &m.lots[i]
+		if lot.remaining == 0 || lot.expiresAt == nil || lot.expiresAt.After(now) {
+			continue
+		}
+		amount := lot.remaining
+		transactionID := len(m.transactions) + 1
+		_, err := m.postEntry(EntryExpiry, lotReference(lot.id),
+			posting{account: WalletAccount(lot.userId), amount: -amount, lot: lot.id},
+			posting{account: AccountIssuance, amount: amount},
+		)
+		if err != nil {
+			return expired, err
+		}
+		m.transactions = append(m.transactions, memTransaction{
+			id:         transactionID,
+			senderId:   lot.userId,
+			receiverId: IssuerUUID,
+			amount:     amount,
+			createdAt:  time.Now(),
+			message:    expiryNote.Message,
+		})
+		expired++
+	}
+	return expired, nil
+}
+
+// lotCovers must be called with the write lock held
+func (m *MemoryStorage) lotCovers(p posting) bool {
+	if p.lot > len(m.lots) {
+		return false
+	}
+	lot := m.lots[p.lot-1]
+	return WalletAccount(lot.userId) == p.account && lot.remaining >= -p.amount
+}
+
+// applyToLots must be called with the write lock held after the balance was
+// checked, so the lots always have enough coins. What entryID takes from each
+// lot is recorded, unless it is zero, so it can be restored.
+func (m *MemoryStorage) applyToLots(entryID int, p posting) {
+	uuid := strings.TrimPrefix(p.account, walletPrefix)
+	if p.amount > 0 && p.returns != nil {
+		m.restoreLots(uuid, p.amount, *p.returns)
+		return
+	}
+	if p.amount > 0 {
+		m.lots = append(m.lots, memLot{
+			id:        len(m.lots) + 1,
+			userId:    uuid,
+			amount:    p.amount,
+			remaining: p.amount,
+			expiresAt: copyTime(p.expiresAt),
+			createdAt: time.Now(),
+		})
+		return
+	}
+	if p.lot > 0 {
+		m.lots[p.lot-1].remaining += p.amount
+		return
+	}
+	need := -p.amount
+	for i := range m.lots {
+		if need == 0 {
+			break
+		}
+		lot := &m.lots[i]
+		if lot.userId != uuid || lot.remaining == 0 {
+			continue
+		}
+		taken := min(lot.remaining, need)
+		lot.remaining -= taken
+		need -= taken
+		if entryID > 0 {
+			m.lotUses = append(m.lotUses, memLotUse{entryID: entryID, lotID: lot.id, amount: taken})
+		}
+	}
+}
+
+// restoreLots puts amount coins back into the lots the returned entry took
+// them from, in the order they were taken, the rest becomes a lot that never
+// lapses. It must be called with the write lock held.
+func (m *MemoryStorage) restoreLots(uuid string, amount int, from entryRef) {
+	type key struct{ entryID, lotID int }
+	left := make(map[key]int)
+	var order []key
+	for _, use := range m.lotUses {
+		// the entry being posted is not in m.entries yet
+		if use.entryID > len(m.entries) {
+			continue
+		}
+		entry := m.entries[use.entryID-1]
+		if entry.kind != from.kind || entry.reference != from.reference || m.lots[use.lotID-1].userId != uuid {
+			continue
+		}
+		k := key{use.entryID, use.lotID}
+		if _, ok := left[k]; !ok {
+			order = append(order, k)
+		}
+		left[k] += use.amount
+	}
+
+	for _, k := range order {
+		if amount == 0 {
+			return
+		}
+		if left[k] <= 0 {
+			continue
+		}
+		restored := min(left[k], amount)
+		m.lots[k.lotID-1].remaining += restored
+		m.lotUses = append(m.lotUses, memLotUse{entryID: k.entryID, lotID: k.lotID, amount: -restored})
+		amount -= restored
+	}
+	if amount > 0 {
+		m.applyToLots(0, posting{account: WalletAccount(uuid), amount: amount})
+	}
+}
+
+// expiringLots must be called with the read lock held
+func (m *MemoryStorage) expiringLots(uuid string) []models.CoinLot {
+	var result []models.CoinLot
+	for _, lot := range m.lots {
+		if lot.userId == uuid && lot.remaining > 0 && lot.expiresAt != nil {
+			result = append(result, models.CoinLot{Amount: lot.remaining, ExpiresAt: *lot.expiresAt})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ExpiresAt.Before(result[j].ExpiresAt)
+	})
+	return result
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+type memOrder struct {
+	id        int
+	userId    string
+	lines     []memOrderLine
+	total     int
+	createdAt time.Time
+	refunds   []memRefund
+	// set on gifts
+	recipientId string
+	message     string
+	// set on orders that paid for an auction win
+	auctionID int
+}
+
+// holder is the user whose inventory got the items
+func (o memOrder) holder() string {
+	if o.recipientId != "" {
+		return o.recipientId
+	}
+	return o.userId
+}
+
+type memOrderLine struct {
+	merchID   int
+	quantity  int
+	unitPrice int
+	refunded  int
+}
+
+func (m *MemoryStorage) CreateOrder(ctx context.Context, uuid string, lines []models.OrderLine) (*models.Order, error) {
+	lines, err := mergeOrderLines(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.placeOrder(uuid, lines)
+}
+
+// placeOrder must be called with the write lock held
+func (m *MemoryStorage) placeOrder(uuid string, lines []models.OrderLine) (*models.Order, error) {
+	return m.placeOrderFor(uuid, "", lines, "")
+}
+
+// placeOrderFor puts the items in the inventory of recipientUuid, or of the
+// buyer when it is empty. It must be called with the write lock held.
+func (m *MemoryStorage) placeOrderFor(uuid, recipientUuid string, lines []models.OrderLine, message string) (*models.Order, error) {
+	if _, ok := m.users[uuid]; !ok {
+		return nil, ErrUserNotFound
+	}
+
+	order := memOrder{userId: uuid, recipientId: recipientUuid, message: message}
+	var items []*memItem
+	for _, line := range lines {
+		merch, ok := m.itemsByName[line.Item]
+		if !ok || merch.hidden || merch.retired {
+			return nil, fmt.Errorf("%w: %s", ErrItemNotFound, line.Item)
+		}
+		if !merch.available {
+			return nil, fmt.Errorf("%w: %s", ErrItemNotAvailable, line.Item)
+		}
+		if !merch.inStock(line.Quantity) {
+			return nil, fmt.Errorf("%w: %s", ErrOutOfStock, line.Item)
+		}
+		total, err := addLineTotal(order.total, merch.price, line.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, line.Item)
+		}
+		order.total = total
+		items = append(items, merch)
+		order.lines = append(order.lines, memOrderLine{
+			merchID:   merch.id,
+			quantity:  line.Quantity,
+			unitPrice: merch.price,
+		})
+	}
+	if m.accounts[WalletAccount(uuid)]-order.total < 0 {
+		return nil, ErrNotEnoughBalance
+	}
+
+	order.id = len(m.orders) + 1
+	order.createdAt = time.Now()
+	_, err := m.postEntry(EntryPurchase, orderReference(order.id),
+		posting{account: WalletAccount(uuid), amount: -order.total},
+		posting{account: AccountStore, amount: order.total},
+	)
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range order.lines {
+		items[i].takeStock(line.quantity)
+		m.addInventory(order.holder(), line.merchID, line.quantity)
+	}
+	m.orders = append(m.orders, order)
+
+	return m.orderModel(order), nil
+}
+
+func (m *MemoryStorage) orderModel(order memOrder) *models.Order {
+	result := &models.Order{
+		ID:        order.id,
+		Total:     order.total,
+		CreatedAt: order.createdAt,
+		Message:   order.message,
+	}
+	if order.recipientId != "" {
+		result.ToUser = m.users[order.recipientId].Username
+	}
+	for _, line := range order.lines {
+		result.Items = append(result.Items, models.OrderLine{
+			Item:      m.items[line.merchID-1].name,
+			Quantity:  line.quantity,
+			UnitPrice: line.unitPrice,
+			Refunded:  line.refunded,
+		})
+	}
+	for _, refund := range order.refunds {
+		result.Refunds = append(result.Refunds, m.refundModel(order, refund))
+	}
+	return result
+}
+
+func (m *MemoryStorage) GetPurchases(ctx context.Context, uuid string, filter models.PurchaseFilter) ([]models.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.Order
+	for i := len(m.orders) - 1; i >= 0 && len(result) < filter.Limit; i-- {
+		order := m.orders[i]
+		if order.userId != uuid {
+			continue
+		}
+		if filter.BeforeID > 0 && order.id >= filter.BeforeID {
+			continue
+		}
+		model := m.orderModel(order)
+		if filter.Item != "" && !containsItem(model.Items, filter.Item) {
+			continue
+		}
+		result = append(result, *model)
+	}
+	return result, nil
+}
+
+func containsItem(lines []models.OrderLine, item string) bool {
+	for _, line := range lines {
+		if line.Item == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"sort"
+	"time"
+)
+
+type memPaymentRequest struct {
+	id          int
+	requesterId string
+	amount      int
+	note        models.TransferNote
+	createdAt   time.Time
+	payers      []memPayer
+}
+
+type memPayer struct {
+	userId        string
+	status        string
+	resolvedAt    *time.Time
+	transactionID int
+}
+
+func (m *MemoryStorage) CreatePaymentRequest(ctx context.Context, uuid string, payers []string, amount int, note models.TransferNote) (*models.PaymentRequest, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[uuid]; !ok {
+		return nil, ErrUserNotFound
+	}
+	payerUuids, err := validateRecipients(uuid, paymentTransfers(payers, amount), func(username string) (string, error) {
+		payerUuid, ok := m.usersByName[username]
+		if !ok {
+			return "", ErrUserNotFound
+		}
+		return payerUuid, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := memPaymentRequest{
+		id:          len(m.payments) + 1,
+		requesterId: uuid,
+		amount:      amount,
+		note:        note,
+		createdAt:   time.Now(),
+	}
+	for _, payerUuid := range payerUuids {
+		r.payers = append(r.payers, memPayer{userId: payerUuid, status: models.PaymentStatusPending})
+	}
+	m.payments = append(m.payments, r)
+	return m.paymentModel(r, uuid), nil
+}
+
+func (m *MemoryStorage) GetPaymentRequests(ctx context.Context, uuid string) ([]models.PaymentRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.PaymentRequest
+	for i := len(m.payments) - 1; i >= 0; i-- {
+		if r := m.paymentModel(m.payments[i], uuid); r != nil {
+			result = append(result, *r)
+		}
+	}
+	return result, nil
+}
+
+func (m *MemoryStorage) PayPaymentRequest(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, p, err := m.openPayment(uuid, requestID)
+	if err != nil {
+		return nil, err
+	}
+	transactionID, err := m.send(uuid, m.users[r.requesterId].Username, r.amount, r.note)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	p.status = models.PaymentStatusPaid
+	p.resolvedAt = &now
+	p.transactionID = transactionID
+	return m.paymentModel(*r, uuid), nil
+}
+
+func (m *MemoryStorage) RejectPaymentRequest(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, p, err := m.openPayment(uuid, requestID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	p.status = models.PaymentStatusRejected
+	p.resolvedAt = &now
+	return m.paymentModel(*r, uuid), nil
+}
+
+// openPayment must be called with the write lock held
+func (m *MemoryStorage) openPayment(uuid string, requestID int) (*memPaymentRequest, *memPayer, error) {
+	if requestID <= 0 || requestID > len(m.payments) {
+		return nil, nil, ErrPaymentRequestNotFound
+	}
+	r := &m.payments[requestID-1]
+	for i := range r.payers {
+		p := &r.payers[i]
+		if p.userId != uuid {
+			continue
+		}
+		if p.status != models.PaymentStatusPending {
+			return nil, nil, ErrPaymentRequestAnswered
+		}
+		return r, p, nil
+	}
+	return nil, nil, ErrPaymentRequestNotFound
+}
+
+// paymentModel returns what uuid can see of the request, nil if nothing.
+// It must be called with the read lock held.
+func (m *MemoryStorage) paymentModel(r memPaymentRequest, uuid string) *models.PaymentRequest {
+	result := &models.PaymentRequest{
+		ID:        r.id,
+		Requester: m.users[r.requesterId].Username,
+		Amount:    r.amount,
+		Message:   r.note.Message,
+		Category:  r.note.Category,
+		CreatedAt: r.createdAt,
+	}
+	for _, p := range r.payers {
+		if r.requesterId != uuid && p.userId != uuid {
+			continue
+		}
+		result.Payers = append(result.Payers, models.Payer{
+			User:          m.users[p.userId].Username,
+			Status:        p.status,
+			ResolvedAt:    copyTime(p.resolvedAt),
+			TransactionID: p.transactionID,
+		})
+	}
+	if len(result.Payers) == 0 {
+		return nil
+	}
+	sort.Slice(result.Payers, func(i, j int) bool {
+		return result.Payers[i].User < result.Payers[j].User
+	})
+	return result
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"strconv"
+	"time"
+)
+
+type memPending struct {
+	id            int
+	senderId      string
+	receiverId    string
+	amount        int
+	note          models.TransferNote
+	status        string
+	expiresAt     time.Time
+	createdAt     time.Time
+	resolvedAt    *time.Time
+	transactionID int
+}
+
+func (m *MemoryStorage) CreatePendingTransfer(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote, ttl time.Duration) (*models.PendingTransfer, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	receiverUuid, ok := m.usersByName[toUser]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	if uuid == receiverUuid {
+		return nil, ErrSendingToYourself
+	}
+	if err := m.checkSendLimit(uuid, amount); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	p := memPending{
+		id:         len(m.pending) + 1,
+		senderId:   uuid,
+		receiverId: receiverUuid,
+		amount:     amount,
+		note:       note,
+		status:     models.PendingStatusPending,
+		expiresAt:  now.Add(ttl),
+		createdAt:  now,
+	}
+	_, err := m.postEntry(EntryHold, pendingReference(p.id),
+		posting{account: WalletAccount(uuid), amount: -amount},
+		posting{account: AccountEscrow, amount: amount},
+	)
+	if err != nil {
+		return nil, err
+	}
+	m.pending = append(m.pending, p)
+	return m.pendingModel(p), nil
+}
+
+func (m *MemoryStorage) GetPendingTransfers(ctx context.Context, uuid string) ([]models.PendingTransfer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.PendingTransfer
+	for i := len(m.pending) - 1; i >= 0; i-- {
+		p := m.pending[i]
+		if p.senderId == uuid || p.receiverId == uuid {
+			result = append(result, *m.pendingModel(p))
+		}
+	}
+	return result, nil
+}
+
+func (m *MemoryStorage) AcceptPendingTransfer(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, err := m.openPendingTransfer(uuid, pendingID)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionID := len(m.transactions) + 1
+	_, err = m.postEntry(EntryTransfer, strconv.Itoa(transactionID),
+		posting{account: AccountEscrow, amount: -p.amount},
+		posting{account: WalletAccount(p.receiverId), amount: p.amount},
+	)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	m.transactions = append(m.transactions, memTransaction{
+		id:         transactionID,
+		senderId:   p.senderId,
+		receiverId: p.receiverId,
+		amount:     p.amount,
+		message:    p.note.Message,
+		category:   p.note.Category,
+		createdAt:  now,
+	})
+	p.status = models.PendingStatusAccepted
+	p.resolvedAt = &now
+	p.transactionID = transactionID
+	return m.pendingModel(*p), nil
+}
+
+func (m *MemoryStorage) DeclinePendingTransfer(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, err := m.openPendingTransfer(uuid, pendingID)
+	if err != nil {
+		return nil, err
+	}
+	err = m.releasePendingTransfer(p, models.PendingStatusDeclined)
+	if err != nil {
+		return nil, err
+	}
+	return m.pendingModel(*p), nil
+}
+
+func (m *MemoryStorage) ExpirePendingTransfers(ctx context.Context, now time.Time, limit int) ([]models.PendingTransfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expired []models.PendingTransfer
+	for i := range m.pending {
+		if len(expired) == limit {
+			break
+		}
+		p := &m.pending[i]
+		if p.status != models.PendingStatusPending || p.expiresAt.After(now) {
+			continue
+		}
+		if err := m.releasePendingTransfer(p, models.PendingStatusExpired); err != nil {
+			return expired, err
+		}
+		expired = append(expired, *m.pendingModel(*p))
+	}
+	return expired, nil
+}
+
+// openPendingTransfer must be called with the write lock held
+func (m *MemoryStorage) openPendingTransfer(uuid string, pendingID int) (*memPending, error) {
+	if pendingID <= 0 || pendingID > len(m.pending) || m.pending[pendingID-1].receiverId != uuid {
+		return nil, ErrPendingTransferNotFound
+	}
+	p := &m.pending[pendingID-1]
+	if p.status != models.PendingStatusPending || !time.Now().Before(p.expiresAt) {
+		return nil, ErrPendingTransferClosed
+	}
+	return p, nil
+}
+
+func (m *MemoryStorage) releasePendingTransfer(p *memPending, status string) error {
+	_, err := m.postEntry(EntryRelease, pendingReference(p.id),
+		posting{account: AccountEscrow, amount: -p.amount},
+		posting{account: WalletAccount(p.senderId), amount: p.amount, returns: &entryRef{kind: EntryHold, reference: pendingReference(p.id)}},
+	)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	p.status = status
+	p.resolvedAt = &now
+	return nil
+}
+
+func (m *MemoryStorage) pendingModel(p memPending) *models.PendingTransfer {
+	return &models.PendingTransfer{
+		ID:            p.id,
+		FromUser:      m.users[p.senderId].Username,
+		ToUser:        m.users[p.receiverId].Username,
+		Amount:        p.amount,
+		Message:       p.note.Message,
+		Category:      p.note.Category,
+		Status:        p.status,
+		ExpiresAt:     p.expiresAt,
+		CreatedAt:     p.createdAt,
+		ResolvedAt:    copyTime(p.resolvedAt),
+		TransactionID: p.transactionID,
+	}
+}
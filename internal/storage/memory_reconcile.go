@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+type memAdjustment struct {
+	runID    int
+	userId   string
+	check    string
+	expected int
+	actual   int
+	entryID  int
+}
+
+func (m *MemoryStorage) Reconcile(ctx context.Context, repair bool) (*models.ReconcileReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := &models.ReconcileReport{
+		Checked:           time.Now().UTC(),
+		UnbalancedEntries: []int{},
+		Discrepancies:     []models.Discrepancy{},
+	}
+	for _, e := range m.entries {
+		sum := 0
+		for _, p := range e.postings {
+			sum += p.amount
+		}
+		if sum != 0 {
+			report.UnbalancedEntries = append(report.UnbalancedEntries, e.id)
+		}
+	}
+
+	wallets := m.walletFigures()
+	report.Wallets = len(wallets)
+	for _, w := range wallets {
+		found := w.discrepancies()
+		if len(found) == 0 {
+			continue
+		}
+		if repair {
+			if report.RunID == 0 {
+				m.reconcileRuns++
+				report.RunID = m.reconcileRuns
+			}
+			err := m.repairWallet(report.RunID, w)
+			for i := range found {
+				if err != nil {
+					found[i].Error = err.Error()
+				} else {
+					found[i].Repaired = true
+				}
+			}
+		}
+		report.Discrepancies = append(report.Discrepancies, found...)
+	}
+	return report, nil
+}
+
+// repairWallet must be called with the write lock held
+func (m *MemoryStorage) repairWallet(runID int, w walletFigures) error {
+	account := WalletAccount(w.uuid)
+	if w.balance != w.ledger {
+		check := models.CheckLedger
+		if w.balance < 0 {
+			check = models.CheckNegativeBalance
+		}
+		m.accounts[account] = w.ledger
+		m.adjustments = append(m.adjustments, memAdjustment{
+			runID: runID, userId: w.uuid, check: check, expected: w.ledger, actual: w.balance,
+		})
+		w.balance = w.ledger
+	}
+
+	if diff := w.activity - w.ledger; diff != 0 {
+		entryID, err := m.postEntry(EntryAdjustment, reconcileReference(runID),
+			posting{account: AccountIssuance, amount: -diff},
+			posting{account: account, amount: diff},
+		)
+		if err != nil {
+			return err
+		}
+		m.adjustments = append(m.adjustments, memAdjustment{
+			runID: runID, userId: w.uuid, check: models.CheckActivity, expected: w.activity, actual: w.ledger, entryID: entryID,
+		})
+		w.balance += diff
+		w.lots = m.lotsTotal(w.uuid)
+	}
+
+	if diff := w.balance - w.lots; diff != 0 {
+		// applyToLots spends lots oldest first or adds a lot that never lapses
+		m.applyToLots(0, posting{account: account, amount: diff})
+		m.adjustments = append(m.adjustments, memAdjustment{
+			runID: runID, userId: w.uuid, check: models.CheckLots, expected: w.balance, actual: w.lots,
+		})
+	}
+	return nil
+}
+
+// walletFigures must be called with the read lock held
+func (m *MemoryStorage) walletFigures() []walletFigures {
+	figures := make(map[string]*walletFigures)
+	var order []*models.User
+	for uuid, user := range m.users {
+		if user.Role == models.RoleSystem {
+			continue
+		}
+		figures[uuid] = &walletFigures{uuid: uuid, balance: m.accounts[WalletAccount(uuid)]}
+		order = append(order, user)
+	}
+
+	for _, e := range m.entries {
+		for _, p := range e.postings {
+			if !isWalletAccount(p.account) {
+				continue
+			}
+			w, ok := figures[strings.TrimPrefix(p.account, walletPrefix)]
+			if !ok {
+				continue
+			}
+			w.ledger += p.amount
+			// allowances are counted as transfers from the issuer below
+			if (e.kind == EntryOpening || e.kind == EntryIssuance) && !strings.HasPrefix(e.reference, "allowance:") {
+				w.activity += p.amount
+			}
+		}
+	}
+	for _, t := range m.transactions {
+		if w, ok := figures[t.receiverId]; ok {
+			w.activity += t.amount
+		}
+		if w, ok := figures[t.senderId]; ok {
+			w.activity -= t.amount
+		}
+	}
+	for _, o := range m.orders {
+		w, ok := figures[o.userId]
+		if !ok {
+			continue
+		}
+		w.activity -= o.total
+		for _, r := range o.refunds {
+			w.activity += r.amount
+		}
+	}
+	for _, p := range m.pending {
+		if w, ok := figures[p.senderId]; ok && p.status == models.PendingStatusPending {
+			w.activity -= p.amount
+		}
+	}
+	for _, a := range m.auctions {
+		for _, b := range a.bids {
+			if w, ok := figures[b.userId]; ok && b.status == models.BidStatusHeld {
+				w.activity -= b.amount
+			}
+		}
+	}
+	for _, lot := range m.lots {
+		if w, ok := figures[lot.userId]; ok {
+			w.lots += lot.remaining
+		}
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].CreatedAt.Equal(order[j].CreatedAt) {
+			return order[i].UUID.String() < order[j].UUID.String()
+		}
+		return order[i].CreatedAt.Before(order[j].CreatedAt)
+	})
+	result := make([]walletFigures, 0, len(order))
+	for _, user := range order {
+		result = append(result, *figures[user.UUID.String()])
+	}
+	return result
+}
+
+// lotsTotal must be called with the read lock held
+func (m *MemoryStorage) lotsTotal(uuid string) int {
+	total := 0
+	for _, lot := range m.lots {
+		if lot.userId == uuid {
+			total += lot.remaining
+		}
+	}
+	return total
+}
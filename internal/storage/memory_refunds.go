@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"time"
+)
+
+type memRefund struct {
+	id         int
+	merchID    int
+	quantity   int
+	amount     int
+	refundedBy string
+	createdAt  time.Time
+}
+
+func (m *MemoryStorage) RefundPurchase(ctx context.Context, uuid string, orderID int, item string, quantity int, window time.Duration) (*models.Refund, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, err := m.getOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.userId != uuid {
+		return nil, ErrOrderNotFound
+	}
+	if time.Since(order.createdAt) > window {
+		return nil, ErrRefundWindowExpired
+	}
+	return m.refund(order, item, quantity, "")
+}
+
+func (m *MemoryStorage) AdminRefundPurchase(ctx context.Context, adminUuid string, orderID int, item string, quantity int) (*models.Refund, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, err := m.getOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	return m.refund(order, item, quantity, adminUuid)
+}
+
+func (m *MemoryStorage) getOrder(orderID int) (*memOrder, error) {
+	if orderID <= 0 || orderID > len(m.orders) {
+		return nil, ErrOrderNotFound
+	}
+	return &m.orders[orderID-1], nil
+}
+
+// refund must be called with the write lock held
+func (m *MemoryStorage) refund(order *memOrder, item string, quantity int, refundedBy string) (*models.Refund, error) {
+	merch, ok := m.itemsByName[item]
+	if !ok {
+		return nil, ErrItemNotFound
+	}
+	var line *memOrderLine
+	for i := range order.lines {
+		if order.lines[i].merchID == merch.id {
+			line = &order.lines[i]
+		}
+	}
+	if line == nil {
+		return nil, ErrItemNotFound
+	}
+	if order.auctionID != 0 {
+		return nil, ErrAuctionWinNotRefundable
+	}
+
+	left := line.quantity - line.refunded
+	if quantity == 0 {
+		quantity = left
+	}
+	if quantity <= 0 || quantity > left {
+		return nil, ErrRefundExceedsPurchase
+	}
+	if m.inventory[order.holder()][merch.id] < quantity {
+		return nil, ErrNotInInventory
+	}
+
+	m.refundCount++
+	refund := memRefund{
+		id:         m.refundCount,
+		merchID:    merch.id,
+		quantity:   quantity,
+		amount:     quantity * line.unitPrice,
+		refundedBy: refundedBy,
+		createdAt:  time.Now(),
+	}
+	_, err := m.postEntry(EntryRefund, refundReference(refund.id),
+		posting{account: AccountStore, amount: -refund.amount},
+		posting{account: WalletAccount(order.userId), amount: refund.amount, returns: &entryRef{kind: EntryPurchase, reference: orderReference(order.id)}},
+	)
+	if err != nil {
+		m.refundCount--
+		return nil, err
+	}
+	m.removeInventory(order.holder(), merch.id, quantity)
+	if merch.stock != nil {
+		*merch.stock += quantity
+	}
+	line.refunded += quantity
+	order.refunds = append(order.refunds, refund)
+
+	result := m.refundModel(*order, refund)
+	if order.recipientId != "" {
+		result.ToUser = m.users[order.recipientId].Username
+	}
+	return &result, nil
+}
+
+func (m *MemoryStorage) removeInventory(uuid string, merchID int, quantity int) {
+	m.inventory[uuid][merchID] -= quantity
+	if m.inventory[uuid][merchID] > 0 {
+		return
+	}
+	delete(m.inventory[uuid], merchID)
+	ids := m.inventoryOrder[uuid]
+	for i, id := range ids {
+		if id == merchID {
+			m.inventoryOrder[uuid] = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+}
+
+func (m *MemoryStorage) refundModel(order memOrder, refund memRefund) models.Refund {
+	return models.Refund{
+		ID:        refund.id,
+		OrderID:   order.id,
+		User:      m.users[order.userId].Username,
+		Item:      m.items[refund.merchID-1].name,
+		Quantity:  refund.quantity,
+		Amount:    refund.amount,
+		CreatedAt: refund.createdAt,
+	}
+}
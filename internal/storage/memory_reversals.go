@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"strconv"
+	"time"
+)
+
+func (m *MemoryStorage) ReverseTransaction(ctx context.Context, adminUuid string, transactionID int, policy string) (*models.Reversal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if transactionID <= 0 || transactionID > len(m.transactions) {
+		return nil, ErrTransactionNotFound
+	}
+	original := m.transactions[transactionID-1]
+	if original.reversesID != 0 {
+		return nil, ErrReversalOfReversal
+	}
+	if original.senderId == IssuerUUID || original.receiverId == IssuerUUID {
+		return nil, ErrSystemTransaction
+	}
+
+	remaining := original.amount
+	for _, t := range m.transactions {
+		if t.reversesID == transactionID {
+			remaining -= t.amount
+		}
+	}
+	if remaining == 0 {
+		return nil, ErrAlreadyReversed
+	}
+
+	balance := m.accounts[WalletAccount(original.receiverId)]
+	amount := remaining
+	if balance < remaining {
+		if policy != models.ReversalPolicyPartial || balance == 0 {
+			return nil, ErrNotEnoughBalance
+		}
+		amount = balance
+	}
+
+	reversal := memTransaction{
+		id:         len(m.transactions) + 1,
+		senderId:   original.receiverId,
+		receiverId: original.senderId,
+		amount:     amount,
+		createdAt:  time.Now(),
+		reversesID: transactionID,
+		reversedBy: adminUuid,
+	}
+	_, err := m.postEntry(EntryReversal, strconv.Itoa(reversal.id),
+		posting{account: WalletAccount(reversal.senderId), amount: -amount},
+		posting{account: WalletAccount(reversal.receiverId), amount: amount, returns: m.transferSource(transactionID)},
+	)
+	if err != nil {
+		return nil, err
+	}
+	m.transactions = append(m.transactions, reversal)
+
+	return &models.Reversal{
+		ID:         reversal.id,
+		OriginalID: transactionID,
+		FromUser:   m.users[reversal.senderId].Username,
+		ToUser:     m.users[reversal.receiverId].Username,
+		Amount:     amount,
+		Remaining:  remaining - amount,
+		CreatedAt:  reversal.createdAt,
+	}, nil
+}
+
+func (m *MemoryStorage) transferSource(transactionID int) *entryRef {
+	for _, p := range m.pending {
+		if p.transactionID == transactionID {
+			return &entryRef{kind: EntryHold, reference: pendingReference(p.id)}
+		}
+	}
+	return &entryRef{kind: EntryTransfer, reference: strconv.Itoa(transactionID)}
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"time"
+)
+
+type memSchedule struct {
+	id         int
+	userId     string
+	receiverId string
+	amount     int
+	note       models.TransferNote
+	interval   string
+	nextRunAt  *time.Time
+	active     bool
+	cancelled  bool
+	createdAt  time.Time
+}
+
+func (m *MemoryStorage) CreateSchedule(ctx context.Context, uuid string, schedule models.ScheduledTransfer) (*models.ScheduledTransfer, error) {
+	if schedule.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	receiverUuid, ok := m.usersByName[schedule.ToUser]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	if receiverUuid == uuid {
+		return nil, ErrSendingToYourself
+	}
+
+	s := memSchedule{
+		id:         len(m.schedules) + 1,
+		userId:     uuid,
+		receiverId: receiverUuid,
+		amount:     schedule.Amount,
+		note:       models.TransferNote{Message: schedule.Message, Category: schedule.Category},
+		interval:   schedule.Interval,
+		nextRunAt:  copyTime(schedule.NextRunAt),
+		active:     true,
+		createdAt:  time.Now(),
+	}
+	m.schedules = append(m.schedules, s)
+	result := m.scheduleModel(s)
+	return &result, nil
+}
+
+func (m *MemoryStorage) GetSchedules(ctx context.Context, uuid string) ([]models.ScheduledTransfer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.ScheduledTransfer
+	for _, s := range m.schedules {
+		if s.userId == uuid && !s.cancelled {
+			result = append(result, m.scheduleModel(s))
+		}
+	}
+	return result, nil
+}
+
+func (m *MemoryStorage) UpdateSchedule(ctx context.Context, uuid string, scheduleID int, update models.ScheduleUpdate) (*models.ScheduledTransfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.getSchedule(uuid, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if s.cancelled {
+		return nil, ErrScheduleNotFound
+	}
+	if update.Amount != nil {
+		s.amount = *update.Amount
+	}
+	if update.Interval != nil {
+		s.interval = *update.Interval
+	}
+	if update.NextRunAt != nil {
+		s.nextRunAt = copyTime(update.NextRunAt)
+	}
+	if update.Active != nil {
+		s.active = *update.Active
+	}
+	result := m.scheduleModel(*s)
+	return &result, nil
+}
+
+func (m *MemoryStorage) CancelSchedule(ctx context.Context, uuid string, scheduleID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.getSchedule(uuid, scheduleID)
+	if err != nil {
+		return err
+	}
+	if s.cancelled {
+		return ErrScheduleNotFound
+	}
+	s.cancelled = true
+	s.active = false
+	return nil
+}
+
+func (m *MemoryStorage) GetScheduleRuns(ctx context.Context, uuid string, scheduleID int) ([]models.ScheduledRun, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, err := m.getSchedule(uuid, scheduleID); err != nil {
+		return nil, err
+	}
+	var result []models.ScheduledRun
+	for i := len(m.runs) - 1; i >= 0; i-- {
+		if m.runs[i].ScheduleID == scheduleID {
+			result = append(result, m.runs[i])
+		}
+	}
+	return result, nil
+}
+
+func (m *MemoryStorage) RunDueSchedules(ctx context.Context, now time.Time, limit int) ([]models.ScheduledRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var runs []models.ScheduledRun
+	for len(runs) < limit {
+		s := m.nextDueSchedule(now)
+		if s == nil {
+			break
+		}
+
+		run := models.ScheduledRun{
+			ID:          len(m.runs) + 1,
+			ScheduleID:  s.id,
+			ScheduledAt: *s.nextRunAt,
+			ExecutedAt:  time.Now(),
+		}
+		transactionID, err := m.send(s.userId, m.users[s.receiverId].Username, s.amount, s.note)
+		if err != nil {
+			run.Status = models.RunFailed
+			run.Error = runError(err)
+		} else {
+			run.Status = models.RunSucceeded
+			run.TransactionID = transactionID
+		}
+		m.runs = append(m.runs, run)
+
+		s.nextRunAt = advanceRun(s.interval, run.ScheduledAt, now)
+		s.active = s.nextRunAt != nil
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func (m *MemoryStorage) nextDueSchedule(now time.Time) *memSchedule {
+	var due *memSchedule
+	for i := range m.schedules {
+		s := &m.schedules[i]
+		if !s.active || s.cancelled || s.nextRunAt == nil || s.nextRunAt.After(now) {
+			continue
+		}
+		if due == nil || s.nextRunAt.Before(*due.nextRunAt) {
+			due = s
+		}
+	}
+	return due
+}
+
+func (m *MemoryStorage) getSchedule(uuid string, scheduleID int) (*memSchedule, error) {
+	if scheduleID <= 0 || scheduleID > len(m.schedules) || m.schedules[scheduleID-1].userId != uuid {
+		return nil, ErrScheduleNotFound
+	}
+	return &m.schedules[scheduleID-1], nil
+}
+
+func (m *MemoryStorage) scheduleModel(s memSchedule) models.ScheduledTransfer {
+	return models.ScheduledTransfer{
+		ID:        s.id,
+		ToUser:    m.users[s.receiverId].Username,
+		Amount:    s.amount,
+		Message:   s.note.Message,
+		Category:  s.note.Category,
+		Interval:  s.interval,
+		NextRunAt: copyTime(s.nextRunAt),
+		Active:    s.active,
+		CreatedAt: s.createdAt,
+	}
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
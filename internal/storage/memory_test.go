@@ -0,0 +1,1122 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func createMemoryUser(t *testing.T, m *MemoryStorage, username string, balance int) string {
+	t.Helper()
+	user := &models.User{UUID: uuid.New(), Username: username, Balance: balance}
+	assert.NoError(t, m.CreateUser(context.Background(), user))
+	return user.UUID.String()
+}
+
+func TestMemoryStorage_Send(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 1000)
+	bob := createMemoryUser(t, m, "bob", 1000)
+
+	assert.ErrorIs(t, m.Send(ctx, alice, "alice", 10, models.TransferNote{}), ErrSendingToYourself)
+	assert.ErrorIs(t, m.Send(ctx, alice, "nobody", 10, models.TransferNote{}), ErrUserNotFound)
+	assert.ErrorIs(t, m.Send(ctx, alice, "bob", 1001, models.TransferNote{}), ErrNotEnoughBalance)
+	assert.ErrorIs(t, m.Send(ctx, alice, "bob", -10, models.TransferNote{}), ErrInvalidAmount)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = m.Send(ctx, alice, "bob", 30, models.TransferNote{})
+		}()
+	}
+	wg.Wait()
+
+	aliceInfo, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	bobInfo, err := m.GetInfo(ctx, bob)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1000-33*30, aliceInfo.Coins)
+	assert.Equal(t, 2000, aliceInfo.Coins+bobInfo.Coins)
+	assert.Len(t, bobInfo.CoinsHistory.Received, 33)
+}
+
+func TestMemoryStorage_TransferNote(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 100)
+	bob := createMemoryUser(t, m, "bob", 0)
+
+	assert.NoError(t, m.Send(ctx, alice, "bob", 10, models.TransferNote{Message: "happy birthday", Category: models.CategoryBirthday}))
+	assert.NoError(t, m.Send(ctx, alice, "bob", 20, models.TransferNote{}))
+
+	info, err := m.GetInfo(ctx, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, "", info.CoinsHistory.Received[0].Message)
+	assert.Equal(t, "happy birthday", info.CoinsHistory.Received[1].Message)
+	assert.Equal(t, models.CategoryBirthday, info.CoinsHistory.Received[1].Category)
+
+	transactions, err := m.GetTransactions(ctx, alice, models.TransactionFilter{Category: models.CategoryBirthday, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, 10, transactions[0].Amount)
+}
+
+func TestMemoryStorage_Buy(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 100)
+
+	assert.ErrorIs(t, m.Buy(ctx, alice, "unknown"), ErrItemNotFound)
+	assert.ErrorIs(t, m.Buy(ctx, alice, "pink-hoody"), ErrNotEnoughBalance)
+	assert.NoError(t, m.Buy(ctx, alice, "t-shirt"))
+	assert.NoError(t, m.Buy(ctx, alice, "pen"))
+	assert.NoError(t, m.Buy(ctx, alice, "pen"))
+
+	info, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, info.Coins)
+	assert.Equal(t, []models.Item{{Type: "t-shirt", Quantity: 1}, {Type: "pen", Quantity: 2}}, info.Inventory)
+	assert.Equal(t, 100, m.accounts[AccountStore])
+	assert.Equal(t, -100, m.accounts[AccountIssuance])
+}
+
+func TestMemoryStorage_IdempotencyKeys(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 1000)
+	createMemoryUser(t, m, "bob", 1000)
+
+	// the response is stored together with the transfer, nothing else has to be saved
+	stored, err := m.ReserveIdempotencyKey(ctx, alice, "send-1", "hash")
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+	assert.NoError(t, m.Send(WithIdempotencyKey(ctx, alice, "send-1"), alice, "bob", 100, models.TransferNote{}))
+	assert.NoError(t, m.ReleaseIdempotencyKey(ctx, alice, "send-1"))
+	stored, err = m.ReserveIdempotencyKey(ctx, alice, "send-1", "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, &completedResponse, stored)
+
+	// a failed operation leaves the key reserved
+	_, err = m.ReserveIdempotencyKey(ctx, alice, "send-2", "hash")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, m.Send(WithIdempotencyKey(ctx, alice, "send-2"), alice, "bob", 5000, models.TransferNote{}), ErrNotEnoughBalance)
+	_, err = m.ReserveIdempotencyKey(ctx, alice, "send-2", "hash")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+
+	// until it is stale
+	m.idempotency[idempotencyMapKey(alice, "send-2")].createdAt = time.Now().Add(-IdempotencyKeyTimeout - time.Second)
+	stored, err = m.ReserveIdempotencyKey(ctx, alice, "send-2", "other-hash")
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+	assert.Equal(t, 900, m.accounts[WalletAccount(alice)])
+}
+
+func TestMemoryStorage_BuyLimitedStock(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	merch, err := m.GetMerch(ctx, "pink-hoody")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, *merch.Stock)
+	stock := 3
+	m.itemsByName["pink-hoody"].stock = &stock
+
+	var buyers []string
+	for i := 0; i < 10; i++ {
+		buyers = append(buyers, createMemoryUser(t, m, "user"+strconv.Itoa(i), 1000))
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	outOfStock := 0
+	for _, buyer := range buyers {
+		wg.Add(1)
+		go func(buyer string) {
+			defer wg.Done()
+			if errors.Is(m.Buy(ctx, buyer, "pink-hoody"), ErrOutOfStock) {
+				mu.Lock()
+				outOfStock++
+				mu.Unlock()
+			}
+		}(buyer)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 7, outOfStock)
+	assert.Equal(t, 3*500, m.accounts[AccountStore])
+	merch, err = m.GetMerch(ctx, "pink-hoody")
+	assert.NoError(t, err)
+	assert.False(t, merch.Available)
+}
+
+func TestMemoryStorage_CreateOrder(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 100)
+
+	_, err := m.CreateOrder(ctx, alice, nil)
+	assert.ErrorIs(t, err, ErrEmptyOrder)
+	_, err = m.CreateOrder(ctx, alice, []models.OrderLine{{Item: "pen", Quantity: 1}, {Item: "unknown", Quantity: 1}})
+	assert.ErrorIs(t, err, ErrItemNotFound)
+	_, err = m.CreateOrder(ctx, alice, []models.OrderLine{{Item: "pen", Quantity: 11}})
+	assert.ErrorIs(t, err, ErrNotEnoughBalance)
+	// a wrapped total would be negative and pass the balance check
+	_, err = m.CreateOrder(ctx, alice, []models.OrderLine{{Item: "pen", Quantity: 1844674407370955061}})
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+	createMemoryUser(t, m, "bob", 0)
+	_, err = m.SendGift(ctx, alice, "bob", []models.OrderLine{{Item: "pen", Quantity: math.MaxInt}}, "")
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+
+	order, err := m.CreateOrder(ctx, alice, []models.OrderLine{
+		{Item: "socks", Quantity: 2},
+		{Item: "pen", Quantity: 3},
+		{Item: "socks", Quantity: 1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 60, order.Total)
+	assert.Equal(t, []models.OrderLine{
+		{Item: "socks", Quantity: 3, UnitPrice: 10},
+		{Item: "pen", Quantity: 3, UnitPrice: 10},
+	}, order.Items)
+
+	info, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 40, info.Coins)
+	assert.Equal(t, []models.Item{{Type: "socks", Quantity: 3}, {Type: "pen", Quantity: 3}}, info.Inventory)
+	assert.Equal(t, 60, m.accounts[AccountStore])
+}
+
+func TestMemoryStorage_GetPurchases(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 100)
+	bob := createMemoryUser(t, m, "bob", 100)
+
+	assert.NoError(t, m.Buy(ctx, alice, "pen"))
+	assert.NoError(t, m.Buy(ctx, bob, "cup"))
+	_, err := m.CreateOrder(ctx, alice, []models.OrderLine{{Item: "cup", Quantity: 1}, {Item: "socks", Quantity: 2}})
+	assert.NoError(t, err)
+
+	purchases, err := m.GetPurchases(ctx, alice, models.PurchaseFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, purchases, 2)
+	assert.Equal(t, 40, purchases[0].Total)
+	assert.Equal(t, []models.OrderLine{{Item: "pen", Quantity: 1, UnitPrice: 10}}, purchases[1].Items)
+
+	purchases, err = m.GetPurchases(ctx, alice, models.PurchaseFilter{Item: "pen", Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, purchases, 1)
+
+	purchases, err = m.GetPurchases(ctx, alice, models.PurchaseFilter{BeforeID: purchases[0].ID, Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, purchases)
+}
+
+func TestMemoryStorage_Refund(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 100)
+	bob := createMemoryUser(t, m, "bob", 100)
+	_, err := m.RestockMerch(ctx, "", "socks", 5)
+	assert.NoError(t, err)
+
+	order, err := m.CreateOrder(ctx, alice, []models.OrderLine{{Item: "socks", Quantity: 3}, {Item: "pen", Quantity: 1}})
+	assert.NoError(t, err)
+
+	_, err = m.RefundPurchase(ctx, bob, order.ID, "socks", 1, time.Hour)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	_, err = m.RefundPurchase(ctx, alice, order.ID, "cup", 1, time.Hour)
+	assert.ErrorIs(t, err, ErrItemNotFound)
+	_, err = m.RefundPurchase(ctx, alice, order.ID, "socks", 4, time.Hour)
+	assert.ErrorIs(t, err, ErrRefundExceedsPurchase)
+	_, err = m.RefundPurchase(ctx, alice, order.ID, "socks", 1, 0)
+	assert.ErrorIs(t, err, ErrRefundWindowExpired)
+
+	refund, err := m.RefundPurchase(ctx, alice, order.ID, "socks", 2, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, refund.Amount)
+
+	// admins are not limited by the window
+	refund, err = m.AdminRefundPurchase(ctx, bob, order.ID, "pen", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, refund.Quantity)
+	_, err = m.AdminRefundPurchase(ctx, bob, order.ID, "pen", 0)
+	assert.ErrorIs(t, err, ErrRefundExceedsPurchase)
+
+	info, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 100-30+20, info.Coins)
+	assert.Equal(t, []models.Item{{Type: "socks", Quantity: 1}}, info.Inventory)
+	merch, err := m.GetMerch(ctx, "socks")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, *merch.Stock)
+
+	purchases, err := m.GetPurchases(ctx, alice, models.PurchaseFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, purchases[0].Refunds, 2)
+	assert.Equal(t, 2, purchases[0].Items[0].Refunded)
+}
+
+func TestMemoryStorage_ReverseTransaction(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 100)
+	bob := createMemoryUser(t, m, "bob", 0)
+	createMemoryUser(t, m, "carol", 0)
+
+	assert.NoError(t, m.Send(ctx, alice, "bob", 50, models.TransferNote{}))
+	assert.NoError(t, m.Send(ctx, bob, "carol", 30, models.TransferNote{}))
+
+	_, err := m.ReverseTransaction(ctx, "", 10, models.ReversalPolicyFail)
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+	_, err = m.ReverseTransaction(ctx, "", 1, models.ReversalPolicyFail)
+	assert.ErrorIs(t, err, ErrNotEnoughBalance)
+
+	reversal, err := m.ReverseTransaction(ctx, "", 1, models.ReversalPolicyPartial)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, reversal.Amount)
+	assert.Equal(t, 30, reversal.Remaining)
+	assert.Equal(t, "bob", reversal.FromUser)
+
+	_, err = m.ReverseTransaction(ctx, "", reversal.ID, models.ReversalPolicyFail)
+	assert.ErrorIs(t, err, ErrReversalOfReversal)
+
+	// the rest is reversed once bob has coins again
+	assert.NoError(t, m.Send(ctx, alice, "bob", 30, models.TransferNote{}))
+	reversal, err = m.ReverseTransaction(ctx, "", 1, models.ReversalPolicyFail)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, reversal.Amount)
+	_, err = m.ReverseTransaction(ctx, "", 1, models.ReversalPolicyPartial)
+	assert.ErrorIs(t, err, ErrAlreadyReversed)
+
+	info, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 100-50-30+20+30, info.Coins)
+	assert.Equal(t, 1, info.CoinsHistory.Received[0].ReversesID)
+
+	// allowances come from the issuer, which has no wallet to return to
+	now := time.Now().UTC()
+	_, err = m.IssueAllowances(ctx, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), 10, nil, 10)
+	assert.NoError(t, err)
+	_, err = m.ReverseTransaction(ctx, "", len(m.transactions), models.ReversalPolicyFail)
+	assert.ErrorIs(t, err, ErrSystemTransaction)
+}
+
+func TestMemoryStorage_RunDueSchedules(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 100)
+	bob := createMemoryUser(t, m, "bob", 0)
+
+	start := time.Date(2025, 5, 2, 10, 0, 0, 0, time.UTC)
+	weekly, err := m.CreateSchedule(ctx, alice, models.ScheduledTransfer{ToUser: "bob", Amount: 40, Message: "rent", Interval: models.IntervalWeekly, NextRunAt: &start})
+	assert.NoError(t, err)
+	later := start.Add(time.Hour)
+	once, err := m.CreateSchedule(ctx, alice, models.ScheduledTransfer{ToUser: "bob", Amount: 10, Interval: models.IntervalOnce, NextRunAt: &later})
+	assert.NoError(t, err)
+	_, err = m.CreateSchedule(ctx, alice, models.ScheduledTransfer{ToUser: "alice", Amount: 10, Interval: models.IntervalOnce, NextRunAt: &start})
+	assert.ErrorIs(t, err, ErrSendingToYourself)
+
+	runs, err := m.RunDueSchedules(ctx, start.Add(30*time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+
+	// missed weeks are run once
+	runs, err = m.RunDueSchedules(ctx, start.AddDate(0, 0, 15), 10)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+
+	schedules, err := m.GetSchedules(ctx, alice)
+	assert.NoError(t, err)
+	assert.True(t, schedules[0].NextRunAt.Equal(start.AddDate(0, 0, 21)))
+	assert.Nil(t, schedules[1].NextRunAt)
+	assert.False(t, schedules[1].Active)
+
+	// the third weekly run has no coins left
+	runs, err = m.RunDueSchedules(ctx, start.AddDate(0, 0, 21), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RunFailed, runs[0].Status)
+	assert.Equal(t, ErrNotEnoughBalance.Error(), runs[0].Error)
+
+	history, err := m.GetScheduleRuns(ctx, alice, weekly.ID)
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	_, err = m.GetScheduleRuns(ctx, bob, once.ID)
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+
+	assert.NoError(t, m.CancelSchedule(ctx, alice, weekly.ID))
+	runs, err = m.RunDueSchedules(ctx, start.AddDate(1, 0, 0), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, runs)
+
+	info, err := m.GetInfo(ctx, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, 90, info.Coins)
+	assert.Equal(t, "rent", info.CoinsHistory.Received[len(info.CoinsHistory.Received)-1].Message)
+}
+
+func TestMemoryStorage_PendingTransfers(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 100)
+	bob := createMemoryUser(t, m, "bob", 0)
+
+	offer, err := m.CreatePendingTransfer(ctx, alice, "bob", 70, models.TransferNote{Message: "lunch", Category: models.CategoryThanks}, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PendingStatusPending, offer.Status)
+
+	// held coins can not be spent
+	assert.ErrorIs(t, m.Send(ctx, alice, "bob", 40, models.TransferNote{}), ErrNotEnoughBalance)
+	_, err = m.CreatePendingTransfer(ctx, alice, "bob", 40, models.TransferNote{}, time.Hour)
+	assert.ErrorIs(t, err, ErrNotEnoughBalance)
+
+	_, err = m.AcceptPendingTransfer(ctx, alice, offer.ID)
+	assert.ErrorIs(t, err, ErrPendingTransferNotFound)
+	accepted, err := m.AcceptPendingTransfer(ctx, bob, offer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PendingStatusAccepted, accepted.Status)
+	assert.NotZero(t, accepted.TransactionID)
+	assert.Equal(t, "lunch", accepted.Message)
+	_, err = m.DeclinePendingTransfer(ctx, bob, offer.ID)
+	assert.ErrorIs(t, err, ErrPendingTransferClosed)
+
+	declined, err := m.CreatePendingTransfer(ctx, alice, "bob", 10, models.TransferNote{}, time.Hour)
+	assert.NoError(t, err)
+	_, err = m.DeclinePendingTransfer(ctx, bob, declined.ID)
+	assert.NoError(t, err)
+
+	lapsed, err := m.CreatePendingTransfer(ctx, alice, "bob", 30, models.TransferNote{}, time.Minute)
+	assert.NoError(t, err)
+	expired, err := m.ExpirePendingTransfers(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+	expired, err = m.ExpirePendingTransfers(ctx, time.Now().Add(time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Len(t, expired, 1)
+	assert.Equal(t, lapsed.ID, expired[0].ID)
+	assert.Equal(t, models.PendingStatusExpired, expired[0].Status)
+
+	info, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, info.Coins)
+	info, err = m.GetInfo(ctx, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, 70, info.Coins)
+	assert.Equal(t, models.CategoryThanks, info.CoinsHistory.Received[0].Category)
+	assert.Equal(t, "lunch", info.CoinsHistory.Received[0].Message)
+	assert.Equal(t, 0, m.accounts[AccountEscrow])
+
+	transfers, err := m.GetPendingTransfers(ctx, bob)
+	assert.NoError(t, err)
+	assert.Len(t, transfers, 3)
+}
+
+func TestMemoryStorage_ScheduledRunOverLimit(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 100)
+	createMemoryUser(t, m, "bob", 0)
+	limit := 30
+	_, err := m.SetSendLimit(ctx, "", models.SendLimit{Scope: models.LimitScopeGlobal, PerTransfer: &limit})
+	assert.NoError(t, err)
+
+	start := time.Date(2025, 5, 2, 10, 0, 0, 0, time.UTC)
+	_, err = m.CreateSchedule(ctx, alice, models.ScheduledTransfer{ToUser: "bob", Amount: 40, Interval: models.IntervalOnce, NextRunAt: &start})
+	assert.NoError(t, err)
+
+	// the run tells which limit stopped it
+	runs, err := m.RunDueSchedules(ctx, start, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RunFailed, runs[0].Status)
+	assert.Equal(t, (&LimitError{Limit: models.LimitPerTransfer, Remaining: 30}).Error(), runs[0].Error)
+}
+
+func TestMemoryStorage_SendLimits(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 1000)
+	bob := createMemoryUser(t, m, "bob", 1000)
+	createMemoryUser(t, m, "carol", 0)
+	limit := func(v int) *int { return &v }
+
+	_, err := m.SetSendLimit(ctx, "", models.SendLimit{Scope: models.LimitScopeGlobal, PerTransfer: limit(100), Daily: limit(150)})
+	assert.NoError(t, err)
+	_, err = m.SetSendLimit(ctx, "", models.SendLimit{Scope: models.LimitScopeUser, Subject: "bob", Daily: limit(0)})
+	assert.NoError(t, err)
+	_, err = m.SetSendLimit(ctx, "", models.SendLimit{Scope: models.LimitScopeUser, Subject: "nobody", Daily: limit(0)})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	var limitErr *LimitError
+	err = m.Send(ctx, alice, "carol", 120, models.TransferNote{})
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, models.LimitPerTransfer, limitErr.Limit)
+	assert.Equal(t, 100, limitErr.Remaining)
+
+	assert.NoError(t, m.Send(ctx, alice, "carol", 100, models.TransferNote{}))
+	sentID := len(m.transactions)
+	_, err = m.CreatePendingTransfer(ctx, alice, "carol", 40, models.TransferNote{}, time.Hour)
+	assert.NoError(t, err)
+	err = m.Send(ctx, alice, "carol", 20, models.TransferNote{})
+	assert.ErrorIs(t, err, ErrSendLimitExceeded)
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, models.LimitDaily, limitErr.Limit)
+	assert.Equal(t, 10, limitErr.Remaining)
+
+	// the reversed part of a transfer is given back to the limits
+	_, err = m.ReverseTransaction(ctx, "", sentID, models.ReversalPolicyFail)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Send(ctx, alice, "carol", 100, models.TransferNote{}))
+	err = m.Send(ctx, alice, "carol", 20, models.TransferNote{})
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, 10, limitErr.Remaining)
+
+	// bob has no daily limit but keeps the global per transfer one
+	assert.NoError(t, m.Send(ctx, bob, "carol", 100, models.TransferNote{}))
+	assert.NoError(t, m.Send(ctx, bob, "carol", 100, models.TransferNote{}))
+	assert.ErrorIs(t, m.Send(ctx, bob, "carol", 101, models.TransferNote{}), ErrSendLimitExceeded)
+
+	limits, err := m.GetSendLimits(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, limits, 2)
+	assert.Equal(t, "bob", limits[1].Subject)
+
+	assert.NoError(t, m.DeleteSendLimit(ctx, models.LimitScopeGlobal, ""))
+	assert.ErrorIs(t, m.DeleteSendLimit(ctx, models.LimitScopeGlobal, ""), ErrSendLimitNotFound)
+	assert.NoError(t, m.Send(ctx, alice, "carol", 500, models.TransferNote{}))
+}
+
+func TestMemoryStorage_IssueAllowances(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 0)
+	createMemoryUser(t, m, "bob", 0)
+	now := time.Now().UTC()
+	period := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	last, err := m.ResumeAllowancePeriod(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, last)
+
+	issued, err := m.IssueAllowances(ctx, period, 100, nil, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, issued)
+	issued, err = m.IssueAllowances(ctx, period, 100, nil, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, issued)
+	issued, err = m.IssueAllowances(ctx, period, 100, nil, 10)
+	assert.NoError(t, err)
+	assert.Zero(t, issued)
+
+	// users registered later don't get past allowances
+	issued, err = m.IssueAllowances(ctx, period.AddDate(0, -2, 0), 100, nil, 10)
+	assert.NoError(t, err)
+	assert.Zero(t, issued)
+
+	last, err = m.ResumeAllowancePeriod(ctx)
+	assert.NoError(t, err)
+	assert.True(t, period.Equal(*last))
+
+	info, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, info.Coins)
+	assert.Equal(t, IssuerUsername, info.CoinsHistory.Received[0].FromUser)
+	assert.ErrorIs(t, m.Send(ctx, alice, IssuerUsername, 10, models.TransferNote{}), ErrUserNotFound)
+
+	// a user skipped in an earlier month is paid before later months,
+	// forget the second issuance as if that user had been skipped
+	m.allowances = m.allowances[:1]
+	issued, err = m.IssueAllowances(ctx, period.AddDate(0, 1, 0), 100, nil, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, issued)
+	last, err = m.ResumeAllowancePeriod(ctx)
+	assert.NoError(t, err)
+	assert.True(t, period.Equal(*last))
+	issued, err = m.IssueAllowances(ctx, *last, 100, nil, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, issued)
+	last, err = m.ResumeAllowancePeriod(ctx)
+	assert.NoError(t, err)
+	assert.True(t, period.AddDate(0, 1, 0).Equal(*last))
+}
+
+func TestMemoryStorage_CoinLots(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 100)
+	createMemoryUser(t, m, "bob", 0)
+	now := time.Now().UTC()
+	period := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	soon := now.Add(time.Hour)
+	later := now.Add(48 * time.Hour)
+
+	_, err := m.IssueAllowances(ctx, period, 50, &later, 10)
+	assert.NoError(t, err)
+	_, err = m.IssueAllowances(ctx, period.AddDate(0, 1, 0), 30, &soon, 10)
+	assert.NoError(t, err)
+
+	info, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 180, info.Coins)
+	assert.Equal(t, []models.CoinLot{{Amount: 30, ExpiresAt: soon}, {Amount: 50, ExpiresAt: later}}, info.Expiring)
+
+	// the signup coins are the oldest and go first
+	assert.NoError(t, m.Send(ctx, alice, "bob", 120, models.TransferNote{}))
+	info, err = m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CoinLot{{Amount: 30, ExpiresAt: soon}, {Amount: 30, ExpiresAt: later}}, info.Expiring)
+
+	expired, err := m.ExpireCoins(ctx, now, 10)
+	assert.NoError(t, err)
+	assert.Zero(t, expired)
+	// both users lose the lot that lapsed
+	expired, err = m.ExpireCoins(ctx, soon, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, expired)
+
+	info, err = m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, info.Coins)
+	assert.Len(t, info.Expiring, 1)
+	assert.Equal(t, IssuerUsername, info.CoinsHistory.Sent[0].ToUser)
+	assert.Equal(t, 30, info.CoinsHistory.Sent[0].Amount)
+}
+
+func TestMemoryStorage_ReturnedCoinsKeepExpiry(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 0)
+	now := time.Now().UTC()
+	soon := now.Add(time.Hour)
+	_, err := m.IssueAllowances(ctx, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), 300, &soon, 10)
+	assert.NoError(t, err)
+	bob := createMemoryUser(t, m, "bob", 0)
+
+	// a refund gives back the expiring coins the purchase took
+	order, err := m.CreateOrder(ctx, alice, []models.OrderLine{{Item: "hoody", Quantity: 1}})
+	assert.NoError(t, err)
+	_, err = m.RefundPurchase(ctx, alice, order.ID, "hoody", 0, time.Hour)
+	assert.NoError(t, err)
+	info, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CoinLot{{Amount: 300, ExpiresAt: soon}}, info.Expiring)
+
+	// so does a declined pending transfer
+	pending, err := m.CreatePendingTransfer(ctx, alice, "bob", 100, models.TransferNote{}, time.Hour)
+	assert.NoError(t, err)
+	_, err = m.DeclinePendingTransfer(ctx, bob, pending.ID)
+	assert.NoError(t, err)
+
+	// and a reversed transfer that was accepted from a pending offer
+	pending, err = m.CreatePendingTransfer(ctx, alice, "bob", 100, models.TransferNote{}, time.Hour)
+	assert.NoError(t, err)
+	pending, err = m.AcceptPendingTransfer(ctx, bob, pending.ID)
+	assert.NoError(t, err)
+	_, err = m.ReverseTransaction(ctx, "", pending.TransactionID, models.ReversalPolicyFail)
+	assert.NoError(t, err)
+
+	expired, err := m.ExpireCoins(ctx, soon, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	info, err = m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Zero(t, info.Coins)
+	assert.Empty(t, info.Expiring)
+}
+
+func TestMemoryStorage_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 1000)
+	bob := createMemoryUser(t, m, "bob", 1000)
+	carol := createMemoryUser(t, m, "carol", 1000)
+	now := time.Now().UTC()
+	period := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, m.Send(ctx, alice, "bob", 100, models.TransferNote{}))
+	_, err := m.CreatePendingTransfer(ctx, bob, "carol", 50, models.TransferNote{}, time.Hour)
+	assert.NoError(t, err)
+	_, err = m.IssueAllowances(ctx, period, 20, nil, 10)
+	assert.NoError(t, err)
+
+	report, err := m.Reconcile(ctx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Wallets)
+	assert.Empty(t, report.Discrepancies)
+	assert.Empty(t, report.UnbalancedEntries)
+
+	m.accounts[WalletAccount(alice)] -= 5
+	m.transactions = append(m.transactions, memTransaction{id: len(m.transactions) + 1, senderId: IssuerUUID, receiverId: bob, amount: 10})
+	for i := range m.lots {
+		if m.lots[i].userId == carol {
+			m.lots[i].remaining--
+			break
+		}
+	}
+
+	report, err = m.Reconcile(ctx, false)
+	assert.NoError(t, err)
+	assert.Zero(t, report.RunID)
+	assert.Equal(t, []models.Discrepancy{
+		{User: alice, Check: models.CheckLedger, Expected: 920, Actual: 915},
+		{User: alice, Check: models.CheckLots, Expected: 915, Actual: 920},
+		{User: bob, Check: models.CheckActivity, Expected: 1080, Actual: 1070},
+		{User: carol, Check: models.CheckLots, Expected: 1020, Actual: 1019},
+	}, report.Discrepancies)
+
+	report, err = m.Reconcile(ctx, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.RunID)
+	assert.Len(t, report.Discrepancies, 4)
+	for _, d := range report.Discrepancies {
+		assert.True(t, d.Repaired)
+	}
+	assert.Len(t, m.adjustments, 3)
+
+	report, err = m.Reconcile(ctx, false)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Discrepancies)
+
+	info, err := m.GetInfo(ctx, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, 1080, info.Coins)
+}
+
+func TestMemoryStorage_ExportActivity(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 1000)
+	createMemoryUser(t, m, "bob", 1000)
+
+	assert.NoError(t, m.Send(ctx, alice, "bob", 30, models.TransferNote{Category: models.CategoryHelp}))
+	order, err := m.CreateOrder(ctx, alice, []models.OrderLine{{Item: "cup", Quantity: 2}, {Item: "pen", Quantity: 1}})
+	assert.NoError(t, err)
+	_, err = m.RefundPurchase(ctx, alice, order.ID, "cup", 1, time.Hour)
+	assert.NoError(t, err)
+
+	collect := func(uuid string, filter models.ExportFilter) []models.ActivityRecord {
+		var result []models.ActivityRecord
+		err := m.ExportActivity(ctx, uuid, filter, func(rec models.ActivityRecord) error {
+			result = append(result, rec)
+			return nil
+		})
+		assert.NoError(t, err)
+		return result
+	}
+
+	records := collect(alice, models.ExportFilter{})
+	assert.Len(t, records, 4)
+	assert.Equal(t, models.ActivityTransfer, records[0].Type)
+	assert.Equal(t, models.CategoryHelp, records[0].Category)
+	assert.Equal(t, models.ActivityPurchase, records[1].Type)
+	assert.Equal(t, 40, records[1].Amount)
+	assert.Equal(t, models.ActivityRefund, records[3].Type)
+	assert.Equal(t, "alice", records[3].To)
+
+	assert.Len(t, collect("", models.ExportFilter{}), 4)
+	future := time.Now().Add(time.Hour)
+	assert.Empty(t, collect("", models.ExportFilter{From: &future}))
+
+	stop := errors.New("stop")
+	calls := 0
+	err = m.ExportActivity(ctx, alice, models.ExportFilter{}, func(rec models.ActivityRecord) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestMemoryStorage_GetCounterparties(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 1000)
+	bob := createMemoryUser(t, m, "bob", 1000)
+	createMemoryUser(t, m, "carol", 1000)
+
+	assert.NoError(t, m.Send(ctx, alice, "bob", 30, models.TransferNote{}))
+	assert.NoError(t, m.Send(ctx, alice, "bob", 20, models.TransferNote{}))
+	assert.NoError(t, m.Send(ctx, alice, "carol", 80, models.TransferNote{}))
+	assert.NoError(t, m.Send(ctx, bob, "alice", 5, models.TransferNote{}))
+
+	result, err := m.GetCounterparties(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CounterpartyTotal{{User: "carol", Amount: 80, Count: 1}, {User: "bob", Amount: 50, Count: 2}}, result.Sent)
+	assert.Equal(t, []models.CounterpartyTotal{{User: "bob", Amount: 5, Count: 1}}, result.Received)
+
+	result, err = m.GetCounterparties(ctx, createMemoryUser(t, m, "dave", 1000))
+	assert.NoError(t, err)
+	assert.Empty(t, result.Sent)
+	assert.NotNil(t, result.Sent)
+}
+
+func TestMemoryStorage_SendBulk(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 100)
+	bob := createMemoryUser(t, m, "bob", 0)
+	createMemoryUser(t, m, "carol", 0)
+
+	_, err := m.SendBulk(ctx, alice, []models.BulkTransfer{
+		{ToUser: "bob", Amount: 10},
+		{ToUser: "alice", Amount: 10},
+		{ToUser: "nobody", Amount: 10},
+		{ToUser: "bob", Amount: 10},
+		{ToUser: "carol", Amount: 0},
+	}, models.TransferNote{})
+	var recipientsErr *RecipientsError
+	assert.ErrorAs(t, err, &recipientsErr)
+	assert.Equal(t, []models.RecipientError{
+		{Index: 1, ToUser: "alice", Error: ErrSendingToYourself.Error()},
+		{Index: 2, ToUser: "nobody", Error: ErrUserNotFound.Error()},
+		{Index: 3, ToUser: "bob", Error: ErrDuplicateRecipient.Error()},
+		{Index: 4, ToUser: "carol", Error: ErrInvalidAmount.Error()},
+	}, recipientsErr.Recipients)
+
+	_, err = m.SendBulk(ctx, alice, []models.BulkTransfer{{ToUser: "bob", Amount: 60}, {ToUser: "carol", Amount: 60}}, models.TransferNote{})
+	assert.ErrorIs(t, err, ErrNotEnoughBalance)
+
+	// the second transfer goes over the daily limit, so nothing is sent
+	daily := 50
+	_, err = m.SetSendLimit(ctx, alice, models.SendLimit{Scope: models.LimitScopeGlobal, Daily: &daily})
+	assert.NoError(t, err)
+	_, err = m.SendBulk(ctx, alice, []models.BulkTransfer{{ToUser: "bob", Amount: 30}, {ToUser: "carol", Amount: 30}}, models.TransferNote{})
+	assert.ErrorIs(t, err, ErrSendLimitExceeded)
+
+	info, err := m.GetInfo(ctx, bob)
+	assert.NoError(t, err)
+	assert.Zero(t, info.Coins)
+
+	sent, err := m.SendBulk(ctx, alice, []models.BulkTransfer{{ToUser: "bob", Amount: 30}, {ToUser: "carol", Amount: 20}}, models.TransferNote{Message: "sprint"})
+	assert.NoError(t, err)
+	assert.Len(t, sent, 2)
+	assert.NotZero(t, sent[1].TransactionID)
+
+	info, err = m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 50, info.Coins)
+	assert.Equal(t, "sprint", info.CoinsHistory.Sent[0].Message)
+}
+
+func TestMemoryStorage_PaymentRequests(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 0)
+	bob := createMemoryUser(t, m, "bob", 100)
+	carol := createMemoryUser(t, m, "carol", 10)
+	dave := createMemoryUser(t, m, "dave", 100)
+
+	_, err := m.CreatePaymentRequest(ctx, alice, []string{"bob", "alice", "nobody"}, 30, models.TransferNote{})
+	var recipientsErr *RecipientsError
+	assert.ErrorAs(t, err, &recipientsErr)
+	assert.Len(t, recipientsErr.Recipients, 2)
+
+	request, err := m.CreatePaymentRequest(ctx, alice, []string{"bob", "carol"}, 30, models.TransferNote{Message: "pizza", Category: "thanks"})
+	assert.NoError(t, err)
+	assert.Len(t, request.Payers, 2)
+
+	// only payers can answer
+	_, err = m.PayPaymentRequest(ctx, dave, request.ID)
+	assert.ErrorIs(t, err, ErrPaymentRequestNotFound)
+	_, err = m.PayPaymentRequest(ctx, carol, request.ID)
+	assert.ErrorIs(t, err, ErrNotEnoughBalance)
+
+	paid, err := m.PayPaymentRequest(ctx, bob, request.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Payer{{User: "bob", Status: models.PaymentStatusPaid, ResolvedAt: paid.Payers[0].ResolvedAt, TransactionID: paid.Payers[0].TransactionID}}, paid.Payers)
+	assert.NotZero(t, paid.Payers[0].TransactionID)
+	_, err = m.RejectPaymentRequest(ctx, bob, request.ID)
+	assert.ErrorIs(t, err, ErrPaymentRequestAnswered)
+
+	_, err = m.RejectPaymentRequest(ctx, carol, request.ID)
+	assert.NoError(t, err)
+
+	requests, err := m.GetPaymentRequests(ctx, alice)
+	assert.NoError(t, err)
+	assert.Len(t, requests, 1)
+	assert.Equal(t, models.PaymentStatusPaid, requests[0].Payers[0].Status)
+	assert.Equal(t, models.PaymentStatusRejected, requests[0].Payers[1].Status)
+	requests, err = m.GetPaymentRequests(ctx, dave)
+	assert.NoError(t, err)
+	assert.Empty(t, requests)
+
+	info, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, info.Coins)
+	assert.Equal(t, "pizza", info.CoinsHistory.Received[0].Message)
+}
+
+func TestMemoryStorage_SendGift(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 1000)
+	bob := createMemoryUser(t, m, "bob", 0)
+
+	_, err := m.SendGift(ctx, alice, "alice", []models.OrderLine{{Item: "hoody", Quantity: 1}}, "")
+	assert.ErrorIs(t, err, ErrGiftToYourself)
+	_, err = m.SendGift(ctx, alice, "nobody", []models.OrderLine{{Item: "hoody", Quantity: 1}}, "")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	order, err := m.SendGift(ctx, alice, "bob", []models.OrderLine{{Item: "hoody", Quantity: 1}, {Item: "pen", Quantity: 2}}, "welcome")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", order.ToUser)
+	assert.Equal(t, 320, order.Total)
+
+	info, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 680, info.Coins)
+	assert.Empty(t, info.Inventory)
+	assert.Len(t, info.GiftHistory.Sent, 1)
+	assert.Empty(t, info.GiftHistory.Received)
+
+	info, err = m.GetInfo(ctx, bob)
+	assert.NoError(t, err)
+	assert.Zero(t, info.Coins)
+	assert.Equal(t, []models.Item{{Type: "hoody", Quantity: 1}, {Type: "pen", Quantity: 2}}, info.Inventory)
+	assert.Equal(t, []models.Gift{{
+		OrderID:   order.ID,
+		FromUser:  "alice",
+		ToUser:    "bob",
+		Items:     []models.Item{{Type: "hoody", Quantity: 1}, {Type: "pen", Quantity: 2}},
+		Message:   "welcome",
+		CreatedAt: order.CreatedAt,
+	}}, info.GiftHistory.Received)
+
+	// the buyer gets the refund, the recipient gives the item back
+	_, err = m.RefundPurchase(ctx, bob, order.ID, "pen", 1, time.Hour)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	refund, err := m.RefundPurchase(ctx, alice, order.ID, "pen", 1, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", refund.ToUser)
+	info, err = m.GetInfo(ctx, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, info.Inventory[1].Quantity)
+	info, err = m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 690, info.Coins)
+
+	// the recipient exports the gift but not the refund paid to the buyer
+	var records []models.ActivityRecord
+	err = m.ExportActivity(ctx, bob, models.ExportFilter{}, func(rec models.ActivityRecord) error {
+		records = append(records, rec)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, models.ActivityPurchase, records[0].Type)
+	assert.Equal(t, "bob", records[0].To)
+
+	// the info keeps only the latest gifts
+	for i := 0; i < GiftHistoryLimit; i++ {
+		_, err = m.SendGift(ctx, alice, "bob", []models.OrderLine{{Item: "pen", Quantity: 1}}, "")
+		assert.NoError(t, err)
+	}
+	info, err = m.GetInfo(ctx, bob)
+	assert.NoError(t, err)
+	assert.Len(t, info.GiftHistory.Received, GiftHistoryLimit)
+	assert.NotEqual(t, order.ID, info.GiftHistory.Received[GiftHistoryLimit-1].OrderID)
+}
+
+func TestMemoryStorage_Listings(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 1000)
+	bob := createMemoryUser(t, m, "bob", 100)
+	carol := createMemoryUser(t, m, "carol", 1000)
+
+	_, err := m.CreateOrder(ctx, alice, []models.OrderLine{{Item: "pen", Quantity: 3}})
+	assert.NoError(t, err)
+
+	_, err = m.CreateListing(ctx, alice, "pen", 4, 50)
+	assert.ErrorIs(t, err, ErrNotInInventory)
+	listing, err := m.CreateListing(ctx, alice, "pen", 2, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ListingStatusActive, listing.Status)
+
+	// listed items are locked
+	_, err = m.CreateListing(ctx, alice, "pen", 2, 50)
+	assert.ErrorIs(t, err, ErrNotInInventory)
+	info, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Item{{Type: "pen", Quantity: 1}}, info.Inventory)
+
+	_, err = m.BuyListing(ctx, alice, listing.ID)
+	assert.ErrorIs(t, err, ErrBuyingOwnListing)
+	_, err = m.BuyListing(ctx, bob, listing.ID)
+	assert.NoError(t, err)
+	_, err = m.BuyListing(ctx, carol, listing.ID)
+	assert.ErrorIs(t, err, ErrListingClosed)
+	_, err = m.CancelListing(ctx, alice, listing.ID)
+	assert.ErrorIs(t, err, ErrListingClosed)
+
+	info, err = m.GetInfo(ctx, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, 50, info.Coins)
+	assert.Equal(t, []models.Item{{Type: "pen", Quantity: 2}}, info.Inventory)
+	info, err = m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000-30+50, info.Coins)
+
+	cancelled, err := m.CreateListing(ctx, alice, "pen", 1, 10)
+	assert.NoError(t, err)
+	_, err = m.CancelListing(ctx, carol, cancelled.ID)
+	assert.ErrorIs(t, err, ErrListingNotFound)
+	_, err = m.CancelListing(ctx, alice, cancelled.ID)
+	assert.NoError(t, err)
+	info, err = m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Item{{Type: "pen", Quantity: 1}}, info.Inventory)
+
+	active, err := m.GetListings(ctx, carol, models.ListingFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, active)
+	mine, err := m.GetListings(ctx, alice, models.ListingFilter{Mine: true})
+	assert.NoError(t, err)
+	assert.Len(t, mine, 2)
+	assert.Equal(t, models.ListingStatusCancelled, mine[0].Status)
+	assert.Equal(t, "bob", mine[1].Buyer)
+
+	// the payment is a transfer, so it is subject to the buyer's sending limits
+	limit := 5
+	_, err = m.SetSendLimit(ctx, "", models.SendLimit{Scope: models.LimitScopeUser, Subject: "carol", PerTransfer: &limit})
+	assert.NoError(t, err)
+	limited, err := m.CreateListing(ctx, alice, "pen", 1, 10)
+	assert.NoError(t, err)
+	var limitErr *LimitError
+	_, err = m.BuyListing(ctx, carol, limited.ID)
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, models.LimitPerTransfer, limitErr.Limit)
+	_, err = m.CancelListing(ctx, alice, limited.ID)
+	assert.NoError(t, err)
+}
+
+func TestMemoryStorage_Auctions(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	admin := createMemoryUser(t, m, "admin", 0)
+	alice := createMemoryUser(t, m, "alice", 1000)
+	bob := createMemoryUser(t, m, "bob", 1000)
+	stock := 1
+	m.itemsByName["pink-hoody"].stock = &stock
+
+	_, err := m.CreateAuction(ctx, admin, "pink-hoody", 100, time.Now().Add(-time.Minute))
+	assert.ErrorIs(t, err, ErrAuctionEndInPast)
+	auction, err := m.CreateAuction(ctx, admin, "pink-hoody", 100, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, *m.itemsByName["pink-hoody"].stock)
+	_, err = m.CreateAuction(ctx, admin, "pink-hoody", 100, time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, ErrOutOfStock)
+
+	_, err = m.PlaceBid(ctx, alice, auction.ID, 50)
+	assert.ErrorIs(t, err, ErrBidTooLow)
+	_, err = m.PlaceBid(ctx, alice, auction.ID, 200)
+	assert.NoError(t, err)
+	// a tie keeps the earlier bid on top
+	_, err = m.PlaceBid(ctx, bob, auction.ID, 200)
+	assert.ErrorIs(t, err, ErrBidTooLow)
+	_, err = m.PlaceBid(ctx, bob, auction.ID, 1001)
+	assert.ErrorIs(t, err, ErrNotEnoughBalance)
+	_, err = m.PlaceBid(ctx, bob, auction.ID, 300)
+	assert.NoError(t, err)
+
+	// alice is released, raising her own bid only needs the difference
+	info, err := m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, info.Coins)
+	_, err = m.PlaceBid(ctx, alice, auction.ID, 400)
+	assert.NoError(t, err)
+	updated, err := m.PlaceBid(ctx, alice, auction.ID, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", updated.TopBid.User)
+	assert.Len(t, updated.Bids, 4)
+	info, err = m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Zero(t, info.Coins)
+	assert.Equal(t, 1000, m.accounts[AccountEscrow])
+
+	// a bid after the end fails even before the auction is closed
+	m.auctions[auction.ID-1].endsAt = time.Now()
+	_, err = m.PlaceBid(ctx, bob, auction.ID, 1000)
+	assert.ErrorIs(t, err, ErrAuctionClosed)
+
+	unsold, err := m.CreateAuction(ctx, admin, "pen", 5, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	closed, err := m.CloseAuctions(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, closed, 1)
+	assert.Equal(t, models.AuctionStatusSold, closed[0].Status)
+	assert.Equal(t, "alice", closed[0].Winner)
+	assert.NotZero(t, closed[0].OrderID)
+
+	closed, err = m.CloseAuctions(ctx, time.Now().Add(time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Len(t, closed, 1)
+	assert.Equal(t, unsold.ID, closed[0].ID)
+	assert.Equal(t, models.AuctionStatusUnsold, closed[0].Status)
+
+	info, err = m.GetInfo(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Item{{Type: "pink-hoody", Quantity: 1}}, info.Inventory)
+	info, err = m.GetInfo(ctx, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, info.Coins)
+	assert.Zero(t, m.accounts[AccountEscrow])
+
+	purchases, err := m.GetPurchases(ctx, alice, models.PurchaseFilter{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, purchases[0].Total)
+
+	// the win is final, neither alice nor an admin can send it back to the store
+	_, err = m.RefundPurchase(ctx, alice, purchases[0].ID, "pink-hoody", 1, time.Hour)
+	assert.ErrorIs(t, err, ErrAuctionWinNotRefundable)
+	_, err = m.AdminRefundPurchase(ctx, admin, purchases[0].ID, "pink-hoody", 1)
+	assert.ErrorIs(t, err, ErrAuctionWinNotRefundable)
+
+	report, err := m.Reconcile(ctx, false)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Discrepancies)
+}
+
+func TestMemoryStorage_CloseAuctionsSkipsFailures(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	admin := createMemoryUser(t, m, "admin", 0)
+	alice := createMemoryUser(t, m, "alice", 0)
+
+	broken, err := m.CreateAuction(ctx, admin, "pen", 5, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	next, err := m.CreateAuction(ctx, admin, "pen", 5, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	// a held bid of nothing can't be paid from escrow
+	m.auctions[broken.ID-1].bids = []memBid{{id: 1, userId: alice, status: models.BidStatusHeld}}
+
+	closed, err := m.CloseAuctions(ctx, time.Now().Add(time.Hour), 10)
+	assert.ErrorIs(t, err, ErrUnbalancedEntry)
+	assert.Len(t, closed, 1)
+	assert.Equal(t, next.ID, closed[0].ID)
+	assert.Equal(t, models.AuctionStatusOpen, m.auctions[broken.ID-1].status)
+}
+
+func TestMemoryStorage_SetUserRole(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStorage()
+	alice := createMemoryUser(t, m, "alice", 0)
+
+	assert.NoError(t, m.SetUserRole(ctx, alice, models.RoleAdmin))
+	role, err := m.GetUserRole(ctx, alice)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, role)
+
+	assert.ErrorIs(t, m.SetUserRole(ctx, IssuerUUID, models.RoleAdmin), ErrUserNotFound)
+	assert.ErrorIs(t, m.SetUserRole(ctx, uuid.NewString(), models.RoleAdmin), ErrUserNotFound)
+}
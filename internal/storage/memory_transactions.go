@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"sort"
+)
+
+func (m *MemoryStorage) GetTransactions(ctx context.Context, uuid string, filter models.TransactionFilter) ([]models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.Transaction
+	for i := len(m.transactions) - 1; i >= 0 && len(result) < filter.Limit; i-- {
+		t := m.transactions[i]
+		if filter.BeforeID > 0 && t.id >= filter.BeforeID {
+			continue
+		}
+
+		var counterparty string
+		switch {
+		case t.senderId == uuid && filter.Direction != models.DirectionReceived:
+			counterparty = m.users[t.receiverId].Username
+		case t.receiverId == uuid && filter.Direction != models.DirectionSent:
+			counterparty = m.users[t.senderId].Username
+		default:
+			continue
+		}
+		if filter.Counterparty != "" && counterparty != filter.Counterparty {
+			continue
+		}
+		if filter.Category != "" && t.category != filter.Category {
+			continue
+		}
+		if filter.MinAmount != nil && t.amount < *filter.MinAmount {
+			continue
+		}
+		if filter.MaxAmount != nil && t.amount > *filter.MaxAmount {
+			continue
+		}
+		if filter.From != nil && t.createdAt.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && !t.createdAt.Before(*filter.To) {
+			continue
+		}
+		result = append(result, m.transactionModel(t))
+	}
+	return result, nil
+}
+
+func (m *MemoryStorage) GetCounterparties(ctx context.Context, uuid string) (*models.Counterparties, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	received := make(map[string]*models.CounterpartyTotal)
+	sent := make(map[string]*models.CounterpartyTotal)
+	add := func(totals map[string]*models.CounterpartyTotal, counterparty string, amount int) {
+		username := m.users[counterparty].Username
+		total, ok := totals[username]
+		if !ok {
+			total = &models.CounterpartyTotal{User: username}
+			totals[username] = total
+		}
+		total.Amount += amount
+		total.Count++
+	}
+	for _, t := range m.transactions {
+		if t.receiverId == uuid {
+			add(received, t.senderId, t.amount)
+		}
+		if t.senderId == uuid {
+			add(sent, t.receiverId, t.amount)
+		}
+	}
+
+	return &models.Counterparties{
+		Received: sortedTotals(received),
+		Sent:     sortedTotals(sent),
+	}, nil
+}
+
+func sortedTotals(totals map[string]*models.CounterpartyTotal) []models.CounterpartyTotal {
+	result := make([]models.CounterpartyTotal, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Amount != result[j].Amount {
+			return result[i].Amount > result[j].Amount
+		}
+		return result[i].User < result[j].User
+	})
+	return result
+}
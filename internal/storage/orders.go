@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// maxOrderTotal is the largest total the orders table can hold
+const maxOrderTotal = math.MaxInt32
+
+// CreateOrder buys all lines of the cart in one transaction: either every
+// item is paid for and added to the inventory or nothing changes.
+func (db *DataBase) CreateOrder(ctx context.Context, uuid string, lines []models.OrderLine) (*models.Order, error) {
+	lines, err := mergeOrderLines(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	var order *models.Order
+	err = db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		var err error
+		order, err = db.placeOrderTx(ctx, tx, uuid, lines)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (db *DataBase) placeOrderTx(ctx context.Context, tx *sql.Tx, uuid string, lines []models.OrderLine) (*models.Order, error) {
+	return db.placeOrderForTx(ctx, tx, uuid, "", lines, "")
+}
+
+// placeOrderForTx charges uuid for the lines and puts the items in the
+// inventory of recipientUuid, or of the buyer when it is empty.
+func (db *DataBase) placeOrderForTx(ctx context.Context, tx *sql.Tx, uuid, recipientUuid string, lines []models.OrderLine, message string) (*models.Order, error) {
+	balance, err := db.getBalanceTx(ctx, tx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := uuid
+	if recipientUuid != "" {
+		owner = recipientUuid
+	}
+
+	order := &models.Order{Message: message}
+	merchIDs := make([]int, len(lines))
+	for i, line := range lines {
+		var available bool
+		err = tx.QueryRowContext(ctx, `
+		SELECT id, price, available
+		  FROM merchandise
+		 WHERE name = $1
+		   AND NOT hidden
+		   AND retired_at IS NULL
+	`, line.Item).Scan(&merchIDs[i], &line.UnitPrice, &available)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("%w: %s", ErrItemNotFound, line.Item)
+			}
+			return nil, err
+		}
+		if !available {
+			return nil, fmt.Errorf("%w: %s", ErrItemNotAvailable, line.Item)
+		}
+		err = db.takeStockTx(ctx, tx, merchIDs[i], line.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, line.Item)
+		}
+		order.Items = append(order.Items, line)
+		order.Total, err = addLineTotal(order.Total, line.UnitPrice, line.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, line.Item)
+		}
+	}
+	if balance-order.Total < 0 {
+		return nil, ErrNotEnoughBalance
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO orders (user_id, total, recipient_id, message)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, NULLIF($4, ''))
+		RETURNING id, created_at
+	`, uuid, order.Total, recipientUuid, message).Scan(&order.ID, &order.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range order.Items {
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO order_items (order_id, merchandise_id, quantity, unit_price)
+		VALUES ($1, $2, $3, $4)
+	`, order.ID, merchIDs[i], line.Quantity, line.UnitPrice)
+		if err != nil {
+			return nil, err
+		}
+		err = db.addInventoryTx(ctx, tx, owner, merchIDs[i], line.Quantity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = db.postEntry(ctx, tx, EntryPurchase, orderReference(order.ID),
+		posting{account: WalletAccount(uuid), amount: -order.Total},
+		posting{account: AccountStore, amount: order.Total},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// addLineTotal adds price*quantity to total and fails instead of overflowing,
+// a wrapped total would be negative and credit the buyer.
+func addLineTotal(total, price, quantity int) (int, error) {
+	if price > 0 && quantity > (maxOrderTotal-total)/price {
+		return 0, ErrInvalidQuantity
+	}
+	return total + price*quantity, nil
+}
+
+func orderReference(orderID int) string {
+	return fmt.Sprintf("order:%d", orderID)
+}
+
+// mergeOrderLines joins lines with the same item keeping the cart order
+func mergeOrderLines(lines []models.OrderLine) ([]models.OrderLine, error) {
+	if len(lines) == 0 {
+		return nil, ErrEmptyOrder
+	}
+	var merged []models.OrderLine
+	index := make(map[string]int)
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidQuantity, line.Item)
+		}
+		if i, ok := index[line.Item]; ok {
+			if line.Quantity > maxOrderTotal-merged[i].Quantity {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidQuantity, line.Item)
+			}
+			merged[i].Quantity += line.Quantity
+			continue
+		}
+		index[line.Item] = len(merged)
+		merged = append(merged, models.OrderLine{Item: line.Item, Quantity: line.Quantity})
+	}
+	return merged, nil
+}
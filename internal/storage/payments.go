@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// queryer is either the database or a transaction
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// CreatePaymentRequest asks every payer for amount coins. Payers are checked
+// like bulk transfer recipients and all problems come in a RecipientsError.
+func (db *DataBase) CreatePaymentRequest(ctx context.Context, uuid string, payers []string, amount int, note models.TransferNote) (*models.PaymentRequest, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	var request *models.PaymentRequest
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		payerUuids, err := validateRecipients(uuid, paymentTransfers(payers, amount), func(username string) (string, error) {
+			return db.getUuidByUsernameTx(ctx, tx, username)
+		})
+		if err != nil {
+			return err
+		}
+
+		var requestID int
+		err = tx.QueryRowContext(ctx, `
+		INSERT INTO payment_requests (requester_id, amount, message, category)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		RETURNING id
+	`, uuid, amount, note.Message, note.Category).Scan(&requestID)
+		if err != nil {
+			return err
+		}
+		for _, payerUuid := range payerUuids {
+			_, err = tx.ExecContext(ctx, `
+		INSERT INTO payment_request_payers (request_id, payer_id)
+		VALUES ($1, $2)
+	`, requestID, payerUuid)
+			if err != nil {
+				return err
+			}
+		}
+		request, err = db.getPaymentRequestTx(ctx, tx, uuid, requestID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// GetPaymentRequests returns requests the user made or has to pay, newest first.
+func (db *DataBase) GetPaymentRequests(ctx context.Context, uuid string) ([]models.PaymentRequest, error) {
+	return db.queryPaymentRequests(ctx, db.Tm.DB, uuid, 0)
+}
+
+// PayPaymentRequest sends the requested coins through the regular transfer
+// path, so balance and sending limits apply as for any transfer.
+func (db *DataBase) PayPaymentRequest(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error) {
+	var request *models.PaymentRequest
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		requester, amount, note, err := db.lockPaymentTx(ctx, tx, uuid, requestID)
+		if err != nil {
+			return err
+		}
+		transactionID, err := db.sendTx(ctx, tx, uuid, requester, amount, note)
+		if err != nil {
+			return err
+		}
+		err = db.answerPaymentTx(ctx, tx, uuid, requestID, models.PaymentStatusPaid, &transactionID)
+		if err != nil {
+			return err
+		}
+		request, err = db.getPaymentRequestTx(ctx, tx, uuid, requestID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (db *DataBase) RejectPaymentRequest(ctx context.Context, uuid string, requestID int) (*models.PaymentRequest, error) {
+	var request *models.PaymentRequest
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		_, _, _, err := db.lockPaymentTx(ctx, tx, uuid, requestID)
+		if err != nil {
+			return err
+		}
+		err = db.answerPaymentTx(ctx, tx, uuid, requestID, models.PaymentStatusRejected, nil)
+		if err != nil {
+			return err
+		}
+		request, err = db.getPaymentRequestTx(ctx, tx, uuid, requestID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// lockPaymentTx checks that the payer has not answered the request yet and
+// returns the requester's username with what to send
+func (db *DataBase) lockPaymentTx(ctx context.Context, tx *sql.Tx, uuid string, requestID int) (string, int, models.TransferNote, error) {
+	var requester, status string
+	var amount int
+	var note models.TransferNote
+	err := tx.QueryRowContext(ctx, `
+		SELECT requester.username, r.amount, COALESCE(r.message, ''), COALESCE(r.category, ''), p.status
+		  FROM payment_request_payers p
+		  JOIN payment_requests r ON p.request_id = r.id
+		  JOIN users requester ON r.requester_id = requester.id
+		 WHERE p.request_id = $1
+		   AND p.payer_id = $2
+		   FOR UPDATE OF p
+	`, requestID, uuid).Scan(&requester, &amount, &note.Message, &note.Category, &status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", 0, note, ErrPaymentRequestNotFound
+		}
+		return "", 0, note, err
+	}
+	if status != models.PaymentStatusPending {
+		return "", 0, note, ErrPaymentRequestAnswered
+	}
+	return requester, amount, note, nil
+}
+
+func (db *DataBase) answerPaymentTx(ctx context.Context, tx *sql.Tx, uuid string, requestID int, status string, transactionID *int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE payment_request_payers
+		   SET status = $1,
+		       resolved_at = CURRENT_TIMESTAMP,
+		       transaction_id = $2
+		 WHERE request_id = $3
+		   AND payer_id = $4
+	`, status, transactionID, requestID, uuid)
+	return err
+}
+
+func (db *DataBase) getPaymentRequestTx(ctx context.Context, tx *sql.Tx, uuid string, requestID int) (*models.PaymentRequest, error) {
+	requests, err := db.queryPaymentRequests(ctx, tx, uuid, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, ErrPaymentRequestNotFound
+	}
+	return &requests[0], nil
+}
+
+// queryPaymentRequests loads the requests the user can see, only the one with
+// requestID unless it is zero. A payer row is visible to the requester and to
+// that payer.
+func (db *DataBase) queryPaymentRequests(ctx context.Context, q queryer, uuid string, requestID int) ([]models.PaymentRequest, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT r.id, requester.username, r.amount, COALESCE(r.message, ''), COALESCE(r.category, ''), r.created_at,
+		       payer.username, p.status, p.resolved_at, COALESCE(p.transaction_id, 0)
+		  FROM payment_requests r
+		  JOIN users requester ON r.requester_id = requester.id
+		  JOIN payment_request_payers p ON p.request_id = r.id
+		  JOIN users payer ON p.payer_id = payer.id
+		 WHERE (r.requester_id = $1 OR p.payer_id = $1)
+		   AND ($2 = 0 OR r.id = $2)
+		 ORDER BY r.id DESC, payer.username
+	`, uuid, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.PaymentRequest
+	for rows.Next() {
+		var r models.PaymentRequest
+		var p models.Payer
+		scanErr := rows.Scan(&r.ID, &r.Requester, &r.Amount, &r.Message, &r.Category, &r.CreatedAt,
+			&p.User, &p.Status, &p.ResolvedAt, &p.TransactionID)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		if len(result) == 0 || result[len(result)-1].ID != r.ID {
+			result = append(result, r)
+		}
+		last := &result[len(result)-1]
+		last.Payers = append(last.Payers, p)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return result, nil
+}
+
+func paymentTransfers(payers []string, amount int) []models.BulkTransfer {
+	transfers := make([]models.BulkTransfer, len(payers))
+	for i, payer := range payers {
+		transfers[i] = models.BulkTransfer{ToUser: payer, Amount: amount}
+	}
+	return transfers
+}
@@ -0,0 +1,278 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const pendingColumns = `
+		SELECT p.id, sender.username, receiver.username, p.amount, COALESCE(p.message, ''), COALESCE(p.category, ''),
+		       p.status, p.expires_at, p.created_at, p.resolved_at, COALESCE(p.transaction_id, 0)
+		  FROM pending_transfers p
+		  JOIN users sender   ON p.sender_id = sender.id
+		  JOIN users receiver ON p.receiver_id = receiver.id`
+
+// CreatePendingTransfer holds amount coins of the sender in escrow until
+// toUser accepts or declines the offer or it expires after ttl. The note goes
+// to the transfer made on accept.
+func (db *DataBase) CreatePendingTransfer(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote, ttl time.Duration) (*models.PendingTransfer, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	var pending *models.PendingTransfer
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		receiverUuid, err := db.getUuidByUsernameTx(ctx, tx, toUser)
+		if err != nil {
+			return err
+		}
+		if uuid == receiverUuid {
+			return ErrSendingToYourself
+		}
+		err = db.checkSendLimitTx(ctx, tx, uuid, amount)
+		if err != nil {
+			return err
+		}
+
+		var pendingID int
+		err = tx.QueryRowContext(ctx, `
+		INSERT INTO pending_transfers (sender_id, receiver_id, amount, message, category, expires_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), CURRENT_TIMESTAMP + make_interval(secs => $6))
+		RETURNING id
+	`, uuid, receiverUuid, amount, note.Message, note.Category, ttl.Seconds()).Scan(&pendingID)
+		if err != nil {
+			return err
+		}
+		_, err = db.postEntry(ctx, tx, EntryHold, pendingReference(pendingID),
+			posting{account: WalletAccount(uuid), amount: -amount},
+			posting{account: AccountEscrow, amount: amount},
+		)
+		if err != nil {
+			return err
+		}
+		pending, err = db.getPendingTransferTx(ctx, tx, pendingID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// GetPendingTransfers returns offers sent and received by the user, newest first.
+func (db *DataBase) GetPendingTransfers(ctx context.Context, uuid string) ([]models.PendingTransfer, error) {
+	rows, err := db.Tm.DB.QueryContext(ctx, pendingColumns+`
+		 WHERE p.sender_id = $1
+		    OR p.receiver_id = $1
+		 ORDER BY p.id DESC
+	`, uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.PendingTransfer
+	for rows.Next() {
+		p, scanErr := scanPendingTransfer(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		result = append(result, *p)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return result, nil
+}
+
+// AcceptPendingTransfer settles the offer as a regular transfer.
+func (db *DataBase) AcceptPendingTransfer(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error) {
+	var pending *models.PendingTransfer
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		senderUuid, amount, note, err := db.lockPendingTransferTx(ctx, tx, uuid, pendingID)
+		if err != nil {
+			return err
+		}
+		transactionID, err := db.createTransaction(ctx, tx, senderUuid, uuid, amount, note)
+		if err != nil {
+			return err
+		}
+		_, err = db.postEntry(ctx, tx, EntryTransfer, strconv.Itoa(transactionID),
+			posting{account: AccountEscrow, amount: -amount},
+			posting{account: WalletAccount(uuid), amount: amount},
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+		UPDATE pending_transfers
+		   SET status = $1,
+		       resolved_at = CURRENT_TIMESTAMP,
+		       transaction_id = $2
+		 WHERE id = $3
+	`, models.PendingStatusAccepted, transactionID, pendingID)
+		if err != nil {
+			return err
+		}
+		pending, err = db.getPendingTransferTx(ctx, tx, pendingID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// DeclinePendingTransfer returns the held coins to the sender.
+func (db *DataBase) DeclinePendingTransfer(ctx context.Context, uuid string, pendingID int) (*models.PendingTransfer, error) {
+	var pending *models.PendingTransfer
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		senderUuid, amount, _, err := db.lockPendingTransferTx(ctx, tx, uuid, pendingID)
+		if err != nil {
+			return err
+		}
+		err = db.releasePendingTransferTx(ctx, tx, pendingID, senderUuid, amount, models.PendingStatusDeclined)
+		if err != nil {
+			return err
+		}
+		pending, err = db.getPendingTransferTx(ctx, tx, pendingID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// ExpirePendingTransfers returns the coins of up to limit offers that expired by now.
+// Rows are taken with SKIP LOCKED, so several instances can run it at once.
+func (db *DataBase) ExpirePendingTransfers(ctx context.Context, now time.Time, limit int) ([]models.PendingTransfer, error) {
+	var expired []models.PendingTransfer
+	err := db.Tm.WriteTX(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+		SELECT id, sender_id, amount
+		  FROM pending_transfers
+		 WHERE status = $1
+		   AND expires_at <= $2
+		 ORDER BY id
+		 LIMIT $3
+		   FOR UPDATE SKIP LOCKED
+	`, models.PendingStatusPending, now, limit)
+		if err != nil {
+			return err
+		}
+		type due struct {
+			id         int
+			senderUuid string
+			amount     int
+		}
+		var dueTransfers []due
+		for rows.Next() {
+			var d due
+			if scanErr := rows.Scan(&d.id, &d.senderUuid, &d.amount); scanErr != nil {
+				rows.Close()
+				return scanErr
+			}
+			dueTransfers = append(dueTransfers, d)
+		}
+		rows.Close()
+		if rowsErr := rows.Err(); rowsErr != nil {
+			return rowsErr
+		}
+
+		for _, d := range dueTransfers {
+			err = db.releasePendingTransferTx(ctx, tx, d.id, d.senderUuid, d.amount, models.PendingStatusExpired)
+			if err != nil {
+				return err
+			}
+			p, err := db.getPendingTransferTx(ctx, tx, d.id)
+			if err != nil {
+				return err
+			}
+			expired = append(expired, *p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+// lockPendingTransferTx checks that the offer is still open for its recipient
+func (db *DataBase) lockPendingTransferTx(ctx context.Context, tx *sql.Tx, uuid string, pendingID int) (string, int, models.TransferNote, error) {
+	var senderUuid, status string
+	var amount int
+	var note models.TransferNote
+	var expired bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT sender_id, amount, COALESCE(message, ''), COALESCE(category, ''), status, expires_at <= CURRENT_TIMESTAMP
+		  FROM pending_transfers
+		 WHERE id = $1
+		   AND receiver_id = $2
+		   FOR UPDATE
+	`, pendingID, uuid).Scan(&senderUuid, &amount, &note.Message, &note.Category, &status, &expired)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", 0, note, ErrPendingTransferNotFound
+		}
+		return "", 0, note, err
+	}
+	// expired offers are released by the background job
+	if status != models.PendingStatusPending || expired {
+		return "", 0, note, ErrPendingTransferClosed
+	}
+	return senderUuid, amount, note, nil
+}
+
+func (db *DataBase) releasePendingTransferTx(ctx context.Context, tx *sql.Tx, pendingID int, senderUuid string, amount int, status string) error {
+	_, err := db.postEntry(ctx, tx, EntryRelease, pendingReference(pendingID),
+		posting{account: AccountEscrow, amount: -amount},
+		posting{account: WalletAccount(senderUuid), amount: amount, returns: &entryRef{kind: EntryHold, reference: pendingReference(pendingID)}},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE pending_transfers
+		   SET status = $1,
+		       resolved_at = CURRENT_TIMESTAMP
+		 WHERE id = $2
+	`, status, pendingID)
+	return err
+}
+
+func (db *DataBase) getPendingTransferTx(ctx context.Context, tx *sql.Tx, pendingID int) (*models.PendingTransfer, error) {
+	row := tx.QueryRowContext(ctx, pendingColumns+`
+		 WHERE p.id = $1
+	`, pendingID)
+	p, err := scanPendingTransfer(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPendingTransferNotFound
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPendingTransfer(row rowScanner) (*models.PendingTransfer, error) {
+	var p models.PendingTransfer
+	err := row.Scan(&p.ID, &p.FromUser, &p.ToUser, &p.Amount, &p.Message, &p.Category, &p.Status, &p.ExpiresAt, &p.CreatedAt, &p.ResolvedAt, &p.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func pendingReference(pendingID int) string {
+	return fmt.Sprintf("pending:%d", pendingID)
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"strconv"
+	"strings"
+)
+
+// GetPurchases returns user's orders with the price paid for every line, newest first.
+func (db *DataBase) GetPurchases(ctx context.Context, uuid string, filter models.PurchaseFilter) ([]models.Order, error) {
+	args := []any{uuid}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"o.user_id = $1"}
+	if filter.Item != "" {
+		conditions = append(conditions, `EXISTS (SELECT 1
+		                  FROM order_items fi
+		                  JOIN merchandise fm ON fi.merchandise_id = fm.id
+		                 WHERE fi.order_id = o.id
+		                   AND fm.name = `+arg(filter.Item)+`)`)
+	}
+	if filter.BeforeID > 0 {
+		conditions = append(conditions, "o.id < "+arg(filter.BeforeID))
+	}
+
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT o.id, o.total, o.created_at, COALESCE(r.username, ''), COALESCE(o.message, ''),
+		       m.name, oi.quantity, oi.unit_price, oi.refunded
+		  FROM (SELECT o.id, o.total, o.created_at, o.recipient_id, o.message
+		          FROM orders o
+		         WHERE `+strings.Join(conditions, "\n\t\t           AND ")+`
+		         ORDER BY o.id DESC
+		         LIMIT `+arg(filter.Limit)+`) o
+		  JOIN order_items oi ON oi.order_id = o.id
+		  JOIN merchandise m  ON oi.merchandise_id = m.id
+		  LEFT JOIN users r   ON o.recipient_id = r.id
+		 ORDER BY o.id DESC, oi.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Order
+	for rows.Next() {
+		var order models.Order
+		var line models.OrderLine
+		scanErr := rows.Scan(&order.ID, &order.Total, &order.CreatedAt, &order.ToUser, &order.Message, &line.Item, &line.Quantity, &line.UnitPrice, &line.Refunded)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		if n := len(result); n > 0 && result[n-1].ID == order.ID {
+			result[n-1].Items = append(result[n-1].Items, line)
+			continue
+		}
+		order.Items = []models.OrderLine{line}
+		result = append(result, order)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	err = db.attachRefunds(ctx, uuid, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// attachRefunds loads refunds of the orders, which are sorted by id descending
+func (db *DataBase) attachRefunds(ctx context.Context, uuid string, orders []models.Order) error {
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT r.id, o.id, u.username, m.name, r.quantity, r.amount, r.created_at
+		  FROM refunds r
+		  JOIN order_items oi ON r.order_item_id = oi.id
+		  JOIN orders o       ON oi.order_id = o.id
+		  JOIN users u        ON o.user_id = u.id
+		  JOIN merchandise m  ON oi.merchandise_id = m.id
+		 WHERE o.user_id = $1
+		   AND o.id BETWEEN $2 AND $3
+		 ORDER BY r.id
+	`, uuid, orders[len(orders)-1].ID, orders[0].ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	index := make(map[int]int, len(orders))
+	for i, order := range orders {
+		index[order.ID] = i
+	}
+	for rows.Next() {
+		var r models.Refund
+		scanErr := rows.Scan(&r.ID, &r.OrderID, &r.User, &r.Item, &r.Quantity, &r.Amount, &r.CreatedAt)
+		if scanErr != nil {
+			return scanErr
+		}
+		if i, ok := index[r.OrderID]; ok {
+			orders[i].Refunds = append(orders[i].Refunds, r)
+		}
+	}
+	return rows.Err()
+}
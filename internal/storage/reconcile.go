@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// walletFigures is one wallet as seen by every source reconciliation compares
+type walletFigures struct {
+	uuid    string
+	balance int
+	// sum of the wallet's ledger postings
+	ledger int
+	// what signup issuance, transfers, purchases, refunds and open holds add
+	// up to, repairs adjust the ledger towards it
+	activity int
+	// sum of the remaining coins of the wallet's lots
+	lots int
+}
+
+func (f walletFigures) discrepancies() []models.Discrepancy {
+	var result []models.Discrepancy
+	if f.balance < 0 {
+		result = append(result, models.Discrepancy{User: f.uuid, Check: models.CheckNegativeBalance, Expected: 0, Actual: f.balance})
+	}
+	if f.balance != f.ledger {
+		result = append(result, models.Discrepancy{User: f.uuid, Check: models.CheckLedger, Expected: f.ledger, Actual: f.balance})
+	}
+	if f.ledger != f.activity {
+		result = append(result, models.Discrepancy{User: f.uuid, Check: models.CheckActivity, Expected: f.activity, Actual: f.ledger})
+	}
+	if f.lots != f.balance {
+		result = append(result, models.Discrepancy{User: f.uuid, Check: models.CheckLots, Expected: f.balance, Actual: f.lots})
+	}
+	return result
+}
+
+// Reconcile recomputes every wallet from the ledger, the activity tables and
+// the coin lots and reports where they disagree. With repair every wallet
+// with discrepancies is fixed in its own transaction: the balance is set to
+// the ledger, the ledger gets an adjustment entry up to the activity and the
+// lots are topped up or spent down to the balance. Each step is recorded in
+// reconcile_adjustments under the run. Unbalanced ledger entries are only
+// reported.
+func (db *DataBase) Reconcile(ctx context.Context, repair bool) (*models.ReconcileReport, error) {
+	report := &models.ReconcileReport{
+		Checked:           time.Now().UTC(),
+		UnbalancedEntries: []int{},
+		Discrepancies:     []models.Discrepancy{},
+	}
+
+	var wallets []walletFigures
+	err := db.Tm.ReadTX(ctx, func(tx *sql.Tx) error {
+		var err error
+		wallets, err = db.getWalletFiguresTx(ctx, tx, "")
+		if err != nil {
+			return err
+		}
+		report.UnbalancedEntries, err = db.getUnbalancedEntriesTx(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Wallets = len(wallets)
+
+	var broken []walletFigures
+	for _, w := range wallets {
+		if found := w.discrepancies(); len(found) > 0 {
+			broken = append(broken, w)
+			if !repair {
+				report.Discrepancies = append(report.Discrepancies, found...)
+			}
+		}
+	}
+	if !repair || len(broken) == 0 {
+		return report, nil
+	}
+
+	err = db.Tm.DB.QueryRowContext(ctx, `
+		INSERT INTO reconcile_runs DEFAULT VALUES
+		RETURNING id
+	`).Scan(&report.RunID)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range broken {
+		found := w.discrepancies()
+		repairErr := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+			return db.repairWalletTx(ctx, tx, report.RunID, w.uuid)
+		})
+		for i := range found {
+			if repairErr != nil {
+				found[i].Error = repairErr.Error()
+			} else {
+				found[i].Repaired = true
+			}
+		}
+		report.Discrepancies = append(report.Discrepancies, found...)
+	}
+	return report, nil
+}
+
+func (db *DataBase) repairWalletTx(ctx context.Context, tx *sql.Tx, runID int, uuid string) error {
+	// the user row lock guards the balance and the lots
+	_, err := tx.ExecContext(ctx, `
+		SELECT id
+		  FROM users
+		 WHERE id = $1
+		   FOR UPDATE
+	`, uuid)
+	if err != nil {
+		return err
+	}
+	wallets, err := db.getWalletFiguresTx(ctx, tx, uuid)
+	if err != nil {
+		return err
+	}
+	if len(wallets) == 0 {
+		return ErrUserNotFound
+	}
+	w := wallets[0]
+
+	if w.balance != w.ledger {
+		check := models.CheckLedger
+		if w.balance < 0 {
+			check = models.CheckNegativeBalance
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE users
+			   SET balance = $1
+			 WHERE id = $2
+		`, w.ledger, uuid)
+		if err != nil {
+			return err
+		}
+		err = db.recordAdjustmentTx(ctx, tx, runID, uuid, check, w.ledger, w.balance, nil)
+		if err != nil {
+			return err
+		}
+		w.balance = w.ledger
+	}
+
+	if diff := w.activity - w.ledger; diff != 0 {
+		entryID, err := db.postEntry(ctx, tx, EntryAdjustment, reconcileReference(runID),
+			posting{account: AccountIssuance, amount: -diff},
+			posting{account: WalletAccount(uuid), amount: diff},
+		)
+		if err != nil {
+			return err
+		}
+		err = db.recordAdjustmentTx(ctx, tx, runID, uuid, models.CheckActivity, w.activity, w.ledger, &entryID)
+		if err != nil {
+			return err
+		}
+		// postEntry moved the balance and the lots along with the ledger
+		w.balance += diff
+		err = tx.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(remaining), 0)
+			  FROM coin_lots
+			 WHERE user_id = $1
+		`, uuid).Scan(&w.lots)
+		if err != nil {
+			return err
+		}
+	}
+
+	if diff := w.balance - w.lots; diff != 0 {
+		if diff > 0 {
+			err = db.addLotTx(ctx, tx, uuid, diff, nil)
+		} else {
+			err = db.takeLotsTx(ctx, tx, uuid, -diff, 0)
+		}
+		if err != nil {
+			return err
+		}
+		return db.recordAdjustmentTx(ctx, tx, runID, uuid, models.CheckLots, w.balance, w.lots, nil)
+	}
+	return nil
+}
+
+func (db *DataBase) recordAdjustmentTx(ctx context.Context, tx *sql.Tx, runID int, uuid, check string, expected, actual int, entryID *int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO reconcile_adjustments (run_id, user_id, check_name, expected, actual, ledger_entry_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, runID, uuid, check, expected, actual, entryID)
+	return err
+}
+
+// getWalletFiguresTx loads one wallet, or every wallet when uuid is empty.
+// Allowances are issuance in the ledger but also transfers from the issuer,
+// so only the other issuance entries are taken from the ledger.
+func (db *DataBase) getWalletFiguresTx(ctx context.Context, tx *sql.Tx, uuid string) ([]walletFigures, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT u.id,
+		       u.balance,
+		       COALESCE((SELECT SUM(p.amount)
+		                   FROM ledger_postings p
+		                   JOIN ledger_accounts a ON p.account_id = a.id
+		                  WHERE a.user_id = u.id), 0),
+		       COALESCE((SELECT SUM(p.amount)
+		                   FROM ledger_postings p
+		                   JOIN ledger_accounts a ON p.account_id = a.id
+		                   JOIN ledger_entries e ON p.entry_id = e.id
+		                  WHERE a.user_id = u.id
+		                    AND e.kind IN ('opening', 'issuance')
+		                    AND COALESCE(e.reference, '') NOT LIKE 'allowance:%'), 0)
+		     + COALESCE((SELECT SUM(amount) FROM transactions WHERE receiver_id = u.id), 0)
+		     - COALESCE((SELECT SUM(amount) FROM transactions WHERE sender_id = u.id), 0)
+		     - COALESCE((SELECT SUM(total) FROM orders WHERE user_id = u.id), 0)
+		     + COALESCE((SELECT SUM(r.amount)
+		                   FROM refunds r
+		                   JOIN order_items i ON r.order_item_id = i.id
+		                   JOIN orders o ON i.order_id = o.id
+		                  WHERE o.user_id = u.id), 0)
+		     - COALESCE((SELECT SUM(amount)
+		                   FROM pending_transfers
+		                  WHERE sender_id = u.id
+		                    AND status = 'pending'), 0)
+		     - COALESCE((SELECT SUM(amount)
+		                   FROM auction_bids
+		                  WHERE bidder_id = u.id
+		                    AND status = 'held'), 0),
+		       COALESCE((SELECT SUM(remaining) FROM coin_lots WHERE user_id = u.id), 0)
+		  FROM users u
+		 WHERE u.role <> 'system'
+		   AND ($1 = '' OR u.id::text = $1)
+		 ORDER BY u.created_at, u.id
+	`, uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []walletFigures
+	for rows.Next() {
+		var w walletFigures
+		if scanErr := rows.Scan(&w.uuid, &w.balance, &w.ledger, &w.activity, &w.lots); scanErr != nil {
+			return nil, scanErr
+		}
+		result = append(result, w)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return result, nil
+}
+
+func (db *DataBase) getUnbalancedEntriesTx(ctx context.Context, tx *sql.Tx) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT entry_id
+		  FROM ledger_postings
+		 GROUP BY entry_id
+		HAVING SUM(amount) <> 0
+		 ORDER BY entry_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []int{}
+	for rows.Next() {
+		var id int
+		if scanErr := rows.Scan(&id); scanErr != nil {
+			return nil, scanErr
+		}
+		result = append(result, id)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return result, nil
+}
+
+func reconcileReference(runID int) string {
+	return "reconcile:" + strconv.Itoa(runID)
+}
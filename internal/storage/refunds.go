@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RefundPurchase returns quantity of item from user's own order placed within
+// the window. Zero quantity returns everything left of the line.
+func (db *DataBase) RefundPurchase(ctx context.Context, uuid string, orderID int, item string, quantity int, window time.Duration) (*models.Refund, error) {
+	var refund *models.Refund
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		var owner string
+		var inWindow bool
+		err := tx.QueryRowContext(ctx, `
+		SELECT user_id, created_at >= CURRENT_TIMESTAMP - make_interval(secs => $2)
+		  FROM orders
+		 WHERE id = $1
+	`, orderID, window.Seconds()).Scan(&owner, &inWindow)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+		if owner != uuid {
+			return ErrOrderNotFound
+		}
+		if !inWindow {
+			return ErrRefundWindowExpired
+		}
+		refund, err = db.refundTx(ctx, tx, orderID, item, quantity, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// AdminRefundPurchase returns a purchase of any user regardless of the window.
+func (db *DataBase) AdminRefundPurchase(ctx context.Context, adminUuid string, orderID int, item string, quantity int) (*models.Refund, error) {
+	var refund *models.Refund
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		var err error
+		refund, err = db.refundTx(ctx, tx, orderID, item, quantity, &adminUuid)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// refundTx takes the items back from the inventory, puts them back in stock and
+// credits the price paid from the store account. Gifts are taken back from the
+// recipient and refunded to the buyer, so they can only be refunded while the
+// recipient still holds the items. Auction wins are final: the item would go
+// back on sale at the catalog price and the bid was paid from escrow.
+func (db *DataBase) refundTx(ctx context.Context, tx *sql.Tx, orderID int, item string, quantity int, refundedBy *string) (*models.Refund, error) {
+	refund := &models.Refund{OrderID: orderID, Item: item}
+	var owner, holder string
+	var orderItemID, merchID, left, unitPrice int
+	var auctionWin bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT o.user_id, COALESCE(o.recipient_id, o.user_id), u.username, COALESCE(r.username, ''),
+		       oi.id, oi.merchandise_id, oi.quantity - oi.refunded, oi.unit_price,
+		       EXISTS (SELECT 1 FROM auctions a WHERE a.order_id = o.id)
+		  FROM orders o
+		  JOIN users u        ON o.user_id = u.id
+		  LEFT JOIN users r   ON o.recipient_id = r.id
+		  JOIN order_items oi ON oi.order_id = o.id
+		  JOIN merchandise m  ON oi.merchandise_id = m.id
+		 WHERE o.id = $1
+		   AND m.name = $2
+	`, orderID, item).Scan(&owner, &holder, &refund.User, &refund.ToUser, &orderItemID, &merchID, &left, &unitPrice, &auctionWin)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if _, orderErr := db.getOrderOwnerTx(ctx, tx, orderID); orderErr != nil {
+				return nil, orderErr
+			}
+			return nil, ErrItemNotFound
+		}
+		return nil, err
+	}
+	if auctionWin {
+		return nil, ErrAuctionWinNotRefundable
+	}
+	if quantity == 0 {
+		quantity = left
+	}
+	if quantity <= 0 || quantity > left {
+		return nil, ErrRefundExceedsPurchase
+	}
+	refund.Quantity = quantity
+	refund.Amount = quantity * unitPrice
+
+	err = db.removeInventoryTx(ctx, tx, holder, merchID, quantity)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE order_items
+		   SET refunded = refunded + $1
+		 WHERE id = $2
+	`, quantity, orderItemID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE merchandise
+		   SET stock = stock + $1
+		 WHERE id = $2
+		   AND stock IS NOT NULL
+	`, quantity, merchID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO refunds (order_item_id, quantity, amount, refunded_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, orderItemID, quantity, refund.Amount, refundedBy).Scan(&refund.ID, &refund.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.postEntry(ctx, tx, EntryRefund, refundReference(refund.ID),
+		posting{account: AccountStore, amount: -refund.Amount},
+		posting{account: WalletAccount(owner), amount: refund.Amount, returns: &entryRef{kind: EntryPurchase, reference: orderReference(orderID)}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+func (db *DataBase) getOrderOwnerTx(ctx context.Context, tx *sql.Tx, orderID int) (string, error) {
+	var owner string
+	err := tx.QueryRowContext(ctx, `
+		SELECT user_id
+		  FROM orders
+		 WHERE id = $1
+	`, orderID).Scan(&owner)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrOrderNotFound
+		}
+		return "", err
+	}
+	return owner, nil
+}
+
+func (db *DataBase) removeInventoryTx(ctx context.Context, tx *sql.Tx, uuid string, merchID int, quantity int) error {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE user_inventory
+		   SET quantity = quantity - $1
+		 WHERE user_id = $2
+		   AND merchandise_id = $3
+		   AND quantity >= $1
+	`, quantity, uuid, merchID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotInInventory
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM user_inventory
+		 WHERE user_id = $1
+		   AND merchandise_id = $2
+		   AND quantity = 0
+	`, uuid, merchID)
+	return err
+}
+
+func refundReference(refundID int) string {
+	return fmt.Sprintf("refund:%d", refundID)
+}
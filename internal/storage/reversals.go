@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+// ReverseTransaction sends the not yet reversed part of a transfer back to the
+// sender. If the recipient has spent some of it, the fail policy returns
+// ErrNotEnoughBalance and the partial policy takes back what is left.
+func (db *DataBase) ReverseTransaction(ctx context.Context, adminUuid string, transactionID int, policy string) (*models.Reversal, error) {
+	var reversal *models.Reversal
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		var senderUuid, receiverUuid string
+		var amount int
+		var reversesID sql.NullInt64
+		err := tx.QueryRowContext(ctx, `
+		SELECT sender_id, receiver_id, amount, reverses_id
+		  FROM transactions
+		 WHERE id = $1
+	`, transactionID).Scan(&senderUuid, &receiverUuid, &amount, &reversesID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrTransactionNotFound
+			}
+			return err
+		}
+		if reversesID.Valid {
+			return ErrReversalOfReversal
+		}
+		// the issuer has no wallet to take the coins from or give them back to
+		if senderUuid == IssuerUUID || receiverUuid == IssuerUUID {
+			return ErrSystemTransaction
+		}
+
+		var reversed int
+		err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount), 0)
+		  FROM transactions
+		 WHERE reverses_id = $1
+	`, transactionID).Scan(&reversed)
+		if err != nil {
+			return err
+		}
+		remaining := amount - reversed
+		if remaining == 0 {
+			return ErrAlreadyReversed
+		}
+
+		balance, err := db.getBalanceTx(ctx, tx, receiverUuid)
+		if err != nil {
+			return err
+		}
+		reverseAmount := remaining
+		if balance < remaining {
+			if policy != models.ReversalPolicyPartial || balance == 0 {
+				return ErrNotEnoughBalance
+			}
+			reverseAmount = balance
+		}
+
+		reversal = &models.Reversal{
+			OriginalID: transactionID,
+			Amount:     reverseAmount,
+			Remaining:  remaining - reverseAmount,
+		}
+		err = tx.QueryRowContext(ctx, `
+		INSERT INTO transactions (sender_id, receiver_id, amount, reverses_id, reversed_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, receiverUuid, senderUuid, reverseAmount, transactionID, adminUuid).Scan(&reversal.ID, &reversal.CreatedAt)
+		if err != nil {
+			return err
+		}
+		returns, err := db.transferSourceTx(ctx, tx, transactionID)
+		if err != nil {
+			return err
+		}
+		_, err = db.postEntry(ctx, tx, EntryReversal, strconv.Itoa(reversal.ID),
+			posting{account: WalletAccount(receiverUuid), amount: -reverseAmount},
+			posting{account: WalletAccount(senderUuid), amount: reverseAmount, returns: returns},
+		)
+		if err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, `
+		SELECT sender.username, receiver.username
+		  FROM users sender, users receiver
+		 WHERE sender.id = $1
+		   AND receiver.id = $2
+	`, receiverUuid, senderUuid).Scan(&reversal.FromUser, &reversal.ToUser)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// transferSourceTx finds the entry that took the transfer from the sender's
+// lots. An accepted pending transfer took them when it was held.
+func (db *DataBase) transferSourceTx(ctx context.Context, tx *sql.Tx, transactionID int) (*entryRef, error) {
+	var pendingID int
+	err := tx.QueryRowContext(ctx, `
+		SELECT id
+		  FROM pending_transfers
+		 WHERE transaction_id = $1
+	`, transactionID).Scan(&pendingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &entryRef{kind: EntryTransfer, reference: strconv.Itoa(transactionID)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entryRef{kind: EntryHold, reference: pendingReference(pendingID)}, nil
+}
@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var errNoDueSchedule = errors.New("no due scheduled transfer")
+
+func (db *DataBase) CreateSchedule(ctx context.Context, uuid string, schedule models.ScheduledTransfer) (*models.ScheduledTransfer, error) {
+	if schedule.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	receiverUuid, err := db.GetUuidByUsername(ctx, schedule.ToUser)
+	if err != nil {
+		return nil, err
+	}
+	if receiverUuid == uuid {
+		return nil, ErrSendingToYourself
+	}
+
+	schedule.Active = true
+	err = db.Tm.DB.QueryRowContext(ctx, `
+		INSERT INTO scheduled_transfers (user_id, receiver_id, amount, message, category, repeat_interval, next_run_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)
+		RETURNING id, created_at
+	`, uuid, receiverUuid, schedule.Amount, schedule.Message, schedule.Category, schedule.Interval, schedule.NextRunAt).Scan(&schedule.ID, &schedule.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (db *DataBase) GetSchedules(ctx context.Context, uuid string) ([]models.ScheduledTransfer, error) {
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT s.id, r.username, s.amount, COALESCE(s.message, ''), COALESCE(s.category, ''),
+		       s.repeat_interval, s.next_run_at, s.active, s.created_at
+		  FROM scheduled_transfers s
+		  JOIN users r ON s.receiver_id = r.id
+		 WHERE s.user_id = $1
+		   AND s.cancelled_at IS NULL
+		 ORDER BY s.id
+	`, uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.ScheduledTransfer
+	for rows.Next() {
+		var s models.ScheduledTransfer
+		scanErr := rows.Scan(&s.ID, &s.ToUser, &s.Amount, &s.Message, &s.Category, &s.Interval, &s.NextRunAt, &s.Active, &s.CreatedAt)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		result = append(result, s)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return result, nil
+}
+
+func (db *DataBase) UpdateSchedule(ctx context.Context, uuid string, scheduleID int, update models.ScheduleUpdate) (*models.ScheduledTransfer, error) {
+	schedule := &models.ScheduledTransfer{}
+	err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+		UPDATE scheduled_transfers
+		   SET amount = COALESCE($1, amount),
+		       repeat_interval = COALESCE($2, repeat_interval),
+		       next_run_at = COALESCE($3, next_run_at),
+		       active = COALESCE($4, active)
+		 WHERE id = $5
+		   AND user_id = $6
+		   AND cancelled_at IS NULL
+		RETURNING id, amount, COALESCE(message, ''), COALESCE(category, ''), repeat_interval, next_run_at, active, created_at
+	`, update.Amount, update.Interval, update.NextRunAt, update.Active, scheduleID, uuid).
+			Scan(&schedule.ID, &schedule.Amount, &schedule.Message, &schedule.Category, &schedule.Interval, &schedule.NextRunAt, &schedule.Active, &schedule.CreatedAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrScheduleNotFound
+			}
+			return err
+		}
+		return tx.QueryRowContext(ctx, `
+		SELECT r.username
+		  FROM scheduled_transfers s
+		  JOIN users r ON s.receiver_id = r.id
+		 WHERE s.id = $1
+	`, scheduleID).Scan(&schedule.ToUser)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// CancelSchedule stops the transfer for good, its runs are kept.
+func (db *DataBase) CancelSchedule(ctx context.Context, uuid string, scheduleID int) error {
+	res, err := db.Tm.DB.ExecContext(ctx, `
+		UPDATE scheduled_transfers
+		   SET cancelled_at = CURRENT_TIMESTAMP,
+		       active = FALSE
+		 WHERE id = $1
+		   AND user_id = $2
+		   AND cancelled_at IS NULL
+	`, scheduleID, uuid)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+func (db *DataBase) GetScheduleRuns(ctx context.Context, uuid string, scheduleID int) ([]models.ScheduledRun, error) {
+	var exists bool
+	err := db.Tm.DB.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1
+		                 FROM scheduled_transfers
+		                WHERE id = $1
+		                  AND user_id = $2)
+	`, scheduleID, uuid).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrScheduleNotFound
+	}
+
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT id, schedule_id, scheduled_at, executed_at, status, COALESCE(error, ''), COALESCE(transaction_id, 0)
+		  FROM scheduled_runs
+		 WHERE schedule_id = $1
+		 ORDER BY id DESC
+	`, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.ScheduledRun
+	for rows.Next() {
+		var run models.ScheduledRun
+		scanErr := rows.Scan(&run.ID, &run.ScheduleID, &run.ScheduledAt, &run.ExecutedAt, &run.Status, &run.Error, &run.TransactionID)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		result = append(result, run)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return result, nil
+}
+
+// RunDueSchedules executes up to limit transfers due at now. Every transfer
+// runs in its own serializable transaction, like a transfer made by hand,
+// holding the schedule row with SKIP LOCKED, so several instances can run the
+// scheduler against one database.
+func (db *DataBase) RunDueSchedules(ctx context.Context, now time.Time, limit int) ([]models.ScheduledRun, error) {
+	var runs []models.ScheduledRun
+	for len(runs) < limit {
+		var run models.ScheduledRun
+		err := db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+			var err error
+			run, err = db.runDueScheduleTx(ctx, tx, now)
+			return err
+		})
+		if errors.Is(err, errNoDueSchedule) {
+			break
+		}
+		if err != nil {
+			return runs, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func (db *DataBase) runDueScheduleTx(ctx context.Context, tx *sql.Tx, now time.Time) (models.ScheduledRun, error) {
+	var run models.ScheduledRun
+	var senderUuid, toUser, interval string
+	var amount int
+	var note models.TransferNote
+	err := tx.QueryRowContext(ctx, `
+		SELECT s.id, s.user_id, r.username, s.amount, COALESCE(s.message, ''), COALESCE(s.category, ''),
+		       s.repeat_interval, s.next_run_at
+		  FROM scheduled_transfers s
+		  JOIN users r ON s.receiver_id = r.id
+		 WHERE s.active
+		   AND s.cancelled_at IS NULL
+		   AND s.next_run_at <= $1
+		 ORDER BY s.next_run_at
+		 LIMIT 1
+		   FOR UPDATE OF s SKIP LOCKED
+	`, now).Scan(&run.ScheduleID, &senderUuid, &toUser, &amount, &note.Message, &note.Category, &interval, &run.ScheduledAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return run, errNoDueSchedule
+		}
+		return run, err
+	}
+
+	// a failed transfer is rolled back to the savepoint and recorded as a failed run
+	_, err = tx.ExecContext(ctx, `SAVEPOINT scheduled_transfer`)
+	if err != nil {
+		return run, err
+	}
+	var transactionID sql.NullInt64
+	id, sendErr := db.sendTx(ctx, tx, senderUuid, toUser, amount, note)
+	// a conflict with a concurrent transfer retries the whole run instead of failing it
+	if isSerializationFailure(sendErr) {
+		return run, sendErr
+	}
+	if sendErr != nil {
+		_, err = tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT scheduled_transfer`)
+		if err != nil {
+			return run, err
+		}
+		run.Status = models.RunFailed
+		run.Error = runError(sendErr)
+	} else {
+		run.Status = models.RunSucceeded
+		run.TransactionID = id
+		transactionID = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+
+	var runErr sql.NullString
+	if run.Error != "" {
+		runErr = sql.NullString{String: run.Error, Valid: true}
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO scheduled_runs (schedule_id, scheduled_at, status, error, transaction_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, executed_at
+	`, run.ScheduleID, run.ScheduledAt, run.Status, runErr, transactionID).Scan(&run.ID, &run.ExecutedAt)
+	if err != nil {
+		return run, err
+	}
+
+	next := advanceRun(interval, run.ScheduledAt, now)
+	_, err = tx.ExecContext(ctx, `
+		UPDATE scheduled_transfers
+		   SET next_run_at = $1,
+		       active = $2
+		 WHERE id = $3
+	`, next, next != nil, run.ScheduleID)
+	if err != nil {
+		return run, err
+	}
+	return run, nil
+}
+
+// advanceRun returns the first run after now. Runs missed while no scheduler
+// was running are skipped, so a late transfer is made once.
+func advanceRun(interval string, runAt time.Time, now time.Time) *time.Time {
+	for {
+		switch interval {
+		case models.IntervalDaily:
+			runAt = runAt.AddDate(0, 0, 1)
+		case models.IntervalWeekly:
+			runAt = runAt.AddDate(0, 0, 7)
+		case models.IntervalMonthly:
+			runAt = runAt.AddDate(0, 1, 0)
+		default:
+			return nil
+		}
+		if runAt.After(now) {
+			return &runAt
+		}
+	}
+}
+
+// runError is the reason of a failed run shown to the user
+func runError(err error) string {
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		return limitErr.Error()
+	}
+	for _, known := range []error{ErrNotEnoughBalance, ErrUserNotFound, ErrSendingToYourself} {
+		if errors.Is(err, known) {
+			return known.Error()
+		}
+	}
+	return "transfer failed"
+}
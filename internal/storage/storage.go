@@ -6,6 +6,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strconv"
 )
 
 type Storage interface {
@@ -14,7 +15,7 @@ type Storage interface {
 
 	GetInfo(ctx context.Context, uuid string) (*models.Info, error)
 
-	Send(ctx context.Context, uuid string, toUser string, amount int) error
+	Send(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote) error
 
 	Buy(ctx context.Context, uuid string, item string) error
 
@@ -31,13 +32,13 @@ func NewStorage(DatabaseDSN string) *DataBase {
 
 func (db *DataBase) GetUser(ctx context.Context, username string) (*models.User, error) {
 	row := db.Tm.DB.QueryRowContext(ctx, `
-		SELECT id, username, password_hash, balance, created_at
+		SELECT id, username, password_hash, role, balance, created_at
 		FROM users
 		WHERE username = $1
 	`, username)
 
 	user := &models.User{}
-	err := row.Scan(&user.UUID, &user.Username, &user.PasswordHash, &user.Balance, &user.CreatedAt)
+	err := row.Scan(&user.UUID, &user.Username, &user.PasswordHash, &user.Role, &user.Balance, &user.CreatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
@@ -48,11 +49,70 @@ func (db *DataBase) GetUser(ctx context.Context, username string) (*models.User,
 }
 
 func (db *DataBase) CreateUser(ctx context.Context, user *models.User) error {
-	_, err := db.Tm.DB.ExecContext(ctx, `
-		INSERT INTO users (id, username, password_hash, balance)
-		VALUES ($1, $2, $3, $4)
-	`, user.UUID, user.Username, user.PasswordHash, user.Balance)
-	return err
+	return db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
+		role := user.Role
+		if role == "" {
+			role = models.RoleUser
+		}
+		_, err := tx.ExecContext(ctx, `
+		INSERT INTO users (id, username, password_hash, role, balance)
+		VALUES ($1, $2, $3, $4, 0)
+	`, user.UUID, user.Username, user.PasswordHash, role)
+		if err != nil {
+			return err
+		}
+		uuid := user.UUID.String()
+		err = db.createWalletAccount(ctx, tx, uuid)
+		if err != nil {
+			return err
+		}
+		if user.Balance == 0 {
+			return nil
+		}
+		// starting coins are issued through the ledger so the total stays balanced
+		_, err = db.postEntry(ctx, tx, EntryIssuance, "signup",
+			posting{account: AccountIssuance, amount: -user.Balance},
+			posting{account: WalletAccount(uuid), amount: user.Balance},
+		)
+		return err
+	})
+}
+
+func (db *DataBase) GetUserRole(ctx context.Context, uuid string) (string, error) {
+	var role string
+	err := db.Tm.DB.QueryRowContext(ctx, `
+		SELECT role
+		  FROM users
+		 WHERE id = $1
+	`, uuid).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+// SetUserRole changes the role of a registered user, the system issuer is not one
+func (db *DataBase) SetUserRole(ctx context.Context, uuid string, role string) error {
+	res, err := db.Tm.DB.ExecContext(ctx, `
+		UPDATE users
+		   SET role = $1
+		 WHERE id = $2
+		   AND role <> 'system'
+	`, role, uuid)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
 }
 
 func (db *DataBase) GetInfo(ctx context.Context, uuid string) (*models.Info, error) {
@@ -86,82 +146,97 @@ func (db *DataBase) GetInfo(ctx context.Context, uuid string) (*models.Info, err
 		Received: received,
 		Sent:     sent,
 	}
+	info.GiftHistory, err = db.getGiftHistory(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
 	inventory, err := db.getUserInventory(ctx, uuid)
 	if err != nil {
 		return nil, err
 	}
 	info.Inventory = inventory
+	expiring, err := db.getExpiringLots(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	info.Expiring = expiring
 
 	return info, nil
 }
 
-func (db *DataBase) Send(ctx context.Context, uuid string, toUser string, amount int) error {
+func (db *DataBase) Send(ctx context.Context, uuid string, toUser string, amount int, note models.TransferNote) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
 	return db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
-		balance, err := db.getBalanceTx(ctx, tx, uuid)
+		_, err := db.sendTx(ctx, tx, uuid, toUser, amount, note)
 		if err != nil {
 			return err
 		}
-		if balance-amount < 0 {
-			return ErrNotEnoughBalance
-		}
-		var receiverUuid string
-		err = tx.QueryRowContext(ctx, `
+		return db.completeIdempotencyKeyTx(ctx, tx)
+	})
+}
+
+// sendTx moves amount coins to toUser and returns the transaction id
+func (db *DataBase) sendTx(ctx context.Context, tx *sql.Tx, uuid string, toUser string, amount int, note models.TransferNote) (int, error) {
+	balance, err := db.getBalanceTx(ctx, tx, uuid)
+	if err != nil {
+		return 0, err
+	}
+	if balance-amount < 0 {
+		return 0, ErrNotEnoughBalance
+	}
+	receiverUuid, err := db.getUuidByUsernameTx(ctx, tx, toUser)
+	if err != nil {
+		return 0, err
+	}
+	if uuid == receiverUuid {
+		return 0, ErrSendingToYourself
+	}
+	err = db.checkSendLimitTx(ctx, tx, uuid, amount)
+	if err != nil {
+		return 0, err
+	}
+	transactionID, err := db.createTransaction(ctx, tx, uuid, receiverUuid, amount, note)
+	if err != nil {
+		return 0, err
+	}
+	_, err = db.postEntry(ctx, tx, EntryTransfer, strconv.Itoa(transactionID),
+		posting{account: WalletAccount(uuid), amount: -amount},
+		posting{account: WalletAccount(receiverUuid), amount: amount},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return transactionID, nil
+}
+
+func (db *DataBase) getUuidByUsernameTx(ctx context.Context, tx *sql.Tx, username string) (string, error) {
+	var userUUID string
+	err := tx.QueryRowContext(ctx, `
 		SELECT id
 		  FROM users
 		 WHERE username = $1
-	`, toUser).Scan(&receiverUuid)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				return ErrUserNotFound
-			}
-			return err
-		}
-		if uuid == receiverUuid {
-			return ErrSendingToYourself
-		}
-		err = db.updateBalance(ctx, tx, uuid, balance-amount)
-		if err != nil {
-			return err
-		}
-		balance, err = db.getBalanceTx(ctx, tx, receiverUuid)
-		if err != nil {
-			return err
-		}
-		err = db.updateBalance(ctx, tx, receiverUuid, balance+amount)
-		if err != nil {
-			return err
-		}
-		err = db.createTransaction(ctx, tx, uuid, receiverUuid, amount)
-		if err != nil {
-			return err
+		   AND role <> 'system'
+	`, username).Scan(&userUUID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
 		}
-		return nil
-	})
+		return "", err
+	}
+	return userUUID, nil
 }
 
+// Buy is a single item order, so every purchase gets a record with the
+// price paid.
 func (db *DataBase) Buy(ctx context.Context, uuid string, item string) error {
 	return db.Tm.WriteTXSerialize(ctx, func(tx *sql.Tx) error {
-		balance, err := db.getBalanceTx(ctx, tx, uuid)
-		if err != nil {
-			return err
-		}
-		var itemPrice int
-		err = tx.QueryRowContext(ctx, "SELECT price FROM merchandise WHERE name = $1", item).Scan(&itemPrice)
+		_, err := db.placeOrderTx(ctx, tx, uuid, []models.OrderLine{{Item: item, Quantity: 1}})
 		if err != nil {
 			return err
 		}
-		if balance-itemPrice < 0 {
-			return ErrNotEnoughBalance
-		}
-		err = db.updateBalance(ctx, tx, uuid, balance-itemPrice)
-		if err != nil {
-			return err
-		}
-		err = db.updateInventory(ctx, tx, uuid, item)
-		if err != nil {
-			return err
-		}
-		return nil
+		return db.completeIdempotencyKeyTx(ctx, tx)
 	})
 }
 
@@ -187,6 +262,7 @@ func (db *DataBase) GetUuidByUsername(ctx context.Context, username string) (str
 		SELECT id
 		  FROM users
 		 WHERE username = $1
+		   AND role <> 'system'
 	`, username).Scan(&userUUID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -229,52 +305,25 @@ func (db *DataBase) getBalanceTx(ctx context.Context, tx *sql.Tx, uuid string) (
 	return balance, nil
 }
 
-func (db *DataBase) updateBalance(ctx context.Context, tx *sql.Tx, uuid string, updatedBalance int) error {
-	_, err := tx.ExecContext(ctx, `
-		UPDATE users
-		   SET balance = $1
-		 WHERE id = $2
-	`, updatedBalance, uuid)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return ErrUserNotFound
-		}
-		return err
-	}
-	return err
-}
-
-func (db *DataBase) createTransaction(ctx context.Context, tx *sql.Tx, senderId, receiverId string, amount int) error {
-	_, err := tx.ExecContext(ctx, `
-        INSERT INTO transactions (sender_id, receiver_id, amount)
-        VALUES ($1, $2, $3)
-    `, senderId, receiverId, amount)
+func (db *DataBase) createTransaction(ctx context.Context, tx *sql.Tx, senderId, receiverId string, amount int, note models.TransferNote) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx, `
+        INSERT INTO transactions (sender_id, receiver_id, amount, message, category)
+        VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+        RETURNING id
+    `, senderId, receiverId, amount, note.Message, note.Category).Scan(&id)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	return id, nil
 }
 
-func (db *DataBase) updateInventory(ctx context.Context, tx *sql.Tx, uuid string, item string) error {
-	var merchID int
-
-	// get merchID
-	err := tx.QueryRowContext(ctx, `
-		SELECT id FROM merchandise
-		 WHERE name = $1
-	`, item).Scan(&merchID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return ErrItemNotFound
-		}
-		return err
-	}
-
+func (db *DataBase) addInventoryTx(ctx context.Context, tx *sql.Tx, uuid string, merchID int, quantity int) error {
 	// check is this user have this merchId
 	var existingID string
 	var existingQty int
-	err = tx.QueryRowContext(ctx, `
+	err := tx.QueryRowContext(ctx, `
 		SELECT id, quantity
 		  FROM user_inventory
 		 WHERE user_id = $1
@@ -285,8 +334,8 @@ func (db *DataBase) updateInventory(ctx context.Context, tx *sql.Tx, uuid string
 	if errors.Is(err, sql.ErrNoRows) {
 		_, insertErr := tx.ExecContext(ctx, `
 			INSERT INTO user_inventory (user_id, merchandise_id, quantity)
-			VALUES ($1, $2, 1)
-		`, uuid, merchID)
+			VALUES ($1, $2, $3)
+		`, uuid, merchID, quantity)
 		return insertErr
 	} else if err != nil {
 		return err
@@ -296,16 +345,21 @@ func (db *DataBase) updateInventory(ctx context.Context, tx *sql.Tx, uuid string
 		UPDATE user_inventory
 		   SET quantity = $1
 		 WHERE id = $2
-	`, existingQty+1, existingID)
+	`, existingQty+quantity, existingID)
 
 	return updateErr
 }
 
 func (db *DataBase) getReceivedTransactions(ctx context.Context, uuid string) ([]models.Transaction, error) {
 	rows, err := db.Tm.DB.QueryContext(ctx, `
-		SELECT sender.username AS from_user,
+		SELECT t.id,
+		       sender.username AS from_user,
 		       receiver.username AS to_user,
-		       t.amount
+		       t.amount,
+		       t.created_at,
+		       COALESCE(t.reverses_id, 0),
+		       COALESCE(t.message, ''),
+		       COALESCE(t.category, '')
 		  FROM transactions t
 		  JOIN users sender   ON t.sender_id = sender.id
 		  JOIN users receiver ON t.receiver_id = receiver.id
@@ -320,7 +374,7 @@ func (db *DataBase) getReceivedTransactions(ctx context.Context, uuid string) ([
 	var result []models.Transaction
 	for rows.Next() {
 		var tr models.Transaction
-		if scanErr := rows.Scan(&tr.FromUser, &tr.ToUser, &tr.Amount); scanErr != nil {
+		if scanErr := rows.Scan(&tr.ID, &tr.FromUser, &tr.ToUser, &tr.Amount, &tr.CreatedAt, &tr.ReversesID, &tr.Message, &tr.Category); scanErr != nil {
 			return nil, scanErr
 		}
 		result = append(result, tr)
@@ -333,9 +387,14 @@ func (db *DataBase) getReceivedTransactions(ctx context.Context, uuid string) ([
 
 func (db *DataBase) getSentTransactions(ctx context.Context, uuid string) ([]models.Transaction, error) {
 	rows, err := db.Tm.DB.QueryContext(ctx, `
-		SELECT sender.username AS from_user,
+		SELECT t.id,
+		       sender.username AS from_user,
 		       receiver.username AS to_user,
-		       t.amount
+		       t.amount,
+		       t.created_at,
+		       COALESCE(t.reverses_id, 0),
+		       COALESCE(t.message, ''),
+		       COALESCE(t.category, '')
 		  FROM transactions t
 		  JOIN users sender   ON t.sender_id = sender.id
 		  JOIN users receiver ON t.receiver_id = receiver.id
@@ -350,7 +409,7 @@ func (db *DataBase) getSentTransactions(ctx context.Context, uuid string) ([]mod
 	var result []models.Transaction
 	for rows.Next() {
 		var tr models.Transaction
-		if scanErr := rows.Scan(&tr.FromUser, &tr.ToUser, &tr.Amount); scanErr != nil {
+		if scanErr := rows.Scan(&tr.ID, &tr.FromUser, &tr.ToUser, &tr.Amount, &tr.CreatedAt, &tr.ReversesID, &tr.Message, &tr.Category); scanErr != nil {
 			return nil, scanErr
 		}
 		result = append(result, tr)
@@ -5,8 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
-	"github.com/lib/pq"
 	"log"
 )
 
@@ -40,11 +40,12 @@ func (t *Transactor) WriteTXSerialize(ctx context.Context, fn func(*sql.Tx) erro
 			if rbErr != nil {
 				return fmt.Errorf("failed to rollback: %v, original error: %w", rbErr, err)
 			}
+			// concurrent serializable transactions may fail on any statement, not only on commit
+			if isSerializationError(err) {
+				continue
+			}
 			return err
 		}
-		if isSerializationError(err) {
-			continue
-		}
 
 		err = tx.Commit()
 		if err != nil {
@@ -59,10 +60,52 @@ func (t *Transactor) WriteTXSerialize(ctx context.Context, fn func(*sql.Tx) erro
 	return errors.New("faild to send tx")
 }
 
+// WriteTX runs fn in a read committed transaction without retries. It is meant
+// for row locking work such as SELECT ... FOR UPDATE SKIP LOCKED.
+func (t *Transactor) WriteTX(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := t.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		rbErr := tx.Rollback()
+		if rbErr != nil {
+			return fmt.Errorf("failed to rollback: %v, original error: %w", rbErr, err)
+		}
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// ReadTX runs fn in a read only transaction that sees a single snapshot.
+func (t *Transactor) ReadTX(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := t.DB.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func isSerializationError(err error) bool {
-	var pqErr *pq.Error
-	if errors.As(err, &pqErr) {
-		return pqErr.Code == "40001"
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001"
 	}
 	return false
 }
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"avito/internal/models"
+	"context"
+	"strconv"
+	"strings"
+)
+
+// GetTransactions returns user's transactions matching the filter, newest first.
+func (db *DataBase) GetTransactions(ctx context.Context, uuid string, filter models.TransactionFilter) ([]models.Transaction, error) {
+	args := []any{uuid}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var conditions []string
+	switch filter.Direction {
+	case models.DirectionSent:
+		conditions = append(conditions, "t.sender_id = $1")
+		if filter.Counterparty != "" {
+			conditions = append(conditions, "receiver.username = "+arg(filter.Counterparty))
+		}
+	case models.DirectionReceived:
+		conditions = append(conditions, "t.receiver_id = $1")
+		if filter.Counterparty != "" {
+			conditions = append(conditions, "sender.username = "+arg(filter.Counterparty))
+		}
+	default:
+		conditions = append(conditions, "(t.sender_id = $1 OR t.receiver_id = $1)")
+		if filter.Counterparty != "" {
+			conditions = append(conditions, `(CASE WHEN t.sender_id = $1
+			                                   THEN receiver.username
+			                                   ELSE sender.username END) = `+arg(filter.Counterparty))
+		}
+	}
+	if filter.Category != "" {
+		conditions = append(conditions, "t.category = "+arg(filter.Category))
+	}
+	if filter.MinAmount != nil {
+		conditions = append(conditions, "t.amount >= "+arg(*filter.MinAmount))
+	}
+	if filter.MaxAmount != nil {
+		conditions = append(conditions, "t.amount <= "+arg(*filter.MaxAmount))
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "t.created_at >= "+arg(filter.From.UTC()))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "t.created_at < "+arg(filter.To.UTC()))
+	}
+	if filter.BeforeID > 0 {
+		conditions = append(conditions, "t.id < "+arg(filter.BeforeID))
+	}
+
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT t.id,
+		       sender.username AS from_user,
+		       receiver.username AS to_user,
+		       t.amount,
+		       t.created_at,
+		       COALESCE(t.reverses_id, 0),
+		       COALESCE(t.message, ''),
+		       COALESCE(t.category, '')
+		  FROM transactions t
+		  JOIN users sender   ON t.sender_id = sender.id
+		  JOIN users receiver ON t.receiver_id = receiver.id
+		 WHERE `+strings.Join(conditions, "\n\t\t   AND ")+`
+		 ORDER BY t.id DESC
+		 LIMIT `+arg(filter.Limit), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Transaction
+	for rows.Next() {
+		var tr models.Transaction
+		if scanErr := rows.Scan(&tr.ID, &tr.FromUser, &tr.ToUser, &tr.Amount, &tr.CreatedAt, &tr.ReversesID, &tr.Message, &tr.Category); scanErr != nil {
+			return nil, scanErr
+		}
+		result = append(result, tr)
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return result, nil
+}
+
+// GetCounterparties sums the user's transfers per counterparty and direction.
+func (db *DataBase) GetCounterparties(ctx context.Context, uuid string) (*models.Counterparties, error) {
+	rows, err := db.Tm.DB.QueryContext(ctx, `
+		SELECT 'received', sender.username, SUM(t.amount), COUNT(*)
+		  FROM transactions t
+		  JOIN users sender ON t.sender_id = sender.id
+		 WHERE t.receiver_id = $1
+		 GROUP BY sender.username
+		 UNION ALL
+		SELECT 'sent', receiver.username, SUM(t.amount), COUNT(*)
+		  FROM transactions t
+		  JOIN users receiver ON t.receiver_id = receiver.id
+		 WHERE t.sender_id = $1
+		 GROUP BY receiver.username
+		 ORDER BY 3 DESC, 2
+	`, uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &models.Counterparties{
+		Received: []models.CounterpartyTotal{},
+		Sent:     []models.CounterpartyTotal{},
+	}
+	for rows.Next() {
+		var direction string
+		var total models.CounterpartyTotal
+		if scanErr := rows.Scan(&direction, &total.User, &total.Amount, &total.Count); scanErr != nil {
+			return nil, scanErr
+		}
+		if direction == models.DirectionSent {
+			result.Sent = append(result.Sent, total)
+		} else {
+			result.Received = append(result.Received, total)
+		}
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	return result, nil
+}
@@ -0,0 +1,15 @@
+package utils
+
+import "time"
+
+const DateLayout = "2006-01-02"
+
+// ParseTime accepts RFC 3339 timestamps and plain dates, the flag tells
+// which one it was.
+func ParseTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(DateLayout, value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
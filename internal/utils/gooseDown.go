@@ -17,6 +17,6 @@ func GooseDown(db *sql.DB) error {
 	basePath := filepath.Dir(filename)
 	migrationsPath := filepath.Join(basePath, "../../migrations")
 
-	err := goose.Down(db, migrationsPath)
+	err := goose.Reset(db, migrationsPath)
 	return err
 }
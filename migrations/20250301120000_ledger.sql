@@ -0,0 +1,65 @@
+-- +goose Up
+CREATE TABLE Ledger_Accounts (
+                                 id SERIAL PRIMARY KEY,
+                                 code VARCHAR(100) UNIQUE NOT NULL,
+                                 user_id UUID UNIQUE REFERENCES Users(id),
+                                 created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Системные счета: источник эмиссии монет и выручка магазина мерча
+INSERT INTO Ledger_Accounts (code) VALUES
+                                       ('system:issuance'),
+                                       ('system:store');
+
+INSERT INTO Ledger_Accounts (code, user_id)
+SELECT 'wallet:' || id, id
+  FROM Users;
+
+CREATE TABLE Ledger_Entries (
+                                id SERIAL PRIMARY KEY,
+                                kind VARCHAR(32) NOT NULL,
+                                reference VARCHAR(100),
+                                created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE Ledger_Postings (
+                                 id SERIAL PRIMARY KEY,
+                                 entry_id INT NOT NULL REFERENCES Ledger_Entries(id),
+                                 account_id INT NOT NULL REFERENCES Ledger_Accounts(id),
+                                 amount INT NOT NULL CHECK (amount <> 0)
+);
+
+CREATE INDEX idx_ledger_postings_entry_id
+    ON Ledger_Postings (entry_id);
+
+CREATE INDEX idx_ledger_postings_account_id
+    ON Ledger_Postings (account_id);
+
+-- Переносим текущие балансы в леджер одной проводкой из счета эмиссии
+INSERT INTO Ledger_Entries (kind, reference)
+SELECT 'opening', 'migration'
+ WHERE EXISTS (SELECT 1 FROM Users WHERE balance <> 0);
+
+INSERT INTO Ledger_Postings (entry_id, account_id, amount)
+SELECT e.id, a.id, u.balance
+  FROM Users u
+  JOIN Ledger_Accounts a ON a.user_id = u.id
+  JOIN Ledger_Entries e ON e.kind = 'opening'
+ WHERE u.balance <> 0;
+
+INSERT INTO Ledger_Postings (entry_id, account_id, amount)
+SELECT e.id, a.id, -SUM(u.balance)
+  FROM Users u
+  JOIN Ledger_Entries e ON e.kind = 'opening'
+  JOIN Ledger_Accounts a ON a.code = 'system:issuance'
+ WHERE u.balance <> 0
+ GROUP BY e.id, a.id;
+
+ALTER TABLE Users
+    ADD CONSTRAINT users_balance_non_negative CHECK (balance >= 0);
+
+-- +goose Down
+ALTER TABLE Users DROP CONSTRAINT IF EXISTS users_balance_non_negative;
+DROP TABLE IF EXISTS Ledger_Postings;
+DROP TABLE IF EXISTS Ledger_Entries;
+DROP TABLE IF EXISTS Ledger_Accounts;
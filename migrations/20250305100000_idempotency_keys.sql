@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE Idempotency_Keys (
+                                  user_id UUID NOT NULL REFERENCES Users(id),
+                                  key VARCHAR(255) NOT NULL,
+                                  request_hash VARCHAR(64) NOT NULL,
+                                  response_status INT,
+                                  response_content_type VARCHAR(100),
+                                  response_body BYTEA,
+                                  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                  PRIMARY KEY (user_id, key)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS Idempotency_Keys;
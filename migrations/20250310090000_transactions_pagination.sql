@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE INDEX idx_transactions_sender_id_id
+    ON Transactions (sender_id, id DESC);
+
+CREATE INDEX idx_transactions_receiver_id_id
+    ON Transactions (receiver_id, id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_transactions_receiver_id_id;
+DROP INDEX IF EXISTS idx_transactions_sender_id_id;
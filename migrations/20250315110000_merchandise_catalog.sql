@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE Merchandise
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN available BOOLEAN NOT NULL DEFAULT TRUE;
+
+UPDATE Merchandise SET description = 'Cotton t-shirt with the company logo' WHERE name = 't-shirt';
+UPDATE Merchandise SET description = 'Ceramic mug for coffee or tea' WHERE name = 'cup';
+UPDATE Merchandise SET description = 'Notebook with a branded cover' WHERE name = 'book';
+UPDATE Merchandise SET description = 'Ballpoint pen' WHERE name = 'pen';
+UPDATE Merchandise SET description = 'Portable 10000 mAh battery' WHERE name = 'powerbank';
+UPDATE Merchandise SET description = 'Warm hoody with the company logo' WHERE name = 'hoody';
+UPDATE Merchandise SET description = 'Folding umbrella' WHERE name = 'umbrella';
+UPDATE Merchandise SET description = 'A pair of branded socks' WHERE name = 'socks';
+UPDATE Merchandise SET description = 'Leather wallet' WHERE name = 'wallet';
+UPDATE Merchandise SET description = 'Limited edition pink hoody' WHERE name = 'pink-hoody';
+
+-- +goose Down
+ALTER TABLE Merchandise
+    DROP COLUMN IF EXISTS available,
+    DROP COLUMN IF EXISTS description;
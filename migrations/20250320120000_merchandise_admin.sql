@@ -0,0 +1,36 @@
+-- +goose Up
+ALTER TABLE Users
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user';
+
+ALTER TABLE Merchandise
+    ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN retired_at TIMESTAMP;
+
+CREATE TABLE Merchandise_History (
+                                     id SERIAL PRIMARY KEY,
+                                     merchandise_id INT NOT NULL REFERENCES Merchandise(id),
+                                     action VARCHAR(20) NOT NULL,
+                                     name VARCHAR(100) NOT NULL,
+                                     price INT NOT NULL,
+                                     description TEXT NOT NULL,
+                                     available BOOLEAN NOT NULL,
+                                     hidden BOOLEAN NOT NULL,
+                                     changed_by UUID REFERENCES Users(id),
+                                     changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_merchandise_history_merchandise_id
+    ON Merchandise_History (merchandise_id);
+
+-- Стартовый каталог из первой миграции
+INSERT INTO Merchandise_History (merchandise_id, action, name, price, description, available, hidden)
+SELECT id, 'create', name, price, description, available, hidden
+  FROM Merchandise;
+
+-- +goose Down
+DROP TABLE IF EXISTS Merchandise_History;
+ALTER TABLE Merchandise
+    DROP COLUMN IF EXISTS retired_at,
+    DROP COLUMN IF EXISTS hidden;
+ALTER TABLE Users
+    DROP COLUMN IF EXISTS role;
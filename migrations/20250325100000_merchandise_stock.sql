@@ -0,0 +1,16 @@
+-- +goose Up
+-- NULL означает неограниченный запас
+ALTER TABLE Merchandise
+    ADD COLUMN stock INT CHECK (stock >= 0);
+
+ALTER TABLE Merchandise_History
+    ADD COLUMN stock INT;
+
+-- розовое худи выпускается ограниченной партией
+UPDATE Merchandise SET stock = 10 WHERE name = 'pink-hoody';
+
+-- +goose Down
+ALTER TABLE Merchandise_History
+    DROP COLUMN IF EXISTS stock;
+ALTER TABLE Merchandise
+    DROP COLUMN IF EXISTS stock;
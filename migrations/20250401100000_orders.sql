@@ -0,0 +1,27 @@
+-- +goose Up
+-- заказ из нескольких позиций, оплаченный одной записью в леджере
+CREATE TABLE Orders (
+                        id SERIAL PRIMARY KEY,
+                        user_id UUID NOT NULL REFERENCES Users(id),
+                        total INT NOT NULL,
+                        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_orders_user_id
+    ON Orders (user_id);
+
+CREATE TABLE Order_Items (
+                             id SERIAL PRIMARY KEY,
+                             order_id INT NOT NULL REFERENCES Orders(id),
+                             merchandise_id INT NOT NULL REFERENCES Merchandise(id),
+                             quantity INT NOT NULL CHECK (quantity > 0),
+                             -- цена на момент покупки
+                             unit_price INT NOT NULL
+);
+
+CREATE INDEX idx_order_items_order_id
+    ON Order_Items (order_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS Order_Items;
+DROP TABLE IF EXISTS Orders;
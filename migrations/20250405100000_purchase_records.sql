@@ -0,0 +1,49 @@
+-- +goose Up
+-- Покупки, сделанные до появления заказов, есть только в леджере (reference = название товара).
+-- Переносим их в Orders, чтобы история покупок совпадала с инвентарем.
+-- Покупки до перехода на леджер и переименованные с тех пор товары восстановить нельзя.
+-- ledger_entry_id остается у перенесенных заказов, по нему их находит откат.
+ALTER TABLE Orders
+    ADD COLUMN ledger_entry_id INT REFERENCES Ledger_Entries(id);
+
+INSERT INTO Orders (user_id, total, created_at, ledger_entry_id)
+SELECT a.user_id, -p.amount, e.created_at, e.id
+  FROM Ledger_Entries e
+  JOIN Ledger_Postings p ON p.entry_id = e.id
+  JOIN Ledger_Accounts a ON p.account_id = a.id AND a.user_id IS NOT NULL
+  JOIN Merchandise m ON m.name = e.reference
+ WHERE e.kind = 'purchase'
+   AND e.reference NOT LIKE 'order:%'
+ ORDER BY e.id;
+
+INSERT INTO Order_Items (order_id, merchandise_id, quantity, unit_price)
+SELECT o.id, m.id, 1, o.total
+  FROM Orders o
+  JOIN Ledger_Entries e ON o.ledger_entry_id = e.id
+  JOIN Merchandise m ON m.name = e.reference
+ ORDER BY o.id;
+
+UPDATE Ledger_Entries e
+   SET reference = 'order:' || o.id
+  FROM Orders o
+ WHERE o.ledger_entry_id = e.id;
+
+-- +goose Down
+-- перенесенные покупки возвращаются в леджер под названием товара
+UPDATE Ledger_Entries e
+   SET reference = m.name
+  FROM Orders o
+  JOIN Order_Items i ON i.order_id = o.id
+  JOIN Merchandise m ON i.merchandise_id = m.id
+ WHERE o.ledger_entry_id = e.id;
+
+DELETE FROM Order_Items i
+ USING Orders o
+ WHERE i.order_id = o.id
+   AND o.ledger_entry_id IS NOT NULL;
+
+DELETE FROM Orders
+ WHERE ledger_entry_id IS NOT NULL;
+
+ALTER TABLE Orders
+    DROP COLUMN ledger_entry_id;
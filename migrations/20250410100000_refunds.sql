@@ -0,0 +1,25 @@
+-- +goose Up
+-- сколько единиц позиции уже возвращено
+ALTER TABLE Order_Items
+    ADD COLUMN refunded INT NOT NULL DEFAULT 0;
+
+ALTER TABLE Order_Items
+    ADD CONSTRAINT order_items_refunded_check CHECK (refunded >= 0 AND refunded <= quantity);
+
+-- refunded_by заполняется, если возврат оформил администратор
+CREATE TABLE Refunds (
+                         id SERIAL PRIMARY KEY,
+                         order_item_id INT NOT NULL REFERENCES Order_Items(id),
+                         quantity INT NOT NULL CHECK (quantity > 0),
+                         amount INT NOT NULL,
+                         refunded_by UUID REFERENCES Users(id),
+                         created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_refunds_order_item_id
+    ON Refunds (order_item_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS Refunds;
+ALTER TABLE Order_Items DROP CONSTRAINT IF EXISTS order_items_refunded_check;
+ALTER TABLE Order_Items DROP COLUMN IF EXISTS refunded;
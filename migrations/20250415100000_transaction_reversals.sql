@@ -0,0 +1,15 @@
+-- +goose Up
+-- компенсирующий перевод ссылается на исходный и хранит администратора, который его оформил
+ALTER TABLE Transactions
+    ADD COLUMN reverses_id INT REFERENCES Transactions(id),
+    ADD COLUMN reversed_by UUID REFERENCES Users(id);
+
+CREATE INDEX idx_transactions_reverses_id
+    ON Transactions (reverses_id)
+    WHERE reverses_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_transactions_reverses_id;
+ALTER TABLE Transactions
+    DROP COLUMN IF EXISTS reversed_by,
+    DROP COLUMN IF EXISTS reverses_id;
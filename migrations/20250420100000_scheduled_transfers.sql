@@ -0,0 +1,39 @@
+-- +goose Up
+-- TIMESTAMPTZ, чтобы планировщики на разных инстансах одинаково понимали время запуска
+CREATE TABLE Scheduled_Transfers (
+                                     id SERIAL PRIMARY KEY,
+                                     user_id UUID NOT NULL REFERENCES Users(id),
+                                     receiver_id UUID NOT NULL REFERENCES Users(id),
+                                     amount INT NOT NULL CHECK (amount > 0),
+                                     repeat_interval VARCHAR(16) NOT NULL,
+                                     -- NULL, когда разовый перевод уже выполнен
+                                     next_run_at TIMESTAMPTZ,
+                                     active BOOLEAN NOT NULL DEFAULT TRUE,
+                                     cancelled_at TIMESTAMPTZ,
+                                     created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_scheduled_transfers_user_id
+    ON Scheduled_Transfers (user_id);
+
+-- поиск готовых к запуску переводов планировщиком
+CREATE INDEX idx_scheduled_transfers_due
+    ON Scheduled_Transfers (next_run_at)
+    WHERE active AND cancelled_at IS NULL;
+
+CREATE TABLE Scheduled_Runs (
+                                id SERIAL PRIMARY KEY,
+                                schedule_id INT NOT NULL REFERENCES Scheduled_Transfers(id),
+                                scheduled_at TIMESTAMPTZ NOT NULL,
+                                executed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                status VARCHAR(16) NOT NULL,
+                                error TEXT,
+                                transaction_id INT REFERENCES Transactions(id)
+);
+
+CREATE INDEX idx_scheduled_runs_schedule_id
+    ON Scheduled_Runs (schedule_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS Scheduled_Runs;
+DROP TABLE IF EXISTS Scheduled_Transfers;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- монеты ожидающих переводов лежат на этом счете до принятия, отказа или истечения срока
+INSERT INTO Ledger_Accounts (code) VALUES ('system:escrow')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE Pending_Transfers (
+                                   id SERIAL PRIMARY KEY,
+                                   sender_id UUID NOT NULL REFERENCES Users(id),
+                                   receiver_id UUID NOT NULL REFERENCES Users(id),
+                                   amount INT NOT NULL CHECK (amount > 0),
+                                   status VARCHAR(16) NOT NULL DEFAULT 'pending',
+                                   expires_at TIMESTAMPTZ NOT NULL,
+                                   created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                   resolved_at TIMESTAMPTZ,
+                                   -- перевод, созданный при принятии
+                                   transaction_id INT REFERENCES Transactions(id)
+);
+
+CREATE INDEX idx_pending_transfers_sender_id
+    ON Pending_Transfers (sender_id);
+
+CREATE INDEX idx_pending_transfers_receiver_id
+    ON Pending_Transfers (receiver_id);
+
+CREATE INDEX idx_pending_transfers_expires_at
+    ON Pending_Transfers (expires_at)
+    WHERE status = 'pending';
+
+-- +goose Down
+-- счет system:escrow остается, на него ссылаются проводки
+DROP TABLE IF EXISTS Pending_Transfers;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- необязательное сообщение и категория перевода
+ALTER TABLE Transactions
+    ADD COLUMN message TEXT CHECK (char_length(message) <= 200),
+    ADD COLUMN category VARCHAR(32);
+
+CREATE INDEX idx_transactions_category
+    ON Transactions (category)
+    WHERE category IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_transactions_category;
+ALTER TABLE Transactions
+    DROP COLUMN IF EXISTS category,
+    DROP COLUMN IF EXISTS message;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- лимиты исходящих переводов: глобальный по умолчанию, для роли и для пользователя (subject = id пользователя).
+-- NULL наследуется из более широкой области, 0 снимает ограничение
+CREATE TABLE Send_Limits (
+                             id SERIAL PRIMARY KEY,
+                             scope VARCHAR(16) NOT NULL CHECK (scope IN ('global', 'role', 'user')),
+                             subject VARCHAR(255) NOT NULL DEFAULT '',
+                             per_transfer INT CHECK (per_transfer >= 0),
+                             daily INT CHECK (daily >= 0),
+                             weekly INT CHECK (weekly >= 0),
+                             updated_by UUID REFERENCES Users(id),
+                             updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                             UNIQUE (scope, subject)
+);
+
+CREATE INDEX idx_transactions_sender_id_created_at
+    ON Transactions (sender_id, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_transactions_sender_id_created_at;
+DROP TABLE IF EXISTS Send_Limits;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- системный пользователь, от имени которого начисляются ежемесячные монеты. Имена обычных пользователей
+-- приводятся к нижнему регистру, поэтому войти под ним нельзя, а кошелька у него нет
+INSERT INTO Users (id, username, password_hash, role, balance)
+VALUES ('00000000-0000-0000-0000-000000000000', 'System', '', 'system', 0)
+ON CONFLICT (id) DO NOTHING;
+
+-- одно начисление на пользователя за период (первое число месяца)
+CREATE TABLE Allowance_Issuances (
+                                     period DATE NOT NULL,
+                                     user_id UUID NOT NULL REFERENCES Users(id),
+                                     amount INT NOT NULL CHECK (amount > 0),
+                                     transaction_id INT REFERENCES Transactions(id),
+                                     created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                     PRIMARY KEY (period, user_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS Allowance_Issuances;
+DELETE FROM Users u
+ WHERE u.id = '00000000-0000-0000-0000-000000000000'
+   AND NOT EXISTS (SELECT 1 FROM Transactions t WHERE t.sender_id = u.id);
@@ -0,0 +1,42 @@
+-- +goose Up
+-- баланс пользователя хранится партиями монет, сначала тратятся самые старые.
+-- Партии с expires_at сгорают, если их не потратить до этого срока
+CREATE TABLE Coin_Lots (
+                           id SERIAL PRIMARY KEY,
+                           user_id UUID NOT NULL REFERENCES Users(id),
+                           amount INT NOT NULL CHECK (amount > 0),
+                           remaining INT NOT NULL CHECK (remaining >= 0 AND remaining <= amount),
+                           expires_at TIMESTAMPTZ,
+                           created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_coin_lots_user_id
+    ON Coin_Lots (user_id, created_at, id)
+    WHERE remaining > 0;
+
+CREATE INDEX idx_coin_lots_expires_at
+    ON Coin_Lots (expires_at)
+    WHERE remaining > 0 AND expires_at IS NOT NULL;
+
+-- сколько монет проводка по кошельку взяла из каждой партии. Возврат (отмена покупки, снятие
+-- удержания, отмена перевода) кладет монеты обратно в те же партии со знаком минус по той же
+-- проводке, поэтому возвращенные монеты сгорают в свой срок
+CREATE TABLE Coin_Lot_Uses (
+                               id SERIAL PRIMARY KEY,
+                               entry_id INT NOT NULL REFERENCES Ledger_Entries(id),
+                               lot_id INT NOT NULL REFERENCES Coin_Lots(id),
+                               amount INT NOT NULL CHECK (amount <> 0)
+);
+
+CREATE INDEX idx_coin_lot_uses_entry_id
+    ON Coin_Lot_Uses (entry_id);
+
+-- текущие балансы становятся бессрочными партиями
+INSERT INTO Coin_Lots (user_id, amount, remaining)
+SELECT id, balance, balance
+  FROM Users
+ WHERE balance > 0;
+
+-- +goose Down
+DROP TABLE IF EXISTS Coin_Lot_Uses;
+DROP TABLE IF EXISTS Coin_Lots;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- запуски сверки с исправлением, сама проверка ничего не записывает
+CREATE TABLE Reconcile_Runs (
+                                id SERIAL PRIMARY KEY,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- каждое исправление кошелька: что ожидалось, что было и проводка, если она понадобилась
+CREATE TABLE Reconcile_Adjustments (
+                                       id SERIAL PRIMARY KEY,
+                                       run_id INT NOT NULL REFERENCES Reconcile_Runs(id),
+                                       user_id UUID NOT NULL REFERENCES Users(id),
+                                       check_name VARCHAR(32) NOT NULL,
+                                       expected INT NOT NULL,
+                                       actual INT NOT NULL,
+                                       ledger_entry_id INT REFERENCES Ledger_Entries(id),
+                                       created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_reconcile_adjustments_run_id
+    ON Reconcile_Adjustments (run_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS Reconcile_Adjustments;
+DROP TABLE IF EXISTS Reconcile_Runs;
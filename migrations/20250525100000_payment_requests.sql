@@ -0,0 +1,30 @@
+-- +goose Up
+-- запрос монет: каждый плательщик переводит amount создателю запроса обычным переводом
+CREATE TABLE Payment_Requests (
+                                  id SERIAL PRIMARY KEY,
+                                  requester_id UUID NOT NULL REFERENCES Users(id),
+                                  amount INT NOT NULL CHECK (amount > 0),
+                                  message TEXT CHECK (char_length(message) <= 200),
+                                  category VARCHAR(32),
+                                  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_payment_requests_requester_id
+    ON Payment_Requests (requester_id);
+
+CREATE TABLE Payment_Request_Payers (
+                                        request_id INT NOT NULL REFERENCES Payment_Requests(id),
+                                        payer_id UUID NOT NULL REFERENCES Users(id),
+                                        status VARCHAR(16) NOT NULL DEFAULT 'pending',
+                                        resolved_at TIMESTAMPTZ,
+                                        -- перевод, созданный при оплате
+                                        transaction_id INT REFERENCES Transactions(id),
+                                        PRIMARY KEY (request_id, payer_id)
+);
+
+CREATE INDEX idx_payment_request_payers_payer_id
+    ON Payment_Request_Payers (payer_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS Payment_Request_Payers;
+DROP TABLE IF EXISTS Payment_Requests;
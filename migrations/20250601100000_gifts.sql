@@ -0,0 +1,15 @@
+-- +goose Up
+-- подарок: заказ оплачивает user_id, товары получает recipient_id
+ALTER TABLE Orders
+    ADD COLUMN recipient_id UUID REFERENCES Users(id),
+    ADD COLUMN message TEXT CHECK (char_length(message) <= 200);
+
+CREATE INDEX idx_orders_recipient_id
+    ON Orders (recipient_id)
+    WHERE recipient_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_orders_recipient_id;
+ALTER TABLE Orders
+    DROP COLUMN message,
+    DROP COLUMN recipient_id;
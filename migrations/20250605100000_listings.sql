@@ -0,0 +1,29 @@
+-- +goose Up
+-- объявления о продаже своего мерча: выставленные товары списываются из инвентаря до продажи или отмены
+CREATE TABLE Listings (
+                          id SERIAL PRIMARY KEY,
+                          seller_id UUID NOT NULL REFERENCES Users(id),
+                          merchandise_id INT NOT NULL REFERENCES Merchandise(id),
+                          quantity INT NOT NULL CHECK (quantity > 0),
+                          -- цена за все количество
+                          price INT NOT NULL CHECK (price > 0),
+                          status VARCHAR(16) NOT NULL DEFAULT 'active',
+                          buyer_id UUID REFERENCES Users(id),
+                          -- перевод покупателя продавцу
+                          transaction_id INT REFERENCES Transactions(id),
+                          created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                          closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_listings_seller_id
+    ON Listings (seller_id);
+
+CREATE INDEX idx_listings_buyer_id
+    ON Listings (buyer_id);
+
+CREATE INDEX idx_listings_active
+    ON Listings (merchandise_id)
+    WHERE status = 'active';
+
+-- +goose Down
+DROP TABLE IF EXISTS Listings;
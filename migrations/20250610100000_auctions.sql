@@ -0,0 +1,44 @@
+-- +goose Up
+-- аукцион на один товар, товар берется из запаса при создании
+CREATE TABLE Auctions (
+                          id SERIAL PRIMARY KEY,
+                          merchandise_id INT NOT NULL REFERENCES Merchandise(id),
+                          starting_bid INT NOT NULL CHECK (starting_bid > 0),
+                          ends_at TIMESTAMPTZ NOT NULL,
+                          status VARCHAR(16) NOT NULL DEFAULT 'open',
+                          created_by UUID NOT NULL REFERENCES Users(id),
+                          created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                          closed_at TIMESTAMPTZ,
+                          -- заказ победителя
+                          order_id INT REFERENCES Orders(id)
+);
+
+CREATE INDEX idx_auctions_ends_at
+    ON Auctions (ends_at)
+    WHERE status = 'open';
+
+-- монеты ставки со статусом held лежат на счете system:escrow
+CREATE TABLE Auction_Bids (
+                              id SERIAL PRIMARY KEY,
+                              auction_id INT NOT NULL REFERENCES Auctions(id),
+                              bidder_id UUID NOT NULL REFERENCES Users(id),
+                              amount INT NOT NULL CHECK (amount > 0),
+                              status VARCHAR(16) NOT NULL DEFAULT 'held',
+                              created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_auction_bids_auction_id
+    ON Auction_Bids (auction_id);
+
+-- в каждом аукционе удерживается только старшая ставка
+CREATE UNIQUE INDEX idx_auction_bids_held
+    ON Auction_Bids (auction_id)
+    WHERE status = 'held';
+
+CREATE INDEX idx_auction_bids_bidder_id
+    ON Auction_Bids (bidder_id)
+    WHERE status = 'held';
+
+-- +goose Down
+DROP TABLE IF EXISTS Auction_Bids;
+DROP TABLE IF EXISTS Auctions;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- сообщение и категория переходят в перевод при принятии или запуске
+ALTER TABLE Pending_Transfers
+    ADD COLUMN message TEXT CHECK (char_length(message) <= 200),
+    ADD COLUMN category VARCHAR(32);
+
+ALTER TABLE Scheduled_Transfers
+    ADD COLUMN message TEXT CHECK (char_length(message) <= 200),
+    ADD COLUMN category VARCHAR(32);
+
+-- +goose Down
+ALTER TABLE Scheduled_Transfers
+    DROP COLUMN IF EXISTS category,
+    DROP COLUMN IF EXISTS message;
+
+ALTER TABLE Pending_Transfers
+    DROP COLUMN IF EXISTS category,
+    DROP COLUMN IF EXISTS message;
@@ -0,0 +1,188 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestAdminMerch(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAdmin := authUser(t, baseURL, "admin", "password123")
+	tokenUser := authUser(t, baseURL, "user", "password123")
+
+	newItem := map[string]any{"name": "sticker", "price": 5, "description": "Laptop sticker"}
+
+	resp, err := doPost(t, baseURL+"/api/admin/merch", newItem, tokenUser)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = doPost(t, baseURL+"/api/admin/merch", newItem, tokenAdmin)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = doPost(t, baseURL+"/api/admin/merch", newItem, tokenAdmin)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, err = doGet(t, baseURL+"/api/buy/sticker", tokenUser)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// repricing does not change what was already paid
+	resp, err = doRequest(t, http.MethodPatch, baseURL+"/api/admin/merch/sticker", map[string]any{"name": "big-sticker", "price": 15}, tokenAdmin, nil)
+	assert.NoError(t, err)
+	var merch models.Merch
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&merch))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, models.Merch{Name: "big-sticker", Price: 15, Description: "Laptop sticker", Available: true}, merch)
+
+	resp, err = doGet(t, baseURL+"/api/buy/big-sticker", tokenUser)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	info := getInfo(t, baseURL, tokenUser)
+	assert.Equal(t, 1000-5-15, info.Coins)
+	assert.Contains(t, info.Inventory, models.Item{Type: "big-sticker", Quantity: 2})
+
+	resp, err = doPost(t, baseURL+"/api/admin/merch/big-sticker/hide", nil, tokenAdmin)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = doGet(t, baseURL+"/api/merch/big-sticker", "")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = doGet(t, baseURL+"/api/buy/big-sticker", tokenUser)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	resp, err = doRequest(t, http.MethodDelete, baseURL+"/api/admin/merch/big-sticker", nil, tokenAdmin, nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = doPost(t, baseURL+"/api/admin/merch/big-sticker/unhide", nil, tokenAdmin)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = doGet(t, baseURL+"/api/admin/merch/big-sticker/history", tokenAdmin)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var history []models.MerchChange
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	var actions []string
+	for _, change := range history {
+		actions = append(actions, change.Action)
+		assert.Equal(t, "admin", change.ChangedBy)
+	}
+	assert.Equal(t, []string{models.MerchActionCreate, models.MerchActionUpdate, models.MerchActionHide, models.MerchActionRetire}, actions)
+	assert.Equal(t, 5, history[0].Price)
+	assert.Equal(t, 15, history[1].Price)
+}
+
+func TestLimitedStock(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAdmin := authUser(t, baseURL, "admin", "password123")
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+
+	resp, err := doGet(t, baseURL+"/api/merch/pink-hoody", "")
+	assert.NoError(t, err)
+	var merch models.Merch
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&merch))
+	resp.Body.Close()
+	assert.Equal(t, 10, *merch.Stock)
+
+	resp, err = doPost(t, baseURL+"/api/admin/merch", map[string]any{"name": "badge", "price": 300, "stock": 0}, tokenAdmin)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = doPost(t, baseURL+"/api/admin/merch/badge/restock", map[string]int{"quantity": 1}, tokenAdmin)
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&merch))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, *merch.Stock)
+
+	resp, err = doGet(t, baseURL+"/api/buy/badge", tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = doGet(t, baseURL+"/api/buy/badge", tokenBob)
+	assert.NoError(t, err)
+	var errResp models.ErrorResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "item is out of stock", errResp.Errors)
+	assert.Equal(t, 1000, getInfo(t, baseURL, tokenBob).Coins)
+
+	resp, err = doGet(t, baseURL+"/api/merch/badge", "")
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&merch))
+	resp.Body.Close()
+	assert.False(t, merch.Available)
+	assert.Equal(t, 0, *merch.Stock)
+}
+
+func TestAdminRoles(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAdmin := authUser(t, baseURL, "admin", "password123")
+	tokenUser := authUser(t, baseURL, "user", "password123")
+	newItem := map[string]any{"name": "sticker", "price": 5}
+
+	// logging in again never grants a role
+	tokenUser = authUser(t, baseURL, "user", "password123")
+	resp, err := doPost(t, baseURL+"/api/admin/merch", newItem, tokenUser)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = doRequest(t, http.MethodPut, baseURL+"/api/admin/users/user/role", map[string]string{"role": "admin"}, tokenUser, nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = doRequest(t, http.MethodPut, baseURL+"/api/admin/users/user/role", map[string]string{"role": "admin"}, tokenAdmin, nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = doPost(t, baseURL+"/api/admin/merch", newItem, tokenUser)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = doRequest(t, http.MethodPut, baseURL+"/api/admin/users/admin/role", map[string]string{"role": "user"}, tokenAdmin, nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, err = doRequest(t, http.MethodPut, baseURL+"/api/admin/users/system/role", map[string]string{"role": "admin"}, tokenAdmin, nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,77 @@
+package integrationTests
+
+import (
+	"avito/internal/scheduler"
+	"avito/internal/storage"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAllowance(t *testing.T) {
+	srv, a := setupTestApp(t)
+	baseURL := srv.URL
+	sched := scheduler.Scheduler{Jobs: []scheduler.Job{scheduler.Allowance(a.Storage, a.Lfu, 150, 0)}}
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+	assert.Equal(t, 1000, getInfo(t, baseURL, tokenAlice).Coins)
+
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 12, 0, 0, 0, time.UTC)
+
+	// ticking twice within a month issues once
+	sched.Tick(context.Background(), now)
+	sched.Tick(context.Background(), now)
+	info := getInfo(t, baseURL, tokenAlice)
+	assert.Equal(t, 1150, info.Coins)
+	assert.Equal(t, storage.IssuerUsername, info.CoinsHistory.Received[0].FromUser)
+	assert.Equal(t, 1150, getInfo(t, baseURL, tokenBob).Coins)
+
+	// the server was down for the next two months
+	sched.Tick(context.Background(), month.AddDate(0, 2, 0))
+	assert.Equal(t, 1450, getInfo(t, baseURL, tokenAlice).Coins)
+
+	resp, err := doPost(t, baseURL+"/api/sendCoin", SendCoinRequest{ToUser: storage.IssuerUsername, Amount: 10}, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCoinExpiration(t *testing.T) {
+	srv, a := setupTestApp(t)
+	baseURL := srv.URL
+	sched := scheduler.Scheduler{Jobs: []scheduler.Job{
+		scheduler.Allowance(a.Storage, a.Lfu, 150, 1),
+		scheduler.ExpireCoins(a.Storage, a.Lfu),
+	}}
+
+	token := authUser(t, baseURL, "user", "password123")
+
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	sched.Tick(context.Background(), now)
+
+	info := getInfo(t, baseURL, token)
+	assert.Equal(t, 1150, info.Coins)
+	assert.Len(t, info.Expiring, 1)
+	assert.Equal(t, 150, info.Expiring[0].Amount)
+	assert.True(t, month.AddDate(0, 1, 0).Equal(info.Expiring[0].ExpiresAt))
+
+	// the signup coins are spent first
+	resp, err := doGet(t, baseURL+"/api/buy/pen", token)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 150, getInfo(t, baseURL, token).Expiring[0].Amount)
+
+	// the next month brings a new allowance and burns the old one
+	sched.Tick(context.Background(), month.AddDate(0, 1, 0).Add(time.Hour))
+	info = getInfo(t, baseURL, token)
+	assert.Equal(t, 1140, info.Coins)
+	assert.Len(t, info.Expiring, 1)
+	assert.True(t, month.AddDate(0, 2, 0).Equal(info.Expiring[0].ExpiresAt))
+	assert.Equal(t, storage.IssuerUsername, info.CoinsHistory.Sent[0].ToUser)
+	assert.Equal(t, 150, info.CoinsHistory.Sent[0].Amount)
+}
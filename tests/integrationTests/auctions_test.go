@@ -0,0 +1,89 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"avito/internal/scheduler"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAuctions(t *testing.T) {
+	srv, a := setupTestApp(t)
+	baseURL := srv.URL
+	sched := scheduler.Scheduler{Jobs: []scheduler.Job{scheduler.CloseAuctions(a.Storage, a.Lfu)}}
+
+	tokenAdmin := authUser(t, baseURL, "admin", "password123")
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+
+	endsAt := time.Now().Add(time.Hour)
+	body := map[string]any{"item": "pink-hoody", "startingBid": 100, "endsAt": endsAt}
+	resp, err := doPost(t, baseURL+"/api/admin/auctions", body, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = doPost(t, baseURL+"/api/admin/auctions", body, tokenAdmin)
+	assert.NoError(t, err)
+	var auction models.Auction
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&auction))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	bid := func(amount int, token string) int {
+		t.Helper()
+		resp, err := doPost(t, baseURL+"/api/auctions/"+strconv.Itoa(auction.ID)+"/bids", map[string]int{"amount": amount}, token)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, bid(300, tokenAlice))
+	assert.Equal(t, 700, getInfo(t, baseURL, tokenAlice).Coins)
+	assert.Equal(t, http.StatusConflict, bid(300, tokenBob))
+	assert.Equal(t, http.StatusOK, bid(400, tokenBob))
+	assert.Equal(t, 1000, getInfo(t, baseURL, tokenAlice).Coins)
+	assert.Equal(t, 600, getInfo(t, baseURL, tokenBob).Coins)
+
+	resp, err = doGet(t, baseURL+"/api/auctions", tokenAlice)
+	assert.NoError(t, err)
+	var open []models.Auction
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&open))
+	resp.Body.Close()
+	assert.Len(t, open, 1)
+	assert.Equal(t, 400, open[0].TopBid.Amount)
+
+	// nothing ends before endsAt
+	sched.Tick(context.Background(), time.Now())
+	assert.Empty(t, getInfo(t, baseURL, tokenBob).Inventory)
+	sched.Tick(context.Background(), endsAt)
+
+	bob := getInfo(t, baseURL, tokenBob)
+	assert.Equal(t, 600, bob.Coins)
+	assert.Equal(t, []models.Item{{Type: "pink-hoody", Quantity: 1}}, bob.Inventory)
+
+	resp, err = doGet(t, baseURL+"/api/auctions/"+strconv.Itoa(auction.ID), tokenAlice)
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&auction))
+	resp.Body.Close()
+	assert.Equal(t, models.AuctionStatusSold, auction.Status)
+	assert.Equal(t, "user2", auction.Winner)
+	assert.Len(t, auction.Bids, 2)
+	assert.Equal(t, http.StatusConflict, bid(500, tokenAlice))
+
+	// the win can't be refunded into stock at the catalog price
+	refundURL := baseURL + "/api/purchases/" + strconv.Itoa(auction.OrderID) + "/refund"
+	resp, err = doPost(t, refundURL, map[string]any{"item": "pink-hoody"}, tokenBob)
+	assert.NoError(t, err)
+	var errResp models.ErrorResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "auction wins can't be refunded", errResp.Errors)
+	assert.Equal(t, 600, getInfo(t, baseURL, tokenBob).Coins)
+}
@@ -1,6 +1,8 @@
 package integrationTests
 
 import (
+	"avito/internal/models"
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"net/http"
@@ -36,3 +38,32 @@ func TestBuyItem(t *testing.T) {
 	}
 	assert.True(t, foundTShirt)
 }
+
+func TestCatalog(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	resp, err := doGet(t, baseURL+"/api/merch", "")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var items []models.Merch
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&items))
+	assert.Len(t, items, 10)
+	assert.Equal(t, models.Merch{Name: "t-shirt", Price: 80, Description: "Cotton t-shirt with the company logo", Available: true}, items[0])
+
+	resp, err = doGet(t, baseURL+"/api/merch/pink-hoody", "")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var merch models.Merch
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&merch))
+	assert.Equal(t, 500, merch.Price)
+
+	resp, err = doGet(t, baseURL+"/api/merch/unknown", "")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
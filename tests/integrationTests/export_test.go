@@ -0,0 +1,96 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestExport(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+	tokenAdmin := authUser(t, baseURL, "admin", "password123")
+
+	resp, err := doPost(t, baseURL+"/api/sendCoin", map[string]any{
+		"toUser": "user2", "amount": 30, "message": "lunch, thanks", "category": "thanks",
+	}, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	resp, err = doGet(t, baseURL+"/api/buy/cup", tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	resp, err = doPost(t, baseURL+"/api/sendCoin", SendCoinRequest{ToUser: "admin", Amount: 10}, tokenBob)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = doGet(t, baseURL+"/api/export", tokenAlice)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	rows, err := csv.NewReader(resp.Body).ReadAll()
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, []string{"transfer", "user", "user2", "30", "lunch, thanks"}, []string{rows[1][1], rows[1][3], rows[1][4], rows[1][7], rows[1][8]})
+	assert.Equal(t, []string{"purchase", "user", "cup", "1", "20"}, []string{rows[2][1], rows[2][3], rows[2][5], rows[2][6], rows[2][7]})
+
+	// a range in the future is empty
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+	resp, err = doGet(t, baseURL+"/api/export?from="+tomorrow, tokenAlice)
+	assert.NoError(t, err)
+	rows, err = csv.NewReader(resp.Body).ReadAll()
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+
+	resp, err = doGet(t, baseURL+"/api/admin/export", tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = doGet(t, baseURL+"/api/admin/export?format=ndjson", tokenAdmin)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var records []models.ActivityRecord
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var rec models.ActivityRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+		records = append(records, rec)
+	}
+	resp.Body.Close()
+	assert.Len(t, records, 3)
+	assert.Equal(t, "user2", records[2].From)
+	assert.Equal(t, "admin", records[2].To)
+}
+
+func TestExportGift(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+
+	resp, err := doPost(t, baseURL+"/api/gifts", map[string]any{
+		"toUser": "user2", "items": []map[string]any{{"item": "cup", "quantity": 1}}, "message": "welcome",
+	}, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// the recipient sees the gift in their own export
+	resp, err = doGet(t, baseURL+"/api/export", tokenBob)
+	assert.NoError(t, err)
+	rows, err := csv.NewReader(resp.Body).ReadAll()
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, []string{"purchase", "user", "user2", "cup", "welcome"}, []string{rows[1][1], rows[1][3], rows[1][4], rows[1][5], rows[1][8]})
+}
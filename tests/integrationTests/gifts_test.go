@@ -0,0 +1,79 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestGifts(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+
+	// bob's info is cached before the gift arrives
+	assert.Empty(t, getInfo(t, baseURL, tokenBob).Inventory)
+
+	gift := map[string]any{
+		"toUser":  "user2",
+		"items":   []map[string]any{{"item": "hoody", "quantity": 1}},
+		"message": "happy birthday",
+	}
+	resp, err := doPost(t, baseURL+"/api/gifts", gift, tokenAlice)
+	assert.NoError(t, err)
+	var order models.Order
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&order))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "user2", order.ToUser)
+
+	gift["toUser"] = "user"
+	resp, err = doPost(t, baseURL+"/api/gifts", gift, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	alice := getInfo(t, baseURL, tokenAlice)
+	assert.Equal(t, 700, alice.Coins)
+	assert.Empty(t, alice.Inventory)
+	assert.Len(t, alice.GiftHistory.Sent, 1)
+	assert.Equal(t, "user2", alice.GiftHistory.Sent[0].ToUser)
+
+	bob := getInfo(t, baseURL, tokenBob)
+	assert.Equal(t, 1000, bob.Coins)
+	assert.Equal(t, []models.Item{{Type: "hoody", Quantity: 1}}, bob.Inventory)
+	assert.Len(t, bob.GiftHistory.Received, 1)
+	assert.Equal(t, "user", bob.GiftHistory.Received[0].FromUser)
+	assert.Equal(t, "happy birthday", bob.GiftHistory.Received[0].Message)
+
+	resp, err = doGet(t, baseURL+"/api/purchases", tokenAlice)
+	assert.NoError(t, err)
+	var page models.PurchasePage
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Len(t, page.Purchases, 1)
+	assert.Equal(t, "user2", page.Purchases[0].ToUser)
+
+	// refunding the gift takes the hoody back from bob, whose info is cached
+	refundURL := baseURL + "/api/purchases/" + strconv.Itoa(order.ID) + "/refund"
+	resp, err = doPost(t, refundURL, map[string]any{"item": "hoody"}, tokenAlice)
+	assert.NoError(t, err)
+	var refund models.Refund
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&refund))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "user2", refund.ToUser)
+
+	assert.Equal(t, 1000, getInfo(t, baseURL, tokenAlice).Coins)
+	assert.Empty(t, getInfo(t, baseURL, tokenBob).Inventory)
+
+	resp, err = doPost(t, refundURL, map[string]any{"item": "hoody"}, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
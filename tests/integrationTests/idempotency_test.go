@@ -0,0 +1,47 @@
+package integrationTests
+
+import (
+	"avito/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestSendCoinIdempotency(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+
+	headers := map[string]string{middleware.IdempotencyHeader: "send-1"}
+	sendBody := SendCoinRequest{ToUser: "user2", Amount: 200}
+
+	for i := 0; i < 3; i++ {
+		resp, err := doPostWithHeaders(t, baseURL+"/api/sendCoin", sendBody, tokenAlice, headers)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		if i > 0 {
+			assert.Equal(t, "true", resp.Header.Get("Idempotent-Replayed"))
+		}
+	}
+
+	assert.Equal(t, 800, getInfo(t, baseURL, tokenAlice).Coins)
+	assert.Equal(t, 1200, getInfo(t, baseURL, tokenBob).Coins)
+
+	otherBody := SendCoinRequest{ToUser: "user2", Amount: 300}
+	resp, err := doPostWithHeaders(t, baseURL+"/api/sendCoin", otherBody, tokenAlice, headers)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	// keys are scoped per user
+	backBody := SendCoinRequest{ToUser: "user", Amount: 200}
+	resp, err = doPostWithHeaders(t, baseURL+"/api/sendCoin", backBody, tokenBob, headers)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.Equal(t, 1000, getInfo(t, baseURL, tokenAlice).Coins)
+}
@@ -0,0 +1,97 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestSendLimits(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAdmin := authUser(t, baseURL, "admin", "password123")
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	authUser(t, baseURL, "user2", "password123")
+
+	setLimit := func(body map[string]any, token string) int {
+		t.Helper()
+		resp, err := doRequest(t, http.MethodPut, baseURL+"/api/admin/limits", body, token, nil)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	send := func(amount int) *http.Response {
+		t.Helper()
+		resp, err := doPost(t, baseURL+"/api/sendCoin", SendCoinRequest{ToUser: "user2", Amount: amount}, tokenAlice)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	assert.Equal(t, http.StatusForbidden, setLimit(map[string]any{"scope": "global", "daily": 300}, tokenAlice))
+	assert.Equal(t, http.StatusOK, setLimit(map[string]any{"scope": "global", "daily": 300}, tokenAdmin))
+	assert.Equal(t, http.StatusOK, setLimit(map[string]any{"scope": "role", "subject": "user", "weekly": 250}, tokenAdmin))
+
+	resp := send(200)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = send(100)
+	var limitResp models.LimitErrorResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&limitResp))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, models.LimitWeekly, limitResp.Limit)
+	assert.Equal(t, 50, limitResp.Remaining)
+	assert.Equal(t, 800, getInfo(t, baseURL, tokenAlice).Coins)
+
+	// a user override lifts the role limit
+	assert.Equal(t, http.StatusOK, setLimit(map[string]any{"scope": "user", "subject": "user", "weekly": 0}, tokenAdmin))
+	resp = send(100)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err := doGet(t, baseURL+"/api/admin/limits", tokenAdmin)
+	assert.NoError(t, err)
+	var limits []models.SendLimit
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&limits))
+	resp.Body.Close()
+	assert.Len(t, limits, 3)
+	assert.Equal(t, models.LimitScopeGlobal, limits[0].Scope)
+	assert.Equal(t, "user", limits[2].Subject)
+}
+
+func TestSendLimitsAfterReversal(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAdmin := authUser(t, baseURL, "admin", "password123")
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	authUser(t, baseURL, "user2", "password123")
+
+	resp, err := doRequest(t, http.MethodPut, baseURL+"/api/admin/limits", map[string]any{"scope": "global", "daily": 300}, tokenAdmin, nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	send := func(amount int) int {
+		t.Helper()
+		resp, err := doPost(t, baseURL+"/api/sendCoin", SendCoinRequest{ToUser: "user2", Amount: amount}, tokenAlice)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, send(250))
+	assert.Equal(t, http.StatusTooManyRequests, send(100))
+
+	// the reversed part of the transfer no longer counts
+	original := getTransactions(t, baseURL+"/api/transactions?direction=sent", tokenAlice).Transactions[0]
+	resp, err = doPost(t, baseURL+"/api/admin/transactions/"+strconv.Itoa(original.ID)+"/reverse", nil, tokenAdmin)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, http.StatusOK, send(300))
+	assert.Equal(t, http.StatusTooManyRequests, send(1))
+}
@@ -0,0 +1,75 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestListings(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+	tokenCarol := authUser(t, baseURL, "user3", "password123")
+
+	resp, err := doPost(t, baseURL+"/api/orders", map[string]any{"items": []map[string]any{{"item": "hoody", "quantity": 1}}}, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	list := func() (models.Listing, int) {
+		t.Helper()
+		resp, err := doPost(t, baseURL+"/api/listings", map[string]any{"item": "hoody", "price": 200}, tokenAlice)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		var listing models.Listing
+		if resp.StatusCode == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&listing))
+		}
+		return listing, resp.StatusCode
+	}
+	action := func(id int, name, token string) int {
+		t.Helper()
+		resp, err := doPost(t, baseURL+"/api/listings/"+strconv.Itoa(id)+"/"+name, nil, token)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	listing, code := list()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, getInfo(t, baseURL, tokenAlice).Inventory)
+
+	// the only hoody is locked in the listing
+	_, code = list()
+	assert.Equal(t, http.StatusConflict, code)
+
+	assert.Equal(t, http.StatusOK, action(listing.ID, "cancel", tokenAlice))
+	assert.Equal(t, []models.Item{{Type: "hoody", Quantity: 1}}, getInfo(t, baseURL, tokenAlice).Inventory)
+
+	listing, code = list()
+	assert.Equal(t, http.StatusOK, code)
+
+	resp, err = doGet(t, baseURL+"/api/listings", tokenBob)
+	assert.NoError(t, err)
+	var active []models.Listing
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&active))
+	resp.Body.Close()
+	assert.Len(t, active, 1)
+
+	assert.Equal(t, http.StatusNotFound, action(listing.ID, "cancel", tokenBob))
+	assert.Equal(t, http.StatusOK, action(listing.ID, "buy", tokenBob))
+	assert.Equal(t, http.StatusConflict, action(listing.ID, "buy", tokenCarol))
+
+	bob := getInfo(t, baseURL, tokenBob)
+	assert.Equal(t, 800, bob.Coins)
+	assert.Equal(t, []models.Item{{Type: "hoody", Quantity: 1}}, bob.Inventory)
+	alice := getInfo(t, baseURL, tokenAlice)
+	assert.Equal(t, 1000-300+200, alice.Coins)
+	assert.Len(t, alice.CoinsHistory.Received, 1)
+}
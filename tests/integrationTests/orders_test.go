@@ -0,0 +1,129 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestOrders(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	token := authUser(t, baseURL, "user", "password123")
+
+	cart := map[string]any{"items": []map[string]any{
+		{"item": "socks", "quantity": 5},
+		{"item": "t-shirt", "quantity": 1},
+	}}
+	resp, err := doPost(t, baseURL+"/api/orders", cart, token)
+	assert.NoError(t, err)
+	var order models.Order
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&order))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 5*10+80, order.Total)
+	assert.Equal(t, []models.OrderLine{
+		{Item: "socks", Quantity: 5, UnitPrice: 10},
+		{Item: "t-shirt", Quantity: 1, UnitPrice: 80},
+	}, order.Items)
+
+	info := getInfo(t, baseURL, token)
+	assert.Equal(t, 1000-130, info.Coins)
+	assert.Contains(t, info.Inventory, models.Item{Type: "socks", Quantity: 5})
+	assert.Contains(t, info.Inventory, models.Item{Type: "t-shirt", Quantity: 1})
+
+	// one line out of stock fails the whole cart
+	cart = map[string]any{"items": []map[string]any{
+		{"item": "pen", "quantity": 1},
+		{"item": "pink-hoody", "quantity": 11},
+	}}
+	resp, err = doPost(t, baseURL+"/api/orders", cart, token)
+	assert.NoError(t, err)
+	var errResp models.ErrorResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "item is out of stock: pink-hoody", errResp.Errors)
+
+	info = getInfo(t, baseURL, token)
+	assert.Equal(t, 1000-130, info.Coins)
+	assert.NotContains(t, info.Inventory, models.Item{Type: "pen", Quantity: 1})
+
+	cart = map[string]any{"items": []map[string]any{
+		{"item": "pink-hoody", "quantity": 1},
+		{"item": "umbrella", "quantity": 2},
+	}}
+	resp, err = doPost(t, baseURL+"/api/orders", cart, token)
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, "not enough tokens", errResp.Errors)
+	assert.Equal(t, 1000-130, getInfo(t, baseURL, token).Coins)
+
+	resp, err = doGet(t, baseURL+"/api/merch/pink-hoody", "")
+	assert.NoError(t, err)
+	var merch models.Merch
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&merch))
+	resp.Body.Close()
+	assert.Equal(t, 10, *merch.Stock)
+}
+
+func TestPurchases(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAdmin := authUser(t, baseURL, "admin", "password123")
+	token := authUser(t, baseURL, "user", "password123")
+
+	resp, err := doGet(t, baseURL+"/api/buy/pen", token)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	// the history keeps the price paid after repricing
+	resp, err = doRequest(t, http.MethodPatch, baseURL+"/api/admin/merch/pen", map[string]any{"price": 15}, tokenAdmin, nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	cart := map[string]any{"items": []map[string]any{
+		{"item": "pen", "quantity": 2},
+		{"item": "cup", "quantity": 1},
+	}}
+	resp, err = doPost(t, baseURL+"/api/orders", cart, token)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = doGet(t, baseURL+"/api/purchases?limit=1", token)
+	assert.NoError(t, err)
+	var page models.PurchasePage
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, page.Purchases, 1)
+	assert.Equal(t, []models.OrderLine{
+		{Item: "pen", Quantity: 2, UnitPrice: 15},
+		{Item: "cup", Quantity: 1, UnitPrice: 20},
+	}, page.Purchases[0].Items)
+	assert.NotEmpty(t, page.NextCursor)
+	assert.False(t, page.Purchases[0].CreatedAt.IsZero())
+
+	resp, err = doGet(t, baseURL+"/api/purchases?limit=1&cursor="+page.NextCursor, token)
+	assert.NoError(t, err)
+	page = models.PurchasePage{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Equal(t, []models.OrderLine{{Item: "pen", Quantity: 1, UnitPrice: 10}}, page.Purchases[0].Items)
+	assert.Empty(t, page.NextCursor)
+
+	// spend in the history matches the balance
+	resp, err = doGet(t, baseURL+"/api/purchases?item=cup", token)
+	assert.NoError(t, err)
+	page = models.PurchasePage{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Len(t, page.Purchases, 1)
+	assert.Equal(t, 1000-10-50, getInfo(t, baseURL, token).Coins)
+}
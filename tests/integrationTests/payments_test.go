@@ -0,0 +1,58 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestPaymentRequests(t *testing.T) {
+	srv, _ := setupTestApp(t)
+	baseURL := srv.URL
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+	tokenCarol := authUser(t, baseURL, "user3", "password123")
+
+	resp, err := doPost(t, baseURL+"/api/paymentRequests", map[string]any{"payers": []string{"user2", "user3"}, "amount": 150, "message": "team lunch"}, tokenAlice)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var request models.PaymentRequest
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&request))
+	resp.Body.Close()
+
+	answer := func(action, token string) int {
+		t.Helper()
+		resp, err := doPost(t, baseURL+"/api/paymentRequests/"+strconv.Itoa(request.ID)+"/"+action, nil, token)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusNotFound, answer("pay", tokenAlice))
+	assert.Equal(t, http.StatusOK, answer("pay", tokenBob))
+	assert.Equal(t, http.StatusConflict, answer("pay", tokenBob))
+	assert.Equal(t, http.StatusOK, answer("reject", tokenCarol))
+
+	assert.Equal(t, 1150, getInfo(t, baseURL, tokenAlice).Coins)
+	assert.Equal(t, 850, getInfo(t, baseURL, tokenBob).Coins)
+	assert.Equal(t, 1000, getInfo(t, baseURL, tokenCarol).Coins)
+
+	resp, err = doGet(t, baseURL+"/api/paymentRequests", tokenAlice)
+	assert.NoError(t, err)
+	var requests []models.PaymentRequest
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&requests))
+	resp.Body.Close()
+	assert.Len(t, requests, 1)
+	assert.Len(t, requests[0].Payers, 2)
+
+	resp, err = doGet(t, baseURL+"/api/paymentRequests", tokenCarol)
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&requests))
+	resp.Body.Close()
+	assert.Len(t, requests[0].Payers, 1)
+	assert.Equal(t, models.PaymentStatusRejected, requests[0].Payers[0].Status)
+}
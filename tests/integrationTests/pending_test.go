@@ -0,0 +1,80 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"avito/internal/scheduler"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPendingTransfers(t *testing.T) {
+	srv, a := setupTestApp(t)
+	baseURL := srv.URL
+	sched := scheduler.Scheduler{Jobs: []scheduler.Job{scheduler.ExpirePendingTransfers(a.Storage, a.Lfu)}}
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+
+	offer := func(amount int) models.PendingTransfer {
+		t.Helper()
+		resp, err := doPost(t, baseURL+"/api/pendingTransfers", map[string]any{
+			"toUser": "user2", "amount": amount, "message": "for the trip", "category": "thanks",
+		}, tokenAlice)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var pending models.PendingTransfer
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&pending))
+		return pending
+	}
+	resolve := func(id int, action, token string) int {
+		t.Helper()
+		resp, err := doPost(t, baseURL+"/api/pendingTransfers/"+strconv.Itoa(id)+"/"+action, nil, token)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	accepted := offer(600)
+	assert.Equal(t, 400, getInfo(t, baseURL, tokenAlice).Coins)
+
+	// the held coins can not be spent
+	resp, err := doPost(t, baseURL+"/api/sendCoin", map[string]any{"toUser": "user2", "amount": 500}, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+
+	assert.Equal(t, http.StatusNotFound, resolve(accepted.ID, "accept", tokenAlice))
+	assert.Equal(t, http.StatusOK, resolve(accepted.ID, "accept", tokenBob))
+	assert.Equal(t, http.StatusConflict, resolve(accepted.ID, "decline", tokenBob))
+	info := getInfo(t, baseURL, tokenBob)
+	assert.Equal(t, 1600, info.Coins)
+	assert.Equal(t, "for the trip", info.CoinsHistory.Received[0].Message)
+	assert.Equal(t, "thanks", info.CoinsHistory.Received[0].Category)
+	assert.Len(t, getInfo(t, baseURL, tokenAlice).CoinsHistory.Sent, 1)
+
+	declined := offer(100)
+	assert.Equal(t, http.StatusOK, resolve(declined.ID, "decline", tokenBob))
+	assert.Equal(t, 400, getInfo(t, baseURL, tokenAlice).Coins)
+
+	offer(300)
+	sched.Tick(context.Background(), time.Now())
+	assert.Equal(t, 100, getInfo(t, baseURL, tokenAlice).Coins)
+	sched.Tick(context.Background(), time.Now().Add(a.PendingController.TTL+time.Minute))
+	assert.Equal(t, 400, getInfo(t, baseURL, tokenAlice).Coins)
+
+	resp, err = doGet(t, baseURL+"/api/pendingTransfers", tokenBob)
+	assert.NoError(t, err)
+	var transfers []models.PendingTransfer
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&transfers))
+	resp.Body.Close()
+	assert.Len(t, transfers, 3)
+	assert.Equal(t, models.PendingStatusExpired, transfers[0].Status)
+	assert.Equal(t, models.PendingStatusDeclined, transfers[1].Status)
+	assert.Equal(t, models.PendingStatusAccepted, transfers[2].Status)
+}
@@ -0,0 +1,73 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestRefunds(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAdmin := authUser(t, baseURL, "admin", "password123")
+	token := authUser(t, baseURL, "user", "password123")
+	tokenOther := authUser(t, baseURL, "user2", "password123")
+
+	cart := map[string]any{"items": []map[string]any{
+		{"item": "socks", "quantity": 3},
+		{"item": "cup", "quantity": 1},
+	}}
+	resp, err := doPost(t, baseURL+"/api/orders", cart, token)
+	assert.NoError(t, err)
+	var order models.Order
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&order))
+	resp.Body.Close()
+	refundURL := baseURL + "/api/purchases/" + strconv.Itoa(order.ID) + "/refund"
+
+	// refunds credit the price paid, not the current one
+	resp, err = doRequest(t, http.MethodPatch, baseURL+"/api/admin/merch/socks", map[string]any{"price": 50}, tokenAdmin, nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = doPost(t, refundURL, map[string]any{"item": "socks", "quantity": 2}, tokenOther)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = doPost(t, refundURL, map[string]any{"item": "socks", "quantity": 2}, token)
+	assert.NoError(t, err)
+	var refund models.Refund
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&refund))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 20, refund.Amount)
+
+	resp, err = doPost(t, refundURL, map[string]any{"item": "socks", "quantity": 2}, token)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, err = doPost(t, baseURL+"/api/admin/purchases/"+strconv.Itoa(order.ID)+"/refund", map[string]any{"item": "cup"}, tokenAdmin)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	info := getInfo(t, baseURL, token)
+	assert.Equal(t, 1000-50+20+20, info.Coins)
+	assert.Equal(t, []models.Item{{Type: "socks", Quantity: 1}}, info.Inventory)
+
+	resp, err = doGet(t, baseURL+"/api/purchases", token)
+	assert.NoError(t, err)
+	var page models.PurchasePage
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Len(t, page.Purchases, 1)
+	refunds := page.Purchases[0].Refunds
+	assert.Len(t, refunds, 2)
+	assert.Equal(t, "socks", refunds[0].Item)
+	assert.Equal(t, models.Refund{ID: refunds[1].ID, OrderID: order.ID, User: "user", Item: "cup", Quantity: 1, Amount: 20, CreatedAt: refunds[1].CreatedAt}, refunds[1])
+}
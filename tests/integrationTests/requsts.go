@@ -9,13 +9,23 @@ import (
 )
 
 func doPost(t *testing.T, url string, body any, token string) (*http.Response, error) {
+	t.Helper()
+	return doPostWithHeaders(t, url, body, token, nil)
+}
+
+func doPostWithHeaders(t *testing.T, url string, body any, token string, headers map[string]string) (*http.Response, error) {
+	t.Helper()
+	return doRequest(t, http.MethodPost, url, body, token, headers)
+}
+
+func doRequest(t *testing.T, method, url string, body any, token string, headers map[string]string) (*http.Response, error) {
 	t.Helper()
 	b, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal body: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	req, err := http.NewRequest(method, url, bytes.NewReader(b))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -23,6 +33,9 @@ func doPost(t *testing.T, url string, body any, token string) (*http.Response, e
 	if token != "" {
 		req.Header.Set("Cookie", "auth_token="+token)
 	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
 
 	client := &http.Client{}
 	return client.Do(req)
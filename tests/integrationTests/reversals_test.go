@@ -0,0 +1,60 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestReverseTransaction(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAdmin := authUser(t, baseURL, "admin", "password123")
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+	authUser(t, baseURL, "user3", "password123")
+
+	resp, err := doPost(t, baseURL+"/api/sendCoin", map[string]any{"toUser": "user2", "amount": 600}, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	// cache the balances to check the invalidation
+	assert.Equal(t, 400, getInfo(t, baseURL, tokenAlice).Coins)
+	assert.Equal(t, 1600, getInfo(t, baseURL, tokenBob).Coins)
+
+	resp, err = doPost(t, baseURL+"/api/sendCoin", map[string]any{"toUser": "user3", "amount": 1500}, tokenBob)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	original := getTransactions(t, baseURL+"/api/transactions?direction=sent", tokenAlice).Transactions[0]
+	reverseURL := baseURL + "/api/admin/transactions/" + strconv.Itoa(original.ID) + "/reverse"
+
+	resp, err = doPost(t, reverseURL, nil, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = doPost(t, reverseURL, map[string]string{"policy": "fail"}, tokenAdmin)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, err = doPost(t, reverseURL, map[string]string{"policy": "partial"}, tokenAdmin)
+	assert.NoError(t, err)
+	var reversal models.Reversal
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&reversal))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 100, reversal.Amount)
+	assert.Equal(t, 500, reversal.Remaining)
+
+	assert.Equal(t, 500, getInfo(t, baseURL, tokenAlice).Coins)
+	assert.Equal(t, 0, getInfo(t, baseURL, tokenBob).Coins)
+
+	received := getTransactions(t, baseURL+"/api/transactions?direction=received", tokenAlice).Transactions
+	assert.Equal(t, original.ID, received[0].ReversesID)
+	assert.Equal(t, "user2", received[0].FromUser)
+}
@@ -0,0 +1,90 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"avito/internal/scheduler"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestScheduledTransfers(t *testing.T) {
+	srv, a := setupTestApp(t)
+	baseURL := srv.URL
+	sched := scheduler.Scheduler{Jobs: []scheduler.Job{scheduler.ScheduledTransfers(a.Storage, a.Lfu)}}
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+
+	runAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	resp, err := doPost(t, baseURL+"/api/schedules", map[string]any{
+		"toUser":   "user2",
+		"amount":   400,
+		"message":  "rent",
+		"category": "help",
+		"interval": "weekly",
+		"runAt":    runAt,
+	}, tokenAlice)
+	assert.NoError(t, err)
+	var schedule models.ScheduledTransfer
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&schedule))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, schedule.Active)
+	scheduleURL := baseURL + "/api/schedules/" + strconv.Itoa(schedule.ID)
+
+	// not due yet
+	sched.Tick(context.Background(), time.Now())
+	assert.Equal(t, 1000, getInfo(t, baseURL, tokenAlice).Coins)
+
+	for week := 0; week < 3; week++ {
+		sched.Tick(context.Background(), runAt.AddDate(0, 0, 7*week))
+	}
+	assert.Equal(t, 200, getInfo(t, baseURL, tokenAlice).Coins)
+	info := getInfo(t, baseURL, tokenBob)
+	assert.Equal(t, 1800, info.Coins)
+	assert.Equal(t, "rent", info.CoinsHistory.Received[0].Message)
+	assert.Equal(t, "help", info.CoinsHistory.Received[0].Category)
+
+	resp, err = doGet(t, scheduleURL+"/runs", tokenAlice)
+	assert.NoError(t, err)
+	var runs []models.ScheduledRun
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&runs))
+	resp.Body.Close()
+	assert.Len(t, runs, 3)
+	assert.Equal(t, models.RunFailed, runs[0].Status)
+	assert.Equal(t, "not enough tokens", runs[0].Error)
+	assert.Equal(t, models.RunSucceeded, runs[1].Status)
+	assert.NotZero(t, runs[1].TransactionID)
+
+	resp, err = doGet(t, scheduleURL+"/runs", tokenBob)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = doRequest(t, http.MethodPatch, scheduleURL, map[string]any{"amount": 100}, tokenAlice, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&schedule))
+	resp.Body.Close()
+	assert.Equal(t, 100, schedule.Amount)
+	assert.Equal(t, "user2", schedule.ToUser)
+
+	resp, err = doRequest(t, http.MethodDelete, scheduleURL, nil, tokenAlice, nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	sched.Tick(context.Background(), runAt.AddDate(0, 0, 21))
+	assert.Equal(t, 200, getInfo(t, baseURL, tokenAlice).Coins)
+
+	resp, err = doGet(t, baseURL+"/api/schedules", tokenAlice)
+	assert.NoError(t, err)
+	var schedules []models.ScheduledTransfer
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&schedules))
+	resp.Body.Close()
+	assert.Empty(t, schedules)
+}
@@ -1,6 +1,8 @@
 package integrationTests
 
 import (
+	"avito/internal/models"
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"testing"
@@ -58,3 +60,80 @@ func TestSendCoin(t *testing.T) {
 	}
 	assert.True(t, foundIncoming)
 }
+
+func TestSendCoinMemo(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+
+	resp, err := doPost(t, baseURL+"/api/sendCoin", map[string]any{
+		"toUser": "user2", "amount": 50, "category": "cake",
+	}, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = doPost(t, baseURL+"/api/sendCoin", map[string]any{
+		"toUser": "user2", "amount": 50, "message": "thanks for the release", "category": "teamwork",
+	}, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = doPost(t, baseURL+"/api/sendCoin", SendCoinRequest{ToUser: "user2", Amount: 20}, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	received := getInfo(t, baseURL, tokenBob).CoinsHistory.Received
+	assert.Len(t, received, 2)
+	assert.Equal(t, "thanks for the release", received[1].Message)
+	assert.Equal(t, "teamwork", received[1].Category)
+
+	page := getTransactions(t, baseURL+"/api/transactions?category=teamwork", tokenBob)
+	assert.Len(t, page.Transactions, 1)
+	assert.Equal(t, 50, page.Transactions[0].Amount)
+}
+
+func TestSendBulk(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+	tokenCarol := authUser(t, baseURL, "user3", "password123")
+
+	resp, err := doPost(t, baseURL+"/api/sendCoin/bulk", map[string]any{
+		"transfers": []map[string]any{{"toUser": "user2", "amount": 100}, {"toUser": "ghost", "amount": 100}},
+	}, tokenAlice)
+	assert.NoError(t, err)
+	var failure models.BulkErrorResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&failure))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, []models.RecipientError{{Index: 1, ToUser: "ghost", Error: "user not found"}}, failure.Recipients)
+	assert.Equal(t, 1000, getInfo(t, baseURL, tokenAlice).Coins)
+
+	resp, err = doPost(t, baseURL+"/api/sendCoin/bulk", map[string]any{
+		"toUsers": []string{"user2", "user3"}, "total": 2001,
+	}, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, err = doPost(t, baseURL+"/api/sendCoin/bulk", map[string]any{
+		"toUsers": []string{"user2", "user3"}, "total": 101, "category": "teamwork",
+	}, tokenAlice)
+	assert.NoError(t, err)
+	var result models.BulkSendResult
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, result.Transfers, 2)
+
+	assert.Equal(t, 899, getInfo(t, baseURL, tokenAlice).Coins)
+	assert.Equal(t, 1051, getInfo(t, baseURL, tokenBob).Coins)
+	assert.Equal(t, 1050, getInfo(t, baseURL, tokenCarol).Coins)
+}
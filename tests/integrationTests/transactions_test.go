@@ -0,0 +1,98 @@
+package integrationTests
+
+import (
+	"avito/internal/models"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func getTransactions(t *testing.T, url, token string) models.TransactionPage {
+	t.Helper()
+
+	resp, err := doGet(t, url, token)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var page models.TransactionPage
+	err = json.NewDecoder(resp.Body).Decode(&page)
+	assert.NoError(t, err)
+	return page
+}
+
+func TestTransactionsPagination(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+	authUser(t, baseURL, "user3", "password123")
+
+	for _, amount := range []int{10, 20, 30, 40, 50} {
+		resp, err := doPost(t, baseURL+"/api/sendCoin", SendCoinRequest{ToUser: "user2", Amount: amount}, tokenAlice)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+	resp, err := doPost(t, baseURL+"/api/sendCoin", SendCoinRequest{ToUser: "user3", Amount: 60}, tokenAlice)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	resp, err = doPost(t, baseURL+"/api/sendCoin", SendCoinRequest{ToUser: "user", Amount: 70}, tokenBob)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	var amounts []int
+	url := baseURL + "/api/transactions?limit=3"
+	for {
+		page := getTransactions(t, url, tokenAlice)
+		for _, tr := range page.Transactions {
+			assert.NotZero(t, tr.ID)
+			assert.False(t, tr.CreatedAt.IsZero())
+			amounts = append(amounts, tr.Amount)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		url = baseURL + "/api/transactions?limit=3&cursor=" + page.NextCursor
+	}
+	assert.Equal(t, []int{70, 60, 50, 40, 30, 20, 10}, amounts)
+
+	page := getTransactions(t, baseURL+"/api/transactions?direction=sent&counterparty=user2&minAmount=20&maxAmount=40", tokenAlice)
+	assert.Len(t, page.Transactions, 3)
+	for _, tr := range page.Transactions {
+		assert.Equal(t, "user2", tr.ToUser)
+	}
+
+	page = getTransactions(t, baseURL+"/api/transactions?direction=received", tokenAlice)
+	assert.Len(t, page.Transactions, 1)
+	assert.Equal(t, "user2", page.Transactions[0].FromUser)
+
+	page = getTransactions(t, baseURL+"/api/transactions?to=2000-01-01", tokenAlice)
+	assert.Empty(t, page.Transactions)
+}
+
+func TestCounterparties(t *testing.T) {
+	srv := setupTestServer(t)
+	baseURL := srv.URL
+
+	tokenAlice := authUser(t, baseURL, "user", "password123")
+	tokenBob := authUser(t, baseURL, "user2", "password123")
+
+	for _, amount := range []int{10, 15} {
+		resp, err := doPost(t, baseURL+"/api/sendCoin", SendCoinRequest{ToUser: "user2", Amount: amount}, tokenAlice)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	resp, err := doGet(t, baseURL+"/api/info/counterparties", tokenBob)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result models.Counterparties
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, []models.CounterpartyTotal{{User: "user", Amount: 25, Count: 2}}, result.Received)
+	assert.Empty(t, result.Sent)
+}